@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 屏蔽用户及不想看的社区、关键词
+
+// blockError 屏蔽相关的错误处理
+func blockError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, logic.ErrorBlockSelf):
+		ResponseError(c, CodeBlockSelf)
+	case errors.Is(err, logic.ErrorTooManyKeywords):
+		ResponseError(c, CodeTooManyKeywords)
+	case errors.Is(err, logic.ErrorKeywordEmpty):
+		ResponseErrorWithMsg(c, CodeInvalidParams, err.Error())
+	case errors.Is(err, mysql.ErrorUserNotExit):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(c, CodeCommunityNotExist)
+	default:
+		zap.L().Error(msg, zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// BlockUserHandler 屏蔽用户
+// @Summary 屏蔽用户
+// @Description 屏蔽后不再看到对方的帖子、评论及通知, 双方不能私信、@提到及关注对方, 已有的关注关系同时解除
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/block [post]
+func BlockUserHandler(c *gin.Context) {
+	blockTarget(c, getUserIDParam, logic.BlockUser, "logic.BlockUser failed")
+}
+
+// UnblockUserHandler 取消屏蔽用户
+// @Summary 取消屏蔽用户
+// @Description 取消屏蔽用户, 未屏蔽时不报错
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/block [delete]
+func UnblockUserHandler(c *gin.Context) {
+	blockTarget(c, getUserIDParam, logic.UnblockUser, "logic.UnblockUser failed")
+}
+
+// MuteCommunityHandler 屏蔽社区
+// @Summary 屏蔽社区
+// @Description 屏蔽的社区的帖子不在全部帖子列表及feed中展示, 直接浏览该社区时仍然展示
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/mute [post]
+func MuteCommunityHandler(c *gin.Context) {
+	blockTarget(c, getCommunityIDParam, logic.MuteCommunity, "logic.MuteCommunity failed")
+}
+
+// UnmuteCommunityHandler 取消屏蔽社区
+// @Summary 取消屏蔽社区
+// @Description 取消屏蔽社区, 未屏蔽时不报错
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/mute [delete]
+func UnmuteCommunityHandler(c *gin.Context) {
+	blockTarget(c, getCommunityIDParam, logic.UnmuteCommunity, "logic.UnmuteCommunity failed")
+}
+
+// blockTarget 屏蔽/取消屏蔽用户及社区的公共处理
+func blockTarget(c *gin.Context, param func(*gin.Context) (uint64, bool), fn func(userID, targetID uint64) error, msg string) {
+	targetID, ok := param(c)
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err = fn(userID, targetID); err != nil {
+		blockError(c, err, msg)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// MuteKeywordHandler 屏蔽关键词
+// @Summary 屏蔽关键词
+// @Description 标题或正文包含该关键词(不区分大小写)的帖子不在帖子列表及feed中展示, 最多50个
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.MuteKeywordForm true "关键词"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/muted_keywords [post]
+func MuteKeywordHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	p := new(models.MuteKeywordForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err = logic.MuteKeyword(userID, p.Keyword); err != nil {
+		blockError(c, err, "logic.MuteKeyword failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnmuteKeywordHandler 取消屏蔽关键词
+// @Summary 取消屏蔽关键词
+// @Description 取消屏蔽关键词, 未屏蔽时不报错
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param keyword path string true "关键词"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/muted_keywords/{keyword} [delete]
+func UnmuteKeywordHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err = logic.UnmuteKeyword(userID, c.Param("keyword")); err != nil {
+		blockError(c, err, "logic.UnmuteKeyword failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// BlockListHandler 我的屏蔽列表
+// @Summary 我的屏蔽列表
+// @Description 查询屏蔽的用户、社区及关键词
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/blocks [get]
+func BlockListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	data, err := logic.GetBlockList(userID)
+	if err != nil {
+		blockError(c, err, "logic.GetBlockList failed")
+		return
+	}
+	ResponseSuccess(c, data)
+}
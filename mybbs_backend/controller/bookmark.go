@@ -0,0 +1,254 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 收藏帖子及收藏夹
+
+// bookmarkError 收藏相关的错误处理
+func bookmarkError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(c, CodePostNotExist)
+	case errors.Is(err, logic.ErrorFolderNotExist):
+		ResponseError(c, CodeFolderNotExist)
+	case errors.Is(err, mysql.ErrorFolderExist):
+		ResponseError(c, CodeFolderExist)
+	case errors.Is(err, logic.ErrorTooManyFolders):
+		ResponseError(c, CodeTooManyFolders)
+	case errors.Is(err, logic.ErrorFolderNameEmpty):
+		ResponseErrorWithMsg(c, CodeInvalidParams, err.Error())
+	default:
+		zap.L().Error(msg, zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// getFolderIDParam 获取路径中的收藏夹id
+func getFolderIDParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return 0, false
+	}
+	return id, true
+}
+
+// SavePostHandler 收藏帖子
+// @Summary 收藏帖子
+// @Description 收藏帖子到指定的收藏夹, 已收藏时移动到该收藏夹; 不传folder_id或为0时为未分类
+// @Tags 帖子相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "帖子id"
+// @Param object body models.SavePostForm false "收藏夹"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /post/{id}/save [post]
+func SavePostHandler(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	p := new(models.SavePostForm)
+	// 没有请求体时收藏到未分类
+	if c.Request.ContentLength > 0 && !bindJSON(c, p) {
+		return
+	}
+	v := getViewer(c)
+	if v == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err = logic.SavePost(v, postID, p.FolderID); err != nil {
+		bookmarkError(c, err, "logic.SavePost failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnsavePostHandler 取消收藏
+// @Summary 取消收藏
+// @Description 取消收藏帖子, 未收藏时不报错
+// @Tags 帖子相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "帖子id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /post/{id}/save [delete]
+func UnsavePostHandler(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	v := getViewer(c)
+	if v == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err = logic.UnsavePost(v, postID); err != nil {
+		bookmarkError(c, err, "logic.UnsavePost failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// SavedPostListHandler 我收藏的帖子
+// @Summary 我收藏的帖子
+// @Description 分页查询收藏的帖子, 最近收藏的在前; 指定folder_id时只查询该收藏夹, 0表示未分类
+// @Tags 帖子相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param folder_id query int false "收藏夹id"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/saved [get]
+func SavedPostListHandler(c *gin.Context) {
+	v := getViewer(c)
+	if v == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	var folderID *uint64
+	if s, ok := c.GetQuery("folder_id"); ok {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		folderID = &id
+	}
+	page, size := getPageInfo(c)
+	data, err := logic.GetSavedPostList(v, folderID, page, size)
+	if err != nil {
+		bookmarkError(c, err, "logic.GetSavedPostList failed")
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// BookmarkFolderListHandler 我的收藏夹
+// @Summary 我的收藏夹
+// @Description 查询收藏夹及其中的帖子数
+// @Tags 帖子相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/saved/folders [get]
+func BookmarkFolderListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	list, err := logic.GetBookmarkFolderList(userID)
+	if err != nil {
+		bookmarkError(c, err, "logic.GetBookmarkFolderList failed")
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// CreateBookmarkFolderHandler 创建收藏夹
+// @Summary 创建收藏夹
+// @Description 创建收藏夹, 名称不能重复, 最多50个
+// @Tags 帖子相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.BookmarkFolderForm true "收藏夹名称"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/saved/folders [post]
+func CreateBookmarkFolderHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	p := new(models.BookmarkFolderForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	folder, err := logic.CreateBookmarkFolder(userID, p.Name)
+	if err != nil {
+		bookmarkError(c, err, "logic.CreateBookmarkFolder failed")
+		return
+	}
+	ResponseSuccess(c, folder)
+}
+
+// RenameBookmarkFolderHandler 重命名收藏夹
+// @Summary 重命名收藏夹
+// @Description 重命名收藏夹, 名称不能重复
+// @Tags 帖子相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "收藏夹id"
+// @Param object body models.BookmarkFolderForm true "收藏夹名称"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/saved/folders/{id} [put]
+func RenameBookmarkFolderHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	folderID, ok := getFolderIDParam(c)
+	if !ok {
+		return
+	}
+	p := new(models.BookmarkFolderForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err = logic.RenameBookmarkFolder(userID, folderID, p.Name); err != nil {
+		bookmarkError(c, err, "logic.RenameBookmarkFolder failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DeleteBookmarkFolderHandler 删除收藏夹
+// @Summary 删除收藏夹
+// @Description 删除收藏夹, 其中收藏的帖子移到未分类
+// @Tags 帖子相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "收藏夹id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/saved/folders/{id} [delete]
+func DeleteBookmarkFolderHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	folderID, ok := getFolderIDParam(c)
+	if !ok {
+		return
+	}
+	if err = logic.DeleteBookmarkFolder(userID, folderID); err != nil {
+		bookmarkError(c, err, "logic.DeleteBookmarkFolder failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
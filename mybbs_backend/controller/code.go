@@ -19,6 +19,72 @@ const (
 	CodeInvalidToken      MyCode = 1006
 	CodeInvalidAuthFormat MyCode = 1007
 	CodeNotLogin          MyCode = 1008
+
+	CodeInvalidRefreshToken MyCode = 1009
+	CodeRefreshTokenReused  MyCode = 1010
+	CodeSessionNotExist     MyCode = 1011
+
+	CodeTOTPNotEnrolled     MyCode = 1012
+	CodeTOTPAlreadyEnabled  MyCode = 1013
+	CodeInvalidTOTPCode     MyCode = 1014
+	CodeInvalidChallenge    MyCode = 1015
+	CodeTooManyAttempts     MyCode = 1016
+
+	CodeOIDCDisabled        MyCode = 1017
+	CodeInvalidOIDCState    MyCode = 1018
+	CodeOIDCLoginFailed     MyCode = 1019
+	CodeOIDCEmailRequired   MyCode = 1020
+
+	CodeAvatarInvalid       MyCode = 1021
+	CodeAvatarTooLarge      MyCode = 1022
+
+	CodeForbidden           MyCode = 1023
+	CodeInvalidRole         MyCode = 1024
+	CodeRoleNotExist        MyCode = 1025
+	CodeLastAdmin           MyCode = 1026
+
+	CodeCommunityExist      MyCode = 1027
+	CodeCommunityNotExist   MyCode = 1028
+	CodeCommunityArchived   MyCode = 1029
+
+	CodePostNotExist        MyCode = 1030
+	CodeBanned              MyCode = 1031
+	CodePostLocked          MyCode = 1032
+	CodeBanModerator        MyCode = 1033
+	CodeBanNotExist         MyCode = 1034
+
+	CodeNotMember           MyCode = 1035
+	CodeMemberNotPending    MyCode = 1036
+
+	CodeInvalidRule         MyCode = 1037
+
+	CodeFlairNotExist       MyCode = 1038
+	CodeFlairExist          MyCode = 1039
+	CodeFlairModOnly        MyCode = 1040
+	CodeNotPostAuthor       MyCode = 1041
+
+	CodeTooManyRequests     MyCode = 1042
+
+	CodeConversationNotExist MyCode = 1043
+	CodeMessageBlocked       MyCode = 1044
+	CodeMessageSelf          MyCode = 1045
+	CodeMessageNotExist      MyCode = 1046
+
+	CodeFollowSelf           MyCode = 1047
+
+	CodeBlockSelf            MyCode = 1048
+	CodeUserBlocked          MyCode = 1049
+	CodeTooManyKeywords      MyCode = 1050
+
+	CodeFolderNotExist       MyCode = 1051
+	CodeFolderExist          MyCode = 1052
+	CodeTooManyFolders       MyCode = 1053
+
+	CodeInvalidUnsubscribe   MyCode = 1054
+
+	CodeWebhookNotExist      MyCode = 1055
+	CodeDeliveryNotExist     MyCode = 1056
+	CodeDeliveryNotFailed    MyCode = 1057
 )
 
 var msgFlags = map[MyCode]string{
@@ -32,6 +98,72 @@ var msgFlags = map[MyCode]string{
 	CodeInvalidToken:      "无效的Token",
 	CodeInvalidAuthFormat: "认证格式有误",
 	CodeNotLogin:          "未登录",
+
+	CodeInvalidRefreshToken: "refresh token无效或已过期,请重新登录",
+	CodeRefreshTokenReused:  "refresh token已被使用,请重新登录",
+	CodeSessionNotExist:     "会话不存在",
+
+	CodeTOTPNotEnrolled:    "尚未开启两步验证",
+	CodeTOTPAlreadyEnabled: "已经开启了两步验证",
+	CodeInvalidTOTPCode:    "验证码错误",
+	CodeInvalidChallenge:   "两步验证已过期,请重新登录",
+	CodeTooManyAttempts:    "验证失败次数过多,请重新登录",
+
+	CodeOIDCDisabled:      "未开启第三方登录",
+	CodeInvalidOIDCState:  "登录请求无效或已过期,请重新登录",
+	CodeOIDCLoginFailed:   "第三方登录失败",
+	CodeOIDCEmailRequired: "第三方账号需要提供已验证的邮箱",
+
+	CodeAvatarInvalid:  "头像只支持png/jpeg/gif格式的图片",
+	CodeAvatarTooLarge: "头像图片不能超过2MB及4096x4096",
+
+	CodeForbidden:    "没有权限",
+	CodeInvalidRole:  "无效的角色,版主需要指定社区",
+	CodeRoleNotExist: "用户没有该角色",
+	CodeLastAdmin:    "不能撤销最后一个管理员",
+
+	CodeCommunityExist:    "社区名称已存在",
+	CodeCommunityNotExist: "社区不存在",
+	CodeCommunityArchived: "社区已归档,不能发帖或加入",
+
+	CodePostNotExist: "帖子不存在",
+	CodeBanned:       "你已被禁言",
+	CodePostLocked:   "帖子已锁定,不能评论",
+	CodeBanModerator: "不能禁言版主或管理员",
+	CodeBanNotExist:  "用户没有被禁言",
+
+	CodeNotMember:        "只有通过审核的社区成员才能发帖",
+	CodeMemberNotPending: "没有待审核的加入申请",
+
+	CodeInvalidRule: "无效的发帖规则,请检查长度限制及字段的校验规则",
+
+	CodeFlairNotExist: "flair不存在",
+	CodeFlairExist:    "flair名称已存在",
+	CodeFlairModOnly:  "只有版主可以使用或去掉该flair",
+	CodeNotPostAuthor: "只有作者或版主可以修改帖子",
+
+	CodeTooManyRequests: "操作太频繁,请稍后再试",
+
+	CodeConversationNotExist: "会话不存在",
+	CodeMessageBlocked:       "对方已屏蔽你或你已屏蔽对方,不能发送私信",
+	CodeMessageSelf:          "不能给自己发私信",
+	CodeMessageNotExist:      "消息不存在",
+
+	CodeFollowSelf: "不能关注自己",
+
+	CodeBlockSelf:       "不能屏蔽自己",
+	CodeUserBlocked:     "对方已屏蔽你或你已屏蔽对方",
+	CodeTooManyKeywords: "屏蔽的关键词数量超出限制",
+
+	CodeFolderNotExist: "收藏夹不存在",
+	CodeFolderExist:    "收藏夹名称已存在",
+	CodeTooManyFolders: "收藏夹数量超出限制",
+
+	CodeInvalidUnsubscribe: "退订链接无效",
+
+	CodeWebhookNotExist:   "webhook不存在",
+	CodeDeliveryNotExist:  "投递记录不存在",
+	CodeDeliveryNotFailed: "只能重新投递失败的记录",
 }
 
 func (c MyCode) Msg() string {
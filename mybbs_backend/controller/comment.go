@@ -2,8 +2,9 @@ package controller
 
 import (
 	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
 	"bluebell_backend/models"
-	"bluebell_backend/pkg/snowflake"
+	"errors"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
@@ -20,13 +21,6 @@ func CommentHandler(c *gin.Context) {
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	// 生成帖子ID
-	commentID, err := snowflake.GetID()
-	if err != nil {
-		zap.L().Error("snowflake.GetID() failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
-		return
-	}
 	// 获取作者ID，当前请求的UserID
 	userID, err := getCurrentUserID(c)
 	if err != nil {
@@ -34,13 +28,22 @@ func CommentHandler(c *gin.Context) {
 		ResponseError(c, CodeNotLogin)
 		return
 	}
-	comment.CommentID = commentID
 	comment.AuthorID = userID
 
-	// 创建帖子
-	if err := mysql.CreateComment(&comment); err != nil {
-		zap.L().Error("mysql.CreatePost(&post) failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+	// 创建评论
+	if err := logic.CreateComment(getViewer(c), &comment); err != nil {
+		if responseBanned(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, mysql.ErrorInvalidID):
+			ResponseError(c, CodePostNotExist)
+		case errors.Is(err, logic.ErrorPostLocked):
+			ResponseError(c, CodePostLocked)
+		default:
+			zap.L().Error("logic.CreateComment failed", zap.Error(err))
+			ResponseError(c, CodeServerBusy)
+		}
 		return
 	}
 	ResponseSuccess(c, nil)
@@ -53,7 +56,7 @@ func CommentListHandler(c *gin.Context) {
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	posts, err := mysql.GetCommentListByIDs(ids)
+	posts, err := logic.GetCommentList(getViewer(c), ids)
 	if err != nil {
 		ResponseError(c, CodeServerBusy)
 		return
@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"bluebell_backend/dao/mysql"
 	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
@@ -28,7 +32,7 @@ import (
 // @Router /community [get]
 func CommunityHandler(c *gin.Context) {
 	// 查询到所有的社区(community_id,community_name)以列表的形式返回
-	communityList, err := logic.GetCommunityList()
+	communityList, err := logic.GetCommunityList(getViewer(c))
 	if err != nil {
 		zap.L().Error("logic.GetCommunityList() failed", zap.Error(err))
 		ResponseError(c, CodeServerBusy)	// 不轻易把服务端报错暴露给外面
@@ -44,7 +48,7 @@ func CommunityHandler(c *gin.Context) {
  **/
 // CommunityDetailHandler 社区详情
 // @Summary 社区详情
-// @Description 社区详情
+// @Description 社区详情, 包括成员数
 // @Tags 社区业务接口
 // @Accept application/json
 // @Produce application/json
@@ -63,11 +67,189 @@ func CommunityDetailHandler(c *gin.Context) {
 	}
 
 	// 2、根据ID获取社区详情
-	communityList, err := logic.GetCommunityDetailByID(communityId)
+	communityList, err := logic.GetCommunityDetailByID(getViewer(c), communityId)
 	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
 		zap.L().Error("logic.GetCommunityByID() failed", zap.Error(err))
-		ResponseErrorWithMsg(c, CodeSuccess, err.Error())
+		ResponseError(c, CodeServerBusy)
 		return
 	}
 	ResponseSuccess(c, communityList)
 }
+
+// 社区管理, 需要管理员权限
+
+// responseCommunityError 将社区管理的业务错误转换成响应
+func responseCommunityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorCommunityExist):
+		ResponseError(c, CodeCommunityExist)
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(c, CodeCommunityNotExist)
+	default:
+		zap.L().Error("manage community failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// bindCommunityForm 获取并校验社区参数
+func bindCommunityForm(c *gin.Context) (*models.CommunityForm, bool) {
+	p := new(models.CommunityForm)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return nil, false
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return nil, false
+	}
+	return p, true
+}
+
+// getCommunityIDParam 获取路径中的社区id
+func getCommunityIDParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return 0, false
+	}
+	return id, true
+}
+
+// AdminCommunityListHandler 全部社区
+// @Summary 全部社区
+// @Description 查询包括已归档在内的全部社区(管理员)
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/community [get]
+func AdminCommunityListHandler(c *gin.Context) {
+	list, err := logic.GetAllCommunities()
+	if err != nil {
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// CreateCommunityHandler 创建社区
+// @Summary 创建社区
+// @Description 创建社区(管理员), community_id自动生成, 新社区排在最后
+// @Tags 管理后台接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.CommunityForm true "社区信息"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/community [post]
+func CreateCommunityHandler(c *gin.Context) {
+	p, ok := bindCommunityForm(c)
+	if !ok {
+		return
+	}
+	community, err := logic.CreateCommunity(p)
+	if err != nil {
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, community)
+}
+
+// UpdateCommunityHandler 修改社区
+// @Summary 修改社区
+// @Description 修改社区名称及简介(管理员)
+// @Tags 管理后台接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param object body models.CommunityForm true "社区信息"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/community/{id} [put]
+func UpdateCommunityHandler(c *gin.Context) {
+	id, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	p, ok := bindCommunityForm(c)
+	if !ok {
+		return
+	}
+	if err := logic.UpdateCommunity(id, p); err != nil {
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ArchiveCommunityHandler 归档社区
+// @Summary 归档社区
+// @Description 归档后社区只读且不在社区列表中展示(管理员)
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/community/{id}/archive [post]
+func ArchiveCommunityHandler(c *gin.Context) {
+	archiveCommunity(c, true)
+}
+
+// UnarchiveCommunityHandler 恢复归档的社区
+// @Summary 恢复归档的社区
+// @Description 恢复归档的社区(管理员)
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/community/{id}/archive [delete]
+func UnarchiveCommunityHandler(c *gin.Context) {
+	archiveCommunity(c, false)
+}
+
+func archiveCommunity(c *gin.Context, archived bool) {
+	id, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	if err := logic.ArchiveCommunity(id, archived); err != nil {
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ReorderCommunityHandler 调整社区排序
+// @Summary 调整社区排序
+// @Description 按给定的顺序排列社区, 未给出的社区排在后面(管理员)
+// @Tags 管理后台接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.CommunityOrderForm true "社区id列表"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/community/order [put]
+func ReorderCommunityHandler(c *gin.Context) {
+	p := new(models.CommunityOrderForm)
+	if err := c.ShouldBindJSON(p); err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.ReorderCommunities(p.CommunityIDs); err != nil {
+		responseCommunityError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
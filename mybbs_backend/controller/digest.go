@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 订阅社区的热门帖子邮件摘要
+
+// DigestSettingHandler 我的邮件摘要设置
+// @Summary 我的邮件摘要设置
+// @Description 查询订阅社区的热门帖子邮件摘要的发送频率: 0不发送 1每天 2每周
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/digest [get]
+func DigestSettingHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	setting, err := logic.GetDigestSetting(userID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExit) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		zap.L().Error("logic.GetDigestSetting failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, setting)
+}
+
+// UpdateDigestSettingHandler 修改邮件摘要设置
+// @Summary 修改邮件摘要设置
+// @Description 设置订阅社区的热门帖子邮件摘要的发送频率: 0不发送 1每天 2每周
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.DigestSetting true "邮件摘要设置"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/digest [put]
+func UpdateDigestSettingHandler(c *gin.Context) {
+	p := new(models.DigestSetting)
+	if !bindJSON(c, p) {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.UpdateDigestSetting(userID, p); err != nil {
+		zap.L().Error("logic.UpdateDigestSetting failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, p)
+}
+
+// getUnsubscribeUser 取出退订链接中的用户id并校验签名
+func getUnsubscribeUser(c *gin.Context) (uint64, bool) {
+	userID, err := strconv.ParseUint(c.Query("uid"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidUnsubscribe)
+		return 0, false
+	}
+	if err := logic.CheckUnsubscribe(userID, c.Query("token")); err != nil {
+		ResponseError(c, CodeInvalidUnsubscribe)
+		return 0, false
+	}
+	return userID, true
+}
+
+// responseUnsubscribePage 返回退订的确认页面或已退订页面
+func responseUnsubscribePage(c *gin.Context, done bool) {
+	page, err := logic.RenderUnsubscribePage(done)
+	if err != nil {
+		zap.L().Error("logic.RenderUnsubscribePage failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
+
+// DigestUnsubscribePageHandler 退订邮件摘要的确认页面
+// @Summary 退订邮件摘要的确认页面
+// @Description 邮件中的退订链接, 不需要登录; 只校验签名并返回确认页面, 不会退订, 避免邮件安全扫描及预加载链接时误退订
+// @Tags 用户业务接口
+// @Produce text/html
+// @Param uid query string true "用户id"
+// @Param token query string true "退订链接的签名"
+// @Success 200 {string} string "确认页面"
+// @Router /digest/unsubscribe [get]
+func DigestUnsubscribePageHandler(c *gin.Context) {
+	if _, ok := getUnsubscribeUser(c); !ok {
+		return
+	}
+	responseUnsubscribePage(c, false)
+}
+
+// DigestUnsubscribeHandler 退订邮件摘要
+// @Summary 退订邮件摘要
+// @Description 确认页面提交或邮件客户端的一键退订(List-Unsubscribe-Post), 不需要登录; 确认页面提交时返回已退订页面
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param uid query string true "用户id"
+// @Param token query string true "退订链接的签名"
+// @Success 200 {object} _ResponsePostList
+// @Router /digest/unsubscribe [post]
+func DigestUnsubscribeHandler(c *gin.Context) {
+	userID, ok := getUnsubscribeUser(c)
+	if !ok {
+		return
+	}
+	if err := logic.Unsubscribe(userID, c.Query("token")); err != nil {
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	if c.PostForm("confirm") == "1" {
+		responseUnsubscribePage(c, true)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/pkg/jwt"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 实时事件(Server-Sent Events)
+
+// eventHeartbeat 没有事件时定期发送心跳, 防止代理断开空闲连接; 每次心跳时重新检查token是否已被吊销
+const eventHeartbeat = 30 * time.Second
+
+// EventStreamHandler 实时事件
+// @Summary 实时事件
+// @Description 使用Server-Sent Events推送新通知(notification); 指定post_id时同时推送该帖子的新评论(comment)及投票数(vote)
+// @Description 浏览器的EventSource不能设置请求头, 可以把access token放在access_token参数中
+// @Description access token过期或被吊销(登出、吊销设备会话)时发送expired事件后断开, 客户端刷新token后重新连接
+// @Tags 通知相关接口
+// @Produce text/event-stream
+// @Param Authorization header string false "Bearer 用户令牌"
+// @Param access_token query string false "用户令牌"
+// @Param post_id query string false "正在浏览的帖子id"
+// @Security ApiKeyAuth
+// @Router /events [get]
+func EventStreamHandler(c *gin.Context) {
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	v := getViewer(c)
+	var postID uint64
+	if s := c.Query("post_id"); len(s) > 0 {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		postID = id
+	}
+	sub, err := logic.SubscribeEvents(v, postID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
+		zap.L().Error("logic.SubscribeEvents failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	defer sub.Close()
+	filter, err := logic.NewEventFilter(v)
+	if err != nil {
+		zap.L().Error("logic.NewEventFilter failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭nginx的缓冲
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	// 连接只在建立时校验过token, 到token的过期时间后断开
+	expire := time.NewTimer(time.Until(time.Unix(claims.ExpiresAt, 0)))
+	defer expire.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			// 屏蔽的用户的新评论不推送
+			if filter.Keep(e) {
+				c.SSEvent(e.Event, e.Data)
+			}
+		case <-heartbeat.C:
+			if err := jwt.CheckRevoked(claims); err != nil {
+				if !errors.Is(err, jwt.ErrorTokenRevoked) {
+					zap.L().Error("jwt.CheckRevoked failed", zap.Uint64("user_id", claims.UserID), zap.Error(err))
+				}
+				c.SSEvent("expired", "")
+				return false
+			}
+			c.SSEvent("ping", time.Now().Unix())
+		case <-expire.C:
+			c.SSEvent("expired", "")
+			return false
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 社区的flair、帖子的NSFW及剧透标记、用户的浏览偏好
+
+// responseFlairError 将flair相关的业务错误转换成响应
+func responseFlairError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.ErrorFlairNotExist):
+		ResponseError(c, CodeFlairNotExist)
+	case errors.Is(err, mysql.ErrorFlairExist):
+		ResponseError(c, CodeFlairExist)
+	case errors.Is(err, logic.ErrorFlairModOnly):
+		ResponseError(c, CodeFlairModOnly)
+	case errors.Is(err, logic.ErrorFlairNameEmpty):
+		ResponseError(c, CodeInvalidParams)
+	case errors.Is(err, logic.ErrorNotPostAuthor):
+		ResponseError(c, CodeNotPostAuthor)
+	default:
+		zap.L().Error("flair operation failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// CommunityFlairListHandler 社区的flair
+// @Summary 社区的flair
+// @Description 查询社区定义的flair, mod_only为true的只有版主可以使用
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param id path int true "社区id"
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/flairs [get]
+func CommunityFlairListHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	list, err := logic.GetFlairList(getViewer(c), communityID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
+		zap.L().Error("logic.GetFlairList failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// CreateFlairHandler 创建flair
+// @Summary 创建flair
+// @Description 版主创建社区的flair, 同一社区内名称不能重复
+// @Tags 社区管理接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param object body models.FlairForm true "flair"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/flairs [post]
+func CreateFlairHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	p := new(models.FlairForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	flair, err := logic.CreateFlair(communityID, p)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
+		responseFlairError(c, err)
+		return
+	}
+	ResponseSuccess(c, flair)
+}
+
+// UpdateFlairHandler 修改flair
+// @Summary 修改flair
+// @Description 版主修改flair的名称、颜色及是否只有版主可以使用
+// @Tags 社区管理接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param flair_id path int true "flair id"
+// @Param object body models.FlairForm true "flair"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/flairs/{flair_id} [put]
+func UpdateFlairHandler(c *gin.Context) {
+	communityID, flairID, ok := getModerationParams(c, "flair_id")
+	if !ok {
+		return
+	}
+	p := new(models.FlairForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logic.UpdateFlair(communityID, flairID, p); err != nil {
+		responseFlairError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DeleteFlairHandler 删除flair
+// @Summary 删除flair
+// @Description 版主删除flair, 使用该flair的帖子改为没有flair
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param flair_id path int true "flair id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/flairs/{flair_id} [delete]
+func DeleteFlairHandler(c *gin.Context) {
+	communityID, flairID, ok := getModerationParams(c, "flair_id")
+	if !ok {
+		return
+	}
+	if err := logic.DeleteFlair(communityID, flairID); err != nil {
+		responseFlairError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// editPost 修改帖子flair及标记的公共处理
+func editPost(c *gin.Context, p interface{}, fn func(v *logic.Viewer, postID uint64) error) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if !bindJSON(c, p) {
+		return
+	}
+	v := getViewer(c)
+	if v == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := fn(v, postID); err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
+		responseFlairError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// PostFlairHandler 修改帖子的flair
+// @Summary 修改帖子的flair
+// @Description 作者或版主修改帖子的flair, flair_id为0表示去掉flair; 版主添加的mod_only flair作者不能去掉
+// @Tags 帖子相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "帖子id"
+// @Param object body models.PostFlairForm true "flair"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /post/{id}/flair [put]
+func PostFlairHandler(c *gin.Context) {
+	p := new(models.PostFlairForm)
+	editPost(c, p, func(v *logic.Viewer, postID uint64) error {
+		return logic.SetPostFlair(v, postID, p.FlairID)
+	})
+}
+
+// PostFlagsHandler 修改帖子的NSFW及剧透标记
+// @Summary 修改帖子的NSFW及剧透标记
+// @Description 作者或版主修改帖子的NSFW及剧透标记, 没有传的标记保持不变
+// @Tags 帖子相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "帖子id"
+// @Param object body models.PostFlagsForm true "标记"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /post/{id}/flags [put]
+func PostFlagsHandler(c *gin.Context) {
+	p := new(models.PostFlagsForm)
+	editPost(c, p, func(v *logic.Viewer, postID uint64) error {
+		return logic.SetPostFlags(v, postID, p)
+	})
+}
+
+// PreferencesHandler 我的浏览偏好
+// @Summary 我的浏览偏好
+// @Description 查询是否在帖子列表中隐藏NSFW帖子及是否模糊显示剧透帖子
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/preferences [get]
+func PreferencesHandler(c *gin.Context) {
+	v := getViewer(c)
+	if v == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	prefs, err := logic.GetPreferences(v)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExit) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		zap.L().Error("logic.GetPreferences failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, prefs)
+}
+
+// UpdatePreferencesHandler 修改浏览偏好
+// @Summary 修改浏览偏好
+// @Description 设置是否在帖子列表中隐藏NSFW帖子及是否模糊显示剧透帖子
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.Preferences true "浏览偏好"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/preferences [put]
+func UpdatePreferencesHandler(c *gin.Context) {
+	p := new(models.Preferences)
+	if !bindJSON(c, p) {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.UpdatePreferences(userID, p); err != nil {
+		zap.L().Error("logic.UpdatePreferences failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, p)
+}
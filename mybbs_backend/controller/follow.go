@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 关注用户及关注的人的帖子feed
+
+// FollowHandler 关注用户
+// @Summary 关注用户
+// @Description 关注后该用户的帖子会出现在关注feed中, 重复关注不报错
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/follow [post]
+func FollowHandler(c *gin.Context) {
+	follow(c, logic.Follow)
+}
+
+// UnfollowHandler 取消关注
+// @Summary 取消关注
+// @Description 取消关注用户, 未关注时不报错
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/follow [delete]
+func UnfollowHandler(c *gin.Context) {
+	follow(c, logic.Unfollow)
+}
+
+// follow 关注/取消关注的公共处理
+func follow(c *gin.Context, fn func(userID, followeeID uint64) error) {
+	followeeID, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err = fn(userID, followeeID); err != nil {
+		switch {
+		case errors.Is(err, logic.ErrorFollowSelf):
+			ResponseError(c, CodeFollowSelf)
+		case errors.Is(err, logic.ErrorUserBlocked):
+			ResponseError(c, CodeUserBlocked)
+		case errors.Is(err, mysql.ErrorUserNotExit):
+			ResponseError(c, CodeUserNotExist)
+		default:
+			zap.L().Error("follow/unfollow failed", zap.Uint64("followee_id", followeeID), zap.Error(err))
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// FollowerListHandler 用户的粉丝
+// @Summary 用户的粉丝
+// @Description 分页查询关注了该用户的人, 最近关注的在前
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param id path string true "用户id"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/followers [get]
+func FollowerListHandler(c *gin.Context) {
+	followList(c, logic.GetFollowerList)
+}
+
+// FollowingListHandler 用户关注的人
+// @Summary 用户关注的人
+// @Description 分页查询该用户关注的人, 最近关注的在前
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param id path string true "用户id"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/following [get]
+func FollowingListHandler(c *gin.Context) {
+	followList(c, logic.GetFollowingList)
+}
+
+// followList 查询粉丝/关注的人的公共处理
+func followList(c *gin.Context, fn func(userID uint64, page, size int64) ([]*models.UserBrief, error)) {
+	userID, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+	page, size := getPageInfo(c)
+	list, err := fn(userID, page, size)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExit) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		zap.L().Error("query follow list failed", zap.Uint64("user_id", userID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// FollowingFeedHandler 关注的人的帖子
+// @Summary 关注的人的帖子
+// @Description 合并当前用户关注的人发布的帖子, 按时间或分数排序分页查询
+// @Tags 帖子相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Param order query string false "排序依据(time/score)"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /feed/following [get]
+func FollowingFeedHandler(c *gin.Context) {
+	p := &models.ParamPostList{
+		Page:  1,
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := c.ShouldBindQuery(p); err != nil || p.Page < 1 || p.Size < 1 {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	v := getViewer(c)
+	if v == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	data, err := logic.GetFollowingFeed(v, p)
+	if err != nil {
+		zap.L().Error("logic.GetFollowingFeed failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 加入社区及首页feed
+
+// JoinCommunityHandler 加入社区
+// @Summary 加入社区
+// @Description 加入社区后该社区的帖子会出现在首页feed中, 重复加入不报错; 受限及私有社区需要版主审核, 返回的status为0表示待审核
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/join [post]
+func JoinCommunityHandler(c *gin.Context) {
+	var status int8
+	membership(c, func(v *logic.Viewer, communityID uint64) (err error) {
+		status, err = logic.JoinCommunity(v, communityID)
+		return
+	}, func() interface{} {
+		return gin.H{"status": status}
+	})
+}
+
+// LeaveCommunityHandler 退出社区
+// @Summary 退出社区
+// @Description 退出社区或撤回加入申请, 未加入时不报错
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/join [delete]
+func LeaveCommunityHandler(c *gin.Context) {
+	membership(c, logic.LeaveCommunity, nil)
+}
+
+// membership 加入/退出社区的公共处理, result为nil时不返回数据
+func membership(c *gin.Context, fn func(v *logic.Viewer, communityID uint64) error, result func() interface{}) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	viewer := getViewer(c)
+	if viewer == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := fn(viewer, communityID); err != nil {
+		switch {
+		case errors.Is(err, mysql.ErrorInvalidID):
+			ResponseError(c, CodeCommunityNotExist)
+		case errors.Is(err, logic.ErrorCommunityArchived):
+			ResponseError(c, CodeCommunityArchived)
+		default:
+			zap.L().Error("join/leave community failed", zap.Uint64("community_id", communityID), zap.Error(err))
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	if result == nil {
+		ResponseSuccess(c, nil)
+		return
+	}
+	ResponseSuccess(c, result())
+}
+
+// UserCommunityListHandler 我加入的社区
+// @Summary 我加入的社区
+// @Description 查询当前用户加入的社区(不包括已归档的社区)
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/communities [get]
+func UserCommunityListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	list, err := logic.GetUserCommunityList(userID)
+	if err != nil {
+		zap.L().Error("logic.GetUserCommunityList failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// FeedHandler 首页feed
+// @Summary 首页feed
+// @Description 合并当前用户加入的社区的帖子, 按时间或分数排序分页查询
+// @Tags 帖子相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Param order query string false "排序依据(time/score)"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /feed [get]
+func FeedHandler(c *gin.Context) {
+	p := &models.ParamPostList{
+		Page:  1,
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := c.ShouldBindQuery(p); err != nil || p.Page < 1 || p.Size < 1 {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	v := getViewer(c)
+	if v == nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	data, err := logic.GetFeed(v, p)
+	if err != nil {
+		zap.L().Error("logic.GetFeed failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 私信
+
+// messageError 私信相关的错误处理
+func messageError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, logic.ErrorConversationNotExist):
+		ResponseError(c, CodeConversationNotExist)
+	case errors.Is(err, logic.ErrorMessageBlocked):
+		ResponseError(c, CodeMessageBlocked)
+	case errors.Is(err, logic.ErrorMessageSelf):
+		ResponseError(c, CodeMessageSelf)
+	case errors.Is(err, logic.ErrorMessageNotExist):
+		ResponseError(c, CodeMessageNotExist)
+	case errors.Is(err, mysql.ErrorUserNotExit):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorMessageEmpty),
+		errors.Is(err, logic.ErrorNoRecipient),
+		errors.Is(err, logic.ErrorGroupSize):
+		ResponseErrorWithMsg(c, CodeInvalidParams, err.Error())
+	default:
+		zap.L().Error(msg, zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// getConversationIDParam 获取路径中的会话id
+func getConversationIDParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return 0, false
+	}
+	return id, true
+}
+
+// SendMessageHandler 发送私信
+// @Summary 发送私信
+// @Description 发送到指定的会话, 或者发给recipient_id(一对一会话不存在时自动创建); 屏蔽对方或被对方屏蔽时不能发送
+// @Tags 私信相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.MessageForm true "消息"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /messages [post]
+func SendMessageHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	p := new(models.MessageForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	m, err := logic.SendMessage(userID, p)
+	if err != nil {
+		messageError(c, err, "logic.SendMessage failed")
+		return
+	}
+	ResponseSuccess(c, m)
+}
+
+// CreateConversationHandler 创建群聊
+// @Summary 创建群聊
+// @Description 创建包括自己在内不超过10人的群聊
+// @Tags 私信相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.ConversationForm true "群聊成员及名称"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /conversations [post]
+func CreateConversationHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	p := new(models.ConversationForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	conv, err := logic.CreateGroupConversation(userID, p)
+	if err != nil {
+		messageError(c, err, "logic.CreateGroupConversation failed")
+		return
+	}
+	ResponseSuccess(c, conv)
+}
+
+// ConversationListHandler 我的会话
+// @Summary 我的会话
+// @Description 分页查询会话, 包括最后一条消息及未读数, 最近有新消息的在前
+// @Tags 私信相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /conversations [get]
+func ConversationListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	page, size := getPageInfo(c)
+	list, err := logic.GetConversationList(userID, page, size)
+	if err != nil {
+		messageError(c, err, "logic.GetConversationList failed")
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// MessageHistoryHandler 会话的历史消息
+// @Summary 会话的历史消息
+// @Description 查询会话的消息(最新的在前)及成员的已读回执, 翻页时before传上一页最早的消息id
+// @Tags 私信相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "会话id"
+// @Param before query string false "只查询id小于该值的消息"
+// @Param size query int false "每页数量, 最多50"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /conversations/{id}/messages [get]
+func MessageHistoryHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	id, ok := getConversationIDParam(c)
+	if !ok {
+		return
+	}
+	var before uint64
+	if s := c.Query("before"); len(s) > 0 {
+		if before, err = strconv.ParseUint(s, 10, 64); err != nil {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+	}
+	_, size := getPageInfo(c)
+	data, err := logic.GetMessageHistory(userID, id, before, size)
+	if err != nil {
+		messageError(c, err, "logic.GetMessageHistory failed")
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// MarkConversationReadHandler 标记会话已读
+// @Summary 标记会话已读
+// @Description 标记会话已读到指定的消息, 其他成员会收到已读回执
+// @Tags 私信相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "会话id"
+// @Param object body models.MessageReadForm true "读到的最后一条消息"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /conversations/{id}/read [post]
+func MarkConversationReadHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	id, ok := getConversationIDParam(c)
+	if !ok {
+		return
+	}
+	p := new(models.MessageReadForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err = logic.MarkConversationRead(userID, id, p.MessageID); err != nil {
+		messageError(c, err, "logic.MarkConversationRead failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
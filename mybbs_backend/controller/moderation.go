@@ -0,0 +1,367 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// 社区管理, 需要对应社区的版主权限
+
+// responseBanned 用户被禁言时返回禁言原因及到期时间
+func responseBanned(c *gin.Context, err error) bool {
+	var banErr *logic.BanError
+	if !errors.As(err, &banErr) {
+		return false
+	}
+	ResponseErrorWithMsg(c, CodeBanned, gin.H{
+		"reason":      banErr.Ban.Reason,
+		"expire_time": banErr.Ban.ExpireTime,
+	})
+	return true
+}
+
+// responseModerationError 将社区管理的业务错误转换成响应
+func responseModerationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(c, CodeInvalidParams)
+	case errors.Is(err, mysql.ErrorUserNotExit):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorBanModerator):
+		ResponseError(c, CodeBanModerator)
+	case errors.Is(err, logic.ErrorBanNotExist):
+		ResponseError(c, CodeBanNotExist)
+	case errors.Is(err, logic.ErrorMemberNotPending):
+		ResponseError(c, CodeMemberNotPending)
+	default:
+		zap.L().Error("moderate community failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// getModerationParams 获取路径中的社区id及帖子/评论/用户id
+func getModerationParams(c *gin.Context, name string) (communityID, id uint64, ok bool) {
+	if communityID, ok = getCommunityIDParam(c); !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return 0, 0, false
+	}
+	return communityID, id, true
+}
+
+// moderatePost 删除、置顶、锁定帖子的公共处理
+func moderatePost(c *gin.Context, fn func(communityID, postID uint64) error) {
+	communityID, postID, ok := getModerationParams(c, "post_id")
+	if !ok {
+		return
+	}
+	if err := fn(communityID, postID); err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RemovePostHandler 删除帖子
+// @Summary 删除帖子
+// @Description 版主删除社区内的帖子
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param post_id path string true "帖子id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/post/{post_id} [delete]
+func RemovePostHandler(c *gin.Context) {
+	moderatePost(c, logic.RemovePost)
+}
+
+// PinPostHandler 置顶帖子
+// @Summary 置顶帖子
+// @Description 置顶的帖子显示在社区帖子列表第一页的最前面
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param post_id path string true "帖子id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/post/{post_id}/pin [post]
+func PinPostHandler(c *gin.Context) {
+	moderatePost(c, func(communityID, postID uint64) error {
+		return logic.PinPost(communityID, postID, true)
+	})
+}
+
+// UnpinPostHandler 取消置顶
+// @Summary 取消置顶
+// @Description 取消帖子的置顶
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param post_id path string true "帖子id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/post/{post_id}/pin [delete]
+func UnpinPostHandler(c *gin.Context) {
+	moderatePost(c, func(communityID, postID uint64) error {
+		return logic.PinPost(communityID, postID, false)
+	})
+}
+
+// LockPostHandler 锁定帖子
+// @Summary 锁定帖子
+// @Description 锁定后帖子不能再评论
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param post_id path string true "帖子id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/post/{post_id}/lock [post]
+func LockPostHandler(c *gin.Context) {
+	moderatePost(c, func(communityID, postID uint64) error {
+		return logic.LockPost(communityID, postID, true)
+	})
+}
+
+// UnlockPostHandler 解锁帖子
+// @Summary 解锁帖子
+// @Description 解锁后帖子可以继续评论
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param post_id path string true "帖子id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/post/{post_id}/lock [delete]
+func UnlockPostHandler(c *gin.Context) {
+	moderatePost(c, func(communityID, postID uint64) error {
+		return logic.LockPost(communityID, postID, false)
+	})
+}
+
+// RemoveCommentHandler 删除评论
+// @Summary 删除评论
+// @Description 版主删除社区内帖子下的评论
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param comment_id path string true "评论id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/comment/{comment_id} [delete]
+func RemoveCommentHandler(c *gin.Context) {
+	communityID, commentID, ok := getModerationParams(c, "comment_id")
+	if !ok {
+		return
+	}
+	if err := logic.RemoveComment(communityID, commentID); err != nil {
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// BanListHandler 社区禁言列表
+// @Summary 社区禁言列表
+// @Description 查询社区内未到期的禁言
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/bans [get]
+func BanListHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	list, err := logic.GetBanList(communityID)
+	if err != nil {
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// BanUserHandler 禁言用户
+// @Summary 禁言用户
+// @Description 在社区内禁言用户, 被禁言期间不能在该社区发帖、评论及投票; 重复禁言会覆盖原来的原因及期限
+// @Tags 社区管理接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param object body models.BanForm true "禁言参数"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/bans [post]
+func BanUserHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	p := new(models.BanForm)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	moderatorID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	ban, err := logic.BanUser(communityID, moderatorID, p)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, ban)
+}
+
+// UnbanUserHandler 解除禁言
+// @Summary 解除禁言
+// @Description 解除用户在社区内的禁言
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param user_id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/bans/{user_id} [delete]
+func UnbanUserHandler(c *gin.Context) {
+	communityID, userID, ok := getModerationParams(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := logic.UnbanUser(communityID, userID); err != nil {
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// CommunityModeratorListHandler 社区的版主
+// @Summary 社区的版主
+// @Description 查询社区的版主列表
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param id path int true "社区id"
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/moderators [get]
+func CommunityModeratorListHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	list, err := logic.GetModeratorList(getViewer(c), communityID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// PendingMemberListHandler 待审核的加入申请
+// @Summary 待审核的加入申请
+// @Description 查询受限及私有社区待审核的加入申请
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/members/pending [get]
+func PendingMemberListHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	list, err := logic.GetPendingMemberList(communityID)
+	if err != nil {
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// ApproveMemberHandler 通过加入申请
+// @Summary 通过加入申请
+// @Description 通过后用户可以浏览私有社区并在受限及私有社区发帖
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param user_id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/members/{user_id}/approve [post]
+func ApproveMemberHandler(c *gin.Context) {
+	communityID, userID, ok := getModerationParams(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := logic.ApproveMember(communityID, userID); err != nil {
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RemoveMemberHandler 移除成员
+// @Summary 移除成员
+// @Description 移除社区成员或拒绝加入申请
+// @Tags 社区管理接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param user_id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/members/{user_id} [delete]
+func RemoveMemberHandler(c *gin.Context) {
+	communityID, userID, ok := getModerationParams(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := logic.RemoveMember(communityID, userID); err != nil {
+		responseModerationError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 站内通知
+
+// NotificationListHandler 我的通知
+// @Summary 我的通知
+// @Description 分页查询通知及未读数, 最近更新的在前; 同一帖子/评论上未读的同类通知聚合为一条
+// @Tags 通知相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Param unread query bool false "只看未读"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /notifications [get]
+func NotificationListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	page, size := getPageInfo(c)
+	data, err := logic.GetNotificationList(userID, c.Query("unread") == "true", page, size)
+	if err != nil {
+		zap.L().Error("logic.GetNotificationList failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// UnreadNotificationCountHandler 未读通知数
+// @Summary 未读通知数
+// @Description 查询未读通知数, 用于展示角标
+// @Tags 通知相关接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /notifications/unread_count [get]
+func UnreadNotificationCountHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	count, err := logic.GetUnreadNotificationCount(userID)
+	if err != nil {
+		zap.L().Error("logic.GetUnreadNotificationCount failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, gin.H{"unread_count": count})
+}
+
+// MarkNotificationsReadHandler 标记通知为已读
+// @Summary 标记通知为已读
+// @Description 标记指定的通知为已读, ids为空时全部标记为已读
+// @Tags 通知相关接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.NotificationReadForm false "通知id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /notifications/read [post]
+func MarkNotificationsReadHandler(c *gin.Context) {
+	p := new(models.NotificationReadForm)
+	// 没有请求体时全部标记为已读
+	if c.Request.ContentLength > 0 && !bindJSON(c, p) {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.MarkNotificationsRead(userID, p.IDs); err != nil {
+		zap.L().Error("logic.MarkNotificationsRead failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
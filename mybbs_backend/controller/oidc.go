@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"bluebell_backend/logic"
+	"bluebell_backend/pkg/jwt"
+	"bluebell_backend/pkg/oidc"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 第三方登录(OpenID Connect)
+
+// oidcStateCookie 发起登录时把state写入cookie, 回调时比对, 防止登录CSRF
+const oidcStateCookie = "oidc_state"
+
+// OIDCLoginHandler 发起第三方登录
+// @Summary 第三方登录
+// @Description 重定向到配置的OIDC身份提供方进行登录
+// @Tags 用户业务接口
+// @Success 302
+// @Router /oauth/oidc/login [get]
+func OIDCLoginHandler(c *gin.Context) {
+	authURL, state, err := logic.OIDCLoginURL()
+	if err != nil {
+		if errors.Is(err, oidc.ErrorNotConfigured) {
+			ResponseError(c, CodeOIDCDisabled)
+			return
+		}
+		zap.L().Error("logic.OIDCLoginURL failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	c.SetCookie(oidcStateCookie, state, 600, "/", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallbackHandler 第三方登录回调
+// @Summary 第三方登录回调
+// @Description 身份提供方登录成功后回调, 首次登录时按已验证的邮箱关联已有账号或创建新账号, 返回access token和refresh token
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param state query string true "发起登录时的state"
+// @Param code query string true "授权码"
+// @Success 200 {object} _ResponsePostList
+// @Router /oauth/oidc/callback [get]
+func OIDCCallbackHandler(c *gin.Context) {
+	// 用户在身份提供方拒绝了授权
+	if e := c.Query("error"); len(e) > 0 {
+		zap.L().Info("oidc authorization denied", zap.String("error", e), zap.String("desc", c.Query("error_description")))
+		ResponseError(c, CodeOIDCLoginFailed)
+		return
+	}
+	state, code := c.Query("state"), c.Query("code")
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+	if len(state) == 0 || len(code) == 0 || subtle.ConstantTimeCompare([]byte(state), []byte(cookie)) != 1 {
+		ResponseError(c, CodeInvalidOIDCState)
+		return
+	}
+
+	user, err := logic.OIDCCallback(state, code, &jwt.Device{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrorNotConfigured):
+			ResponseError(c, CodeOIDCDisabled)
+		case errors.Is(err, logic.ErrorOIDCState):
+			ResponseError(c, CodeInvalidOIDCState)
+		case errors.Is(err, logic.ErrorOIDCEmail):
+			ResponseError(c, CodeOIDCEmailRequired)
+		default:
+			zap.L().Error("logic.OIDCCallback failed", zap.Error(err))
+			ResponseError(c, CodeOIDCLoginFailed)
+		}
+		return
+	}
+	// 开启了两步验证, 需要再调用 /login/2fa 提交验证码
+	if len(user.ChallengeToken) > 0 {
+		ResponseSuccess(c, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     user.ChallengeToken,
+		})
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"user_id":       fmt.Sprintf("%d", user.UserID),
+		"user_name":     user.Email,
+		"access_token":  user.AccessToken,
+		"refresh_token": user.RefreshToken,
+	})
+}
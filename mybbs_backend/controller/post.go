@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"bluebell_backend/dao/mysql"
 	"bluebell_backend/logic"
 	"bluebell_backend/models"
+	"errors"
 	"strconv"
 
 	"go.uber.org/zap"
@@ -46,9 +48,42 @@ func CreatePostHandler(c *gin.Context) {
 	}
 	post.AuthorId = userID
 	// 2、创建帖子
-	err = logic.CreatePost(&post)
+	err = logic.CreatePost(getViewer(c), &post)
 	if err != nil {
 		zap.L().Error("logic.CreatePost failed", zap.Error(err))
+		if responseBanned(c, err) {
+			return
+		}
+		var slowErr *logic.SlowModeError
+		if errors.As(err, &slowErr) {
+			ResponseTooManyRequests(c, slowErr.RetryAfter)
+			return
+		}
+		var ruleErr *logic.PostRuleError
+		if errors.As(err, &ruleErr) {
+			ResponseErrorWithMsg(c, CodeInvalidParams, ruleErr.Translate(trans))
+			return
+		}
+		if errors.Is(err, logic.ErrorNotMember) {
+			ResponseError(c, CodeNotMember)
+			return
+		}
+		if errors.Is(err, logic.ErrorFlairNotExist) {
+			ResponseError(c, CodeFlairNotExist)
+			return
+		}
+		if errors.Is(err, logic.ErrorFlairModOnly) {
+			ResponseError(c, CodeFlairModOnly)
+			return
+		}
+		if errors.Is(err, logic.ErrorCommunityArchived) {
+			ResponseError(c, CodeCommunityArchived)
+			return
+		}
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
@@ -91,7 +126,7 @@ func PostListHandler(c *gin.Context) {
 	// 获取分页参数
 	page,size := getPageInfo(c)
 	// 获取数据
-	data, err := logic.GetPostList(page,size)
+	data, err := logic.GetPostList(getViewer(c), page, size)
 	if err != nil {
 		ResponseError(c, CodeServerBusy)
 		return
@@ -137,7 +172,7 @@ func PostList2Handler(c *gin.Context)  {
 	}
 
 	// 获取数据
-	data, err := logic.GetPostListNew(p)	// 更新：合二为一
+	data, err := logic.GetPostListNew(getViewer(c), p)	// 更新：合二为一
 	if err != nil {
 		ResponseError(c, CodeServerBusy)
 		return
@@ -168,13 +203,19 @@ func PostDetailHandler(c *gin.Context) {
 	if err != nil {
 		zap.L().Error("get post detail with invalid param",zap.Error(err))
 		ResponseError(c,CodeInvalidParams)
+		return
 	}
 
 	// 2、根据id取出id帖子数据(查数据库)
-	post, err := logic.GetPostById(postId)
+	post, err := logic.GetPostById(getViewer(c), postId)
 	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
 		zap.L().Error("logic.GetPost(postID) failed", zap.Error(err))
 		ResponseError(c,CodeServerBusy)
+		return
 	}
 
 	// 3、返回响应
@@ -203,7 +244,7 @@ func GetCommunityPostListHandler(c *gin.Context)  {
 		return
 	}
 	// 获取数据
-	data, err := logic.GetCommunityPostList(p)
+	data, err := logic.GetPostListNew(getViewer(c), p)
 	if err != nil {
 		ResponseError(c, CodeServerBusy)
 		return
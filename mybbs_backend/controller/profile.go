@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// 用户资料
+
+// getUserIDParam 获取路径中的用户id
+func getUserIDParam(c *gin.Context) (uint64, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return 0, false
+	}
+	return userID, true
+}
+
+// UserProfileHandler 用户公开资料
+// @Summary 用户公开资料
+// @Description 查询用户的昵称、简介、头像、karma及发帖、评论数
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param id path string true "用户id"
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id} [get]
+func UserProfileHandler(c *gin.Context) {
+	userID, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+	profile, err := logic.GetUserProfile(getViewer(c), userID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExit) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		zap.L().Error("logic.GetUserProfile failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, profile)
+}
+
+// UserPostListHandler 用户发布的帖子
+// @Summary 用户发布的帖子
+// @Description 分页查询用户发布的帖子, 最新的在前
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param id path string true "用户id"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/posts [get]
+func UserPostListHandler(c *gin.Context) {
+	userID, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+	page, size := getPageInfo(c)
+	data, err := logic.GetUserPostList(getViewer(c), userID, page, size)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExit) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		zap.L().Error("logic.GetUserPostList failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// UserCommentListHandler 用户发表的评论
+// @Summary 用户发表的评论
+// @Description 分页查询用户发表的评论, 最新的在前
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param id path string true "用户id"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Success 200 {object} _ResponsePostList
+// @Router /user/{id}/comments [get]
+func UserCommentListHandler(c *gin.Context) {
+	userID, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+	page, size := getPageInfo(c)
+	data, err := logic.GetUserCommentList(getViewer(c), userID, page, size)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExit) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		zap.L().Error("logic.GetUserCommentList failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// UserIdenticonHandler 自动生成的默认头像
+// @Summary 默认头像
+// @Description 根据用户id生成的identicon头像(PNG)
+// @Tags 用户业务接口
+// @Produce image/png
+// @Param id path string true "用户id"
+// @Router /user/{id}/identicon [get]
+func UserIdenticonHandler(c *gin.Context) {
+	userID, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+	png, err := logic.Identicon(userID)
+	if err != nil {
+		zap.L().Error("logic.Identicon failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	// 同一个用户的identicon永远不变
+	c.Header("Cache-Control", "public, max-age=604800")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// UpdateProfileHandler 修改个人资料
+// @Summary 修改个人资料
+// @Description 修改昵称及个人简介
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.ProfileForm true "个人资料"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/profile [put]
+func UpdateProfileHandler(c *gin.Context) {
+	p := new(models.ProfileForm)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.UpdateProfile(userID, p); err != nil {
+		if errors.Is(err, logic.ErrorNicknameEmpty) {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		zap.L().Error("logic.UpdateProfile failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UploadAvatarHandler 上传头像
+// @Summary 上传头像
+// @Description 上传png/jpeg/gif格式的头像, 不超过2MB
+// @Tags 用户业务接口
+// @Accept multipart/form-data
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param avatar formData file true "头像图片"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/avatar [post]
+func UploadAvatarHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	// 请求体是multipart表单, 包含分隔符及各部分的头, 在头像大小限制之外多留1MiB,
+	// 稍大于限制的文件仍能完整读出, 由下面的fh.Size判断后返回头像过大
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, logic.AvatarMaxSize+(1<<20))
+	fh, err := c.FormFile("avatar")
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if fh.Size > logic.AvatarMaxSize {
+		ResponseError(c, CodeAvatarTooLarge)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	avatar, err := logic.UploadAvatar(userID, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, logic.ErrorAvatarInvalid):
+			ResponseError(c, CodeAvatarInvalid)
+		case errors.Is(err, logic.ErrorAvatarTooLarge):
+			ResponseError(c, CodeAvatarTooLarge)
+		default:
+			zap.L().Error("logic.UploadAvatar failed", zap.Error(err))
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(c, gin.H{"avatar": avatar})
+}
+
+// ResetAvatarHandler 恢复默认头像
+// @Summary 恢复默认头像
+// @Description 删除上传的头像, 恢复为自动生成的identicon
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/avatar [delete]
+func ResetAvatarHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.ResetAvatar(userID); err != nil {
+		zap.L().Error("logic.ResetAvatar failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
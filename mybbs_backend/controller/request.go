@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"bluebell_backend/logic"
+	"bluebell_backend/pkg/jwt"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"strconv"
 )
 
 const (
 	ContextUserIDKey = "userID"
+	ContextClaimsKey = "claims"
 )
 
 var (
@@ -43,6 +47,43 @@ func getCurrentUserID(c *gin.Context) (userID uint64, err error) {
 	return
 }
 
+// getCurrentClaims 获取当前请求携带的access token的claims
+func getCurrentClaims(c *gin.Context) (claims *jwt.MyClaims, err error) {
+	_claims, ok := c.Get(ContextClaimsKey)
+	if !ok {
+		err = ErrorUserNotLogin
+		return
+	}
+	claims, ok = _claims.(*jwt.MyClaims)
+	if !ok {
+		err = ErrorUserNotLogin
+	}
+	return
+}
+
+// bindJSON 绑定并校验请求参数, 失败时返回翻译后的提示信息
+func bindJSON(c *gin.Context, p interface{}) bool {
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return false
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return false
+	}
+	return true
+}
+
+// getViewer 获取当前访问的用户及其角色, 未登录时返回nil
+func getViewer(c *gin.Context) *logic.Viewer {
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		return nil
+	}
+	return &logic.Viewer{UserID: claims.UserID, Roles: claims.Roles}
+}
+
 /**
  * @Author huchao
  * @Description //TODO 分页参数
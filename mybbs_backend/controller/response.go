@@ -2,6 +2,8 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,7 +35,7 @@ func ResponseErrorWithMsg(ctx *gin.Context, code MyCode, data interface{}) {
 	rd := &ResponseData{
 		Code:    code,
 		Message: code.Msg(),
-		Data:    nil,
+		Data:    data,
 	}
 	ctx.JSON(http.StatusOK, rd)
 }
@@ -46,3 +48,14 @@ func ResponseSuccess(ctx *gin.Context, data interface{}) {
 	}
 	ctx.JSON(http.StatusOK, rd)
 }
+
+// ResponseTooManyRequests 触发限流时返回429, Retry-After为需要等待的秒数
+func ResponseTooManyRequests(ctx *gin.Context, wait time.Duration) {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+	ctx.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	ctx.JSON(http.StatusTooManyRequests, &ResponseData{
+		Code:    CodeTooManyRequests,
+		Message: CodeTooManyRequests.Msg(),
+		Data:    gin.H{"retry_after": seconds},
+	})
+}
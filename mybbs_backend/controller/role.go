@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// 角色管理, 只有管理员可以访问
+
+// responseRoleError 将角色管理的业务错误转换成响应
+func responseRoleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorUserNotExit):
+		ResponseError(c, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorInvalidRole):
+		ResponseError(c, CodeInvalidRole)
+	case errors.Is(err, logic.ErrorRoleNotExist):
+		ResponseError(c, CodeRoleNotExist)
+	case errors.Is(err, logic.ErrorLastAdmin):
+		ResponseError(c, CodeLastAdmin)
+	default:
+		zap.L().Error("manage role failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// bindRoleForm 获取并校验角色参数
+func bindRoleForm(c *gin.Context) (*models.RoleForm, bool) {
+	p := new(models.RoleForm)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return nil, false
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return nil, false
+	}
+	return p, true
+}
+
+// UserRoleListHandler 用户的角色
+// @Summary 用户的角色
+// @Description 查询用户拥有的全部角色(管理员)
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "用户id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/users/{id}/roles [get]
+func UserRoleListHandler(c *gin.Context) {
+	userID, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+	roles, err := logic.GetUserRoleList(userID)
+	if err != nil {
+		responseRoleError(c, err)
+		return
+	}
+	ResponseSuccess(c, roles)
+}
+
+// GrantRoleHandler 授予角色
+// @Summary 授予角色
+// @Description 授予管理员或某个社区的版主角色(管理员), 用户下一次刷新token后生效
+// @Tags 管理后台接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.RoleForm true "角色"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/roles [post]
+func GrantRoleHandler(c *gin.Context) {
+	p, ok := bindRoleForm(c)
+	if !ok {
+		return
+	}
+	if err := logic.GrantRole(p); err != nil {
+		responseRoleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RevokeRoleHandler 撤销角色
+// @Summary 撤销角色
+// @Description 撤销用户的角色(管理员), 用户下一次刷新token后生效
+// @Tags 管理后台接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.RoleForm true "角色"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/roles [delete]
+func RevokeRoleHandler(c *gin.Context) {
+	p, ok := bindRoleForm(c)
+	if !ok {
+		return
+	}
+	if err := logic.RevokeRole(p); err != nil {
+		responseRoleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// 社区的发帖规则及发帖模板
+
+// CommunityRuleHandler 社区的发帖规则
+// @Summary 社区的发帖规则
+// @Description 查询社区的发帖规则及发帖模板, 客户端据此预填内容及展示需要填写的字段
+// @Tags 社区业务接口
+// @Produce application/json
+// @Param id path int true "社区id"
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/rules [get]
+func CommunityRuleHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	rule, err := logic.GetCommunityRule(getViewer(c), communityID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
+		zap.L().Error("logic.GetCommunityRule failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, rule)
+}
+
+// UpdateCommunityRuleHandler 修改社区的发帖规则
+// @Summary 修改社区的发帖规则
+// @Description 修改社区的发帖规则及发帖模板(版主), 模板字段的rule使用validator的校验规则, 如 required,url
+// @Tags 社区管理接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param object body models.CommunityRule true "发帖规则"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/rules [put]
+func UpdateCommunityRuleHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	p := new(models.CommunityRule)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	if err := logic.SaveCommunityRule(communityID, p); err != nil {
+		switch {
+		case errors.Is(err, mysql.ErrorInvalidID):
+			ResponseError(c, CodeCommunityNotExist)
+		case errors.Is(err, logic.ErrorInvalidRule):
+			ResponseError(c, CodeInvalidRule)
+		default:
+			zap.L().Error("logic.SaveCommunityRule failed", zap.Error(err))
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UpdateSlowModeHandler 设置社区的慢速模式
+// @Summary 设置社区的慢速模式
+// @Description 版主设置每个用户两次发帖的最小间隔(分钟), 0表示关闭; 间隔未到时发帖返回429及Retry-After
+// @Tags 社区管理接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "社区id"
+// @Param object body models.SlowModeForm true "慢速模式"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /community/{id}/slow_mode [put]
+func UpdateSlowModeHandler(c *gin.Context) {
+	communityID, ok := getCommunityIDParam(c)
+	if !ok {
+		return
+	}
+	p := new(models.SlowModeForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logic.SetSlowMode(communityID, p); err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeCommunityNotExist)
+			return
+		}
+		zap.L().Error("logic.SetSlowMode failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bluebell_backend/logic"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 设备会话管理
+
+// SessionListHandler 查询当前用户的设备会话
+// @Summary 设备会话列表
+// @Description 查询当前用户已登录的设备(User-Agent、IP、最近活跃时间)
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/sessions [get]
+func SessionListHandler(c *gin.Context) {
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	sessions, err := logic.GetUserSessions(claims.UserID, claims.FamilyID)
+	if err != nil {
+		zap.L().Error("logic.GetUserSessions failed", zap.Uint64("user_id", claims.UserID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, sessions)
+}
+
+// SessionRevokeHandler 注销指定的设备会话
+// @Summary 注销设备会话
+// @Description 注销指定的设备会话, 该设备需要重新登录
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path string true "会话ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/sessions/{id} [delete]
+func SessionRevokeHandler(c *gin.Context) {
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.RevokeSession(claims.UserID, c.Param("id")); err != nil {
+		if errors.Is(err, logic.ErrorSessionNotExist) {
+			ResponseError(c, CodeSessionNotExist)
+			return
+		}
+		zap.L().Error("logic.RevokeSession failed", zap.Uint64("user_id", claims.UserID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// SessionRevokeOthersHandler 注销除当前设备外的所有会话
+// @Summary 注销其他设备
+// @Description 注销除当前设备外的所有会话
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/sessions [delete]
+func SessionRevokeOthersHandler(c *gin.Context) {
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.RevokeOtherSessions(claims.UserID, claims.FamilyID); err != nil {
+		zap.L().Error("logic.RevokeOtherSessions failed", zap.Uint64("user_id", claims.UserID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
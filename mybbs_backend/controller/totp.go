@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/jwt"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// 两步验证
+
+// responseTOTPError 将两步验证的业务错误转换成响应
+func responseTOTPError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.ErrorTOTPNotEnrolled):
+		ResponseError(c, CodeTOTPNotEnrolled)
+	case errors.Is(err, logic.ErrorTOTPAlreadyEnabled):
+		ResponseError(c, CodeTOTPAlreadyEnabled)
+	case errors.Is(err, logic.ErrorTOTPInvalidCode):
+		ResponseError(c, CodeInvalidTOTPCode)
+	case errors.Is(err, logic.ErrorInvalidChallenge):
+		ResponseError(c, CodeInvalidChallenge)
+	case errors.Is(err, logic.ErrorTooManyAttempts):
+		ResponseError(c, CodeTooManyAttempts)
+	default:
+		zap.L().Error("two factor auth failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// bindTOTPCode 获取并校验验证码参数
+func bindTOTPCode(c *gin.Context) (*models.TOTPCodeForm, bool) {
+	p := new(models.TOTPCodeForm)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return nil, false
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return nil, false
+	}
+	return p, true
+}
+
+// TOTPEnrollHandler 生成两步验证密钥
+// @Summary 生成两步验证密钥
+// @Description 返回TOTP密钥、otpauth URI及二维码, 提交一次验证码确认后才会启用
+// @Tags 用户业务接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/2fa/enroll [post]
+func TOTPEnrollHandler(c *gin.Context) {
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	data, err := logic.EnrollTOTP(claims.UserID, claims.Username)
+	if err != nil {
+		responseTOTPError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// TOTPQRCodeHandler 待确认的两步验证密钥二维码
+// @Summary 两步验证二维码
+// @Description 以PNG图片返回待确认的TOTP密钥二维码
+// @Tags 用户业务接口
+// @Produce image/png
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Router /user/2fa/qrcode [get]
+func TOTPQRCodeHandler(c *gin.Context) {
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	png, err := logic.GetTOTPQRCode(claims.UserID, claims.Username)
+	if err != nil {
+		responseTOTPError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// TOTPConfirmHandler 确认并开启两步验证
+// @Summary 开启两步验证
+// @Description 提交验证器App上的验证码, 成功后开启两步验证并返回恢复码(只返回这一次)
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.TOTPCodeForm true "验证码"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/2fa/confirm [post]
+func TOTPConfirmHandler(c *gin.Context) {
+	p, ok := bindTOTPCode(c)
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	codes, err := logic.ConfirmTOTP(userID, p.Code)
+	if err != nil {
+		responseTOTPError(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"recovery_codes": codes})
+}
+
+// TOTPDisableHandler 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 提交当前的验证码关闭两步验证
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.TOTPCodeForm true "验证码"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /user/2fa/disable [post]
+func TOTPDisableHandler(c *gin.Context) {
+	p, ok := bindTOTPCode(c)
+	if !ok {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.DisableTOTP(userID, p.Code); err != nil {
+		responseTOTPError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// LoginTwoFactorHandler 两步验证登录
+// @Summary 两步验证登录
+// @Description 使用登录返回的challenge_token及验证码(或恢复码)换取access token和refresh token
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param object body models.TwoFactorLoginForm true "两步验证参数"
+// @Success 200 {object} _ResponsePostList
+// @Router /login/2fa [post]
+func LoginTwoFactorHandler(c *gin.Context) {
+	p := new(models.TwoFactorLoginForm)
+	if err := c.ShouldBindJSON(p); err != nil {
+		zap.L().Error("LoginTwoFactor with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParams, removeTopStruct(errs.Translate(trans)))
+		return
+	}
+	aToken, rToken, err := logic.LoginTwoFactor(p, &jwt.Device{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		responseTOTPError(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"access_token":  aToken,
+		"refresh_token": rToken,
+	})
+}
@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -116,7 +115,10 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 	// 2、业务逻辑处理——登录
-	user, err := logic.Login(u)
+	user, err := logic.Login(u, &jwt.Device{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
 	if err != nil {
 		zap.L().Error("logic.Login failed", zap.String("username", u.UserName), zap.Error(err))
 		if errors.Is(err, mysql.ErrorUserNotExit) {
@@ -126,6 +128,14 @@ func LoginHandler(c *gin.Context) {
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
+	// 开启了两步验证, 需要再调用 /login/2fa 提交验证码
+	if len(user.ChallengeToken) > 0 {
+		ResponseSuccess(c, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     user.ChallengeToken,
+		})
+		return
+	}
 	// 3、返回响应
 	ResponseSuccess(c, gin.H{
 		"user_id":       fmt.Sprintf("%d", user.UserID), //js识别的最大值：id值大于1<<53-1  int64: i<<63-1
@@ -146,33 +156,90 @@ func LoginHandler(c *gin.Context) {
 // @Tags 用户业务接口
 // @Accept application/json
 // @Produce application/json
-// @Param Authorization header string true "Bearer 用户令牌"
-// @Param object query models.ParamPostList false "查询参数"
-// @Security ApiKeyAuth
+// @Param object body models.RefreshTokenForm false "refresh token(也可以放在query中)"
 // @Success 200 {object} _ResponsePostList
-// @Router /refresh_token [GET]
+// @Router /refresh_token [POST]
 func RefreshTokenHandler(c *gin.Context) {
-	rt := c.Query("refresh_token")
-	// 客户端携带Token有三种方式 1.放在请求头 2.放在请求体 3.放在URI
-	// 这里假设Token放在Header的Authorization中，并使用Bearer开头
-	// 这里的具体实现方式要依据你的实际业务情况决定
-	authHeader := c.Request.Header.Get("Authorization")
-	if authHeader == "" {
-		ResponseErrorWithMsg(c, CodeInvalidToken, "请求头缺少Auth Token")
-		c.Abort()
+	// refresh token 放在请求体中, 兼容旧客户端放在URI中的方式(GET, 或没有请求体的POST)
+	p := &models.RefreshTokenForm{RefreshToken: c.Query("refresh_token")}
+	if p.RefreshToken != "" {
+		// 与access_token参数一样, 不在访问日志中记录token
+		q := c.Request.URL.Query()
+		q.Del("refresh_token")
+		c.Request.URL.RawQuery = q.Encode()
+	}
+	if c.Request.Method == http.MethodPost && c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(p); err != nil {
+			zap.L().Error("RefreshToken with invalid param", zap.Error(err))
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+	}
+	if len(p.RefreshToken) == 0 {
+		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	// 按空格分割
-	parts := strings.SplitN(authHeader, " ", 2)
-	if !(len(parts) == 2 && parts[0] == "Bearer") {
-		ResponseErrorWithMsg(c, CodeInvalidToken, "Token格式不对")
-		c.Abort()
+	aToken, rToken, err := jwt.RefreshToken(p.RefreshToken)
+	if err != nil {
+		zap.L().Warn("jwt.RefreshToken failed", zap.Error(err))
+		switch {
+		case errors.Is(err, jwt.ErrorRefreshTokenReused):
+			ResponseError(c, CodeRefreshTokenReused)
+		case errors.Is(err, jwt.ErrorRefreshTokenInvalid):
+			ResponseError(c, CodeInvalidRefreshToken)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
 		return
 	}
-	aToken, rToken, err := jwt.RefreshToken(parts[1], rt)
-	fmt.Println(err)
-	c.JSON(http.StatusOK, gin.H{
+	ResponseSuccess(c, gin.H{
 		"access_token":  aToken,
 		"refresh_token": rToken,
 	})
 }
+
+// LogoutHandler 登出
+// @Summary 登出
+// @Description 吊销当前的access token及可选的refresh token
+// @Tags 用户业务接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.LogoutForm false "登出参数"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /logout [POST]
+func LogoutHandler(c *gin.Context) {
+	p := new(models.LogoutForm)
+	// 请求体可以为空, 此时只吊销access token
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(p); err != nil {
+			zap.L().Error("Logout with invalid param", zap.Error(err))
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+	}
+	claims, err := getCurrentClaims(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	if err := logic.Logout(claims, p); err != nil {
+		zap.L().Error("logic.Logout failed", zap.Uint64("user_id", claims.UserID), zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// JWKSHandler 公开JWT验签公钥
+// @Summary JWKS
+// @Description 以JWKS格式返回所有非对称验签公钥, 供其他服务校验本服务签发的token
+// @Tags 用户业务接口
+// @Produce application/json
+// @Success 200 {object} jwt.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func JWKSHandler(c *gin.Context) {
+	// JWKS是标准格式, 不包装成ResponseData
+	c.JSON(http.StatusOK, jwt.JWKS())
+}
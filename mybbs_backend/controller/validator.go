@@ -60,11 +60,45 @@ func InitTrans(locale string) (err error) {
 		default:
 			err = enTranslations.RegisterDefaultTranslations(v, trans)
 		}
+		if err != nil {
+			return
+		}
+		// 社区发帖规则中要求帖子至少带有一个指定标签
+		if err = v.RegisterValidation("anyof", anyOf); err != nil {
+			return
+		}
+		msg := "{0} must contain at least one of [{1}]"
+		if locale == "zh" {
+			msg = "{0}必须包含[{1}]中的至少一个"
+		}
+		err = v.RegisterTranslation("anyof", trans, func(ut ut.Translator) error {
+			return ut.Add("anyof", msg, true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("anyof", fe.Field(), fe.Param())
+			return t
+		})
 		return
 	}
 	return
 }
 
+// anyOf 自定义校验: 字符串列表中至少有一个元素在参数给出的(空格分隔)集合中
+func anyOf(fl validator.FieldLevel) bool {
+	allowed := strings.Fields(fl.Param())
+	field := fl.Field()
+	if field.Kind() != reflect.Slice {
+		return false
+	}
+	for i := 0; i < field.Len(); i++ {
+		for _, a := range allowed {
+			if field.Index(i).String() == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //定义一个去掉结构体名称前缀的自定义方法：
 func removeTopStruct(fields map[string]string) map[string]string {
 	res := map[string]string{}
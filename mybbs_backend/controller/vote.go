@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bluebell_backend/dao/mysql"
 	"bluebell_backend/logic"
 	"bluebell_backend/models"
 	"encoding/json"
@@ -66,14 +67,21 @@ func VoteHandler(c *gin.Context) {
 		ResponseErrorWithMsg(c, CodeInvalidParams, errdata)
 		return
 	}
-	// 获取当前请求用户的id
-	userID, err := getCurrentUserID(c)
-	if err != nil {
+	// 获取当前请求的用户
+	viewer := getViewer(c)
+	if viewer == nil {
 		ResponseError(c, CodeNotLogin)
 		return
 	}
 	// 具体投票的业务逻辑
-	if err := logic.VoteForPost(userID, vote); err != nil {
+	if err := logic.VoteForPost(viewer, vote); err != nil {
+		if responseBanned(c, err) {
+			return
+		}
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
 		zap.L().Error("logic.VoteForPost() failed",zap.Error(err))
 		ResponseError(c, CodeServerBusy)
 		return
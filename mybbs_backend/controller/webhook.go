@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/logic"
+	"bluebell_backend/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 管理后台: webhook及其投递记录
+
+// webhookError webhook相关的错误处理
+func webhookError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, logic.ErrorWebhookNotExist):
+		ResponseError(c, CodeWebhookNotExist)
+	case errors.Is(err, logic.ErrorDeliveryNotExist):
+		ResponseError(c, CodeDeliveryNotExist)
+	case errors.Is(err, logic.ErrorDeliveryNotFailed):
+		ResponseError(c, CodeDeliveryNotFailed)
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(c, CodeCommunityNotExist)
+	case errors.Is(err, logic.ErrorWebhookURL):
+		ResponseErrorWithMsg(c, CodeInvalidParams, err.Error())
+	default:
+		zap.L().Error(msg, zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+	}
+}
+
+// getWebhookIDParam 获取路径中的webhook id或投递记录id
+func getWebhookIDParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParams)
+		return 0, false
+	}
+	return id, true
+}
+
+// WebhookListHandler 全部webhook
+// @Summary 全部webhook
+// @Description 查询注册的webhook(管理员), 不返回签名密钥
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/webhooks [get]
+func WebhookListHandler(c *gin.Context) {
+	list, err := logic.GetWebhookList()
+	if err != nil {
+		webhookError(c, err, "logic.GetWebhookList failed")
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// CreateWebhookHandler 注册webhook
+// @Summary 注册webhook
+// @Description 按事件类型及社区注册webhook(管理员), community_id为0时接收所有社区的事件(私有社区除外, 私有社区的事件需要在该社区上注册); 签名密钥只在创建时返回
+// @Tags 管理后台接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param object body models.WebhookForm true "webhook"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/webhooks [post]
+func CreateWebhookHandler(c *gin.Context) {
+	p := new(models.WebhookForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNotLogin)
+		return
+	}
+	hook, err := logic.CreateWebhook(userID, p)
+	if err != nil {
+		webhookError(c, err, "logic.CreateWebhook failed")
+		return
+	}
+	ResponseSuccess(c, hook)
+}
+
+// UpdateWebhookHandler 修改webhook
+// @Summary 修改webhook
+// @Description 修改webhook的地址、事件、社区及是否启用(管理员), 签名密钥不变
+// @Tags 管理后台接口
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "webhook id"
+// @Param object body models.WebhookForm true "webhook"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/webhooks/{id} [put]
+func UpdateWebhookHandler(c *gin.Context) {
+	id, ok := getWebhookIDParam(c)
+	if !ok {
+		return
+	}
+	p := new(models.WebhookForm)
+	if !bindJSON(c, p) {
+		return
+	}
+	hook, err := logic.UpdateWebhook(id, p)
+	if err != nil {
+		webhookError(c, err, "logic.UpdateWebhook failed")
+		return
+	}
+	ResponseSuccess(c, hook)
+}
+
+// DeleteWebhookHandler 删除webhook
+// @Summary 删除webhook
+// @Description 删除webhook及其投递记录(管理员)
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "webhook id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/webhooks/{id} [delete]
+func DeleteWebhookHandler(c *gin.Context) {
+	id, ok := getWebhookIDParam(c)
+	if !ok {
+		return
+	}
+	if err := logic.DeleteWebhook(id); err != nil {
+		webhookError(c, err, "logic.DeleteWebhook failed")
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// WebhookDeliveryListHandler webhook的投递记录
+// @Summary webhook的投递记录
+// @Description 分页查询webhook的投递记录, 新的在前; status: 0待投递 1成功 2失败, 不传时查询全部
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "webhook id"
+// @Param status query int false "投递状态"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/webhooks/{id}/deliveries [get]
+func WebhookDeliveryListHandler(c *gin.Context) {
+	id, ok := getWebhookIDParam(c)
+	if !ok {
+		return
+	}
+	status := int8(-1)
+	if s := c.Query("status"); len(s) > 0 {
+		v, err := strconv.ParseInt(s, 10, 8)
+		if err != nil || v < 0 {
+			ResponseError(c, CodeInvalidParams)
+			return
+		}
+		status = int8(v)
+	}
+	page, size := getPageInfo(c)
+	list, err := logic.GetWebhookDeliveryList(id, status, page, size)
+	if err != nil {
+		webhookError(c, err, "logic.GetWebhookDeliveryList failed")
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// ReplayFailedDeliveriesHandler 重新投递webhook所有失败的记录
+// @Summary 重新投递webhook所有失败的记录
+// @Description 重试次数用完的记录各创建一条新的投递记录(管理员), 已经重新投递过的不再重复
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "webhook id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/webhooks/{id}/replay [post]
+func ReplayFailedDeliveriesHandler(c *gin.Context) {
+	id, ok := getWebhookIDParam(c)
+	if !ok {
+		return
+	}
+	n, err := logic.ReplayFailedDeliveries(id)
+	if err != nil {
+		webhookError(c, err, "logic.ReplayFailedDeliveries failed")
+		return
+	}
+	ResponseSuccess(c, gin.H{"replayed": n})
+}
+
+// ReplayDeliveryHandler 重新投递一条失败的记录
+// @Summary 重新投递一条失败的记录
+// @Description 复制失败记录的请求体创建一条新的投递记录(管理员)
+// @Tags 管理后台接口
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "投递记录id"
+// @Security ApiKeyAuth
+// @Success 200 {object} _ResponsePostList
+// @Router /admin/webhooks/deliveries/{id}/replay [post]
+func ReplayDeliveryHandler(c *gin.Context) {
+	id, ok := getWebhookIDParam(c)
+	if !ok {
+		return
+	}
+	d, err := logic.ReplayDelivery(id)
+	if err != nil {
+		webhookError(c, err, "logic.ReplayDelivery failed")
+		return
+	}
+	ResponseSuccess(c, d)
+}
@@ -0,0 +1,58 @@
+package mysql
+
+import "bluebell_backend/models"
+
+// 用户屏蔽
+
+// IsBlocked userID是否屏蔽了targetID
+func IsBlocked(userID, targetID uint64) (bool, error) {
+	var count int64
+	err := db.Get(&count, `select count(*) from user_block where user_id = ? and blocked_id = ?`, userID, targetID)
+	return count > 0, err
+}
+
+// BlockUser 屏蔽用户并解除双方的关注关系, 重复屏蔽不报错
+func BlockUser(userID, targetID uint64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`insert ignore into user_block(user_id, blocked_id) values(?,?)`, userID, targetID); err != nil {
+		return
+	}
+	sqlStr := `delete from user_follow where (user_id = ? and followee_id = ?) or (user_id = ? and followee_id = ?)`
+	if _, err = tx.Exec(sqlStr, userID, targetID, targetID, userID); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// UnblockUser 取消屏蔽, 未屏蔽时不报错
+func UnblockUser(userID, targetID uint64) (err error) {
+	_, err = db.Exec(`delete from user_block where user_id = ? and blocked_id = ?`, userID, targetID)
+	return
+}
+
+// GetBlockedIDs 查询用户屏蔽的全部用户
+func GetBlockedIDs(userID uint64) (ids []uint64, err error) {
+	ids = make([]uint64, 0)
+	err = db.Select(&ids, `select blocked_id from user_block where user_id = ?`, userID)
+	return
+}
+
+// GetBlockedUserList 查询用户屏蔽的用户, 最近屏蔽的在前
+func GetBlockedUserList(userID uint64) (list []*models.UserBrief, err error) {
+	sqlStr := `select u.user_id, ` + nicknameColumn + `, u.avatar
+	from user_block b
+	join user u on u.user_id = b.blocked_id
+	where b.user_id = ?
+	order by b.create_time desc`
+	list = make([]*models.UserBrief, 0)
+	err = db.Select(&list, sqlStr, userID)
+	return
+}
@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 收藏夹及收藏的帖子
+
+// CreateBookmarkFolder 创建收藏夹, 同一用户的收藏夹不能重名
+func CreateBookmarkFolder(userID uint64, name string) (id uint64, err error) {
+	res, err := db.Exec(`insert into bookmark_folder(user_id, name) values(?,?)`, userID, name)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			err = ErrorFolderExist
+		}
+		return
+	}
+	lastID, err := res.LastInsertId()
+	return uint64(lastID), err
+}
+
+// RenameBookmarkFolder 重命名收藏夹
+func RenameBookmarkFolder(userID, folderID uint64, name string) (err error) {
+	sqlStr := `update bookmark_folder set name = ? where folder_id = ? and user_id = ?`
+	if _, err = db.Exec(sqlStr, name, folderID, userID); err != nil && isDuplicateEntry(err) {
+		err = ErrorFolderExist
+	}
+	return
+}
+
+// DeleteBookmarkFolder 删除收藏夹, 其中收藏的帖子移到未分类
+func DeleteBookmarkFolder(userID, folderID uint64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`update bookmark set folder_id = 0 where user_id = ? and folder_id = ?`, userID, folderID); err != nil {
+		return
+	}
+	if _, err = tx.Exec(`delete from bookmark_folder where folder_id = ? and user_id = ?`, folderID, userID); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// GetBookmarkFolder 查询用户的收藏夹, 不存在或不属于该用户时返回ErrorInvalidID
+func GetBookmarkFolder(userID, folderID uint64) (folder *models.BookmarkFolder, err error) {
+	folder = new(models.BookmarkFolder)
+	sqlStr := `select folder_id, name, create_time from bookmark_folder where folder_id = ? and user_id = ?`
+	err = db.Get(folder, sqlStr, folderID, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorInvalidID
+	}
+	return
+}
+
+// GetBookmarkFolderList 查询用户的收藏夹及其中的帖子数, 按创建时间排序
+func GetBookmarkFolderList(userID uint64) (list []*models.BookmarkFolder, err error) {
+	sqlStr := `select f.folder_id, f.name, f.create_time,
+	(select count(*) from bookmark where user_id = f.user_id and folder_id = f.folder_id) as post_count
+	from bookmark_folder f
+	where f.user_id = ?
+	order by f.create_time, f.folder_id`
+	list = make([]*models.BookmarkFolder, 0)
+	err = db.Select(&list, sqlStr, userID)
+	return
+}
+
+// SavePost 收藏帖子, 已收藏时移动到指定的收藏夹
+func SavePost(userID, postID, folderID uint64) (err error) {
+	sqlStr := `insert into bookmark(user_id, post_id, folder_id) values(?,?,?)
+	on duplicate key update folder_id = values(folder_id)`
+	_, err = db.Exec(sqlStr, userID, postID, folderID)
+	return
+}
+
+// UnsavePost 取消收藏, 未收藏时不报错
+func UnsavePost(userID, postID uint64) (err error) {
+	_, err = db.Exec(`delete from bookmark where user_id = ? and post_id = ?`, userID, postID)
+	return
+}
+
+// GetBookmarks 查询帖子中用户收藏了的帖子及所在的收藏夹
+func GetBookmarks(userID uint64, postIDs []uint64) (list []*models.Bookmark, err error) {
+	if len(postIDs) == 0 {
+		return
+	}
+	query, args, err := sqlx.In(`select post_id, folder_id from bookmark where user_id = ? and post_id in (?)`, userID, postIDs)
+	if err != nil {
+		return
+	}
+	err = db.Select(&list, db.Rebind(query), args...)
+	return
+}
+
+// GetSavedPostList 分页查询用户收藏的帖子, 最近收藏的在前; folderID为nil时查询全部收藏夹
+func GetSavedPostList(userID uint64, folderID *uint64, page, size int64, hidden []uint64, hideNSFW bool) (posts []*models.Post, err error) {
+	cond, hiddenArgs := notInCommunities("p.community_id", hidden)
+	cond += notNSFW("p.nsfw", hideNSFW)
+	args := []interface{}{userID}
+	if folderID != nil {
+		cond = ` and b.folder_id = ?` + cond
+		args = append(args, *folderID)
+	}
+	sqlStr := `select p.post_id, p.title, p.content, p.author_id, p.community_id, p.pinned, p.locked, p.tags, p.attachments, p.fields,
+	p.flair_id, p.nsfw, p.spoiler, p.create_time
+	from bookmark b
+	join post p on p.post_id = b.post_id
+	where b.user_id = ? and p.status = 1` + cond + `
+	order by b.create_time desc
+	limit ?,?`
+	args = append(append(args, hiddenArgs...), (page-1)*size, size)
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return
+	}
+	posts = make([]*models.Post, 0, size)
+	err = db.Select(&posts, db.Rebind(query), args...)
+	return
+}
@@ -22,12 +22,14 @@ func CreateComment(comment *models.Comment) (err error) {
 	return
 }
 
-func GetCommentListByIDs(ids []string) (commentList []*models.Comment, err error) {
-	sqlStr := `select comment_id, content, post_id, author_id, parent_id, create_time
-	from comment
-	where comment_id in (?)`
+func GetCommentListByIDs(ids []string, hidden []uint64) (commentList []*models.Comment, err error) {
+	cond, args := notInCommunities("p.community_id", hidden)
+	sqlStr := `select c.comment_id, c.content, c.post_id, c.author_id, c.parent_id, c.create_time
+	from comment c
+	join post p on p.post_id = c.post_id
+	where c.comment_id in (?) and c.status = 1 and p.status = 1` + cond
 	// 动态填充id
-	query, args, err := sqlx.In(sqlStr, ids)
+	query, args, err := sqlx.In(sqlStr, append([]interface{}{ids}, args...)...)
 	if err != nil {
 		return
 	}
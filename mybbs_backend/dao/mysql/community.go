@@ -3,6 +3,10 @@ package mysql
 import (
 	"bluebell_backend/models"
 	"database/sql"
+	"errors"
+	"strings"
+
+	driver "github.com/go-sql-driver/mysql"
 
 	"go.uber.org/zap"
 )
@@ -13,7 +17,9 @@ import (
  * @Date 16:42 2022/2/12
  **/
 func GetCommunityList() (communityList []*models.Community, err error) {
-	sqlStr := "select community_id, community_name from community"
+	sqlStr := `select community_id, community_name, visibility from community
+	where status = 1
+	order by sort_order, community_id`
 	err = db.Select(&communityList, sqlStr)
 	if err == sql.ErrNoRows {	// 查询为空
 		zap.L().Warn("there is no community in db")
@@ -47,7 +53,7 @@ func GetCommunityNameByID(idStr string) (community *models.Community, err error)
  **/
 func GetCommunityByID(id uint64) (community *models.CommunityDetail, err error) {
 	community = new(models.CommunityDetail)
-	sqlStr := `select community_id, community_name, introduction, create_time
+	sqlStr := `select community_id, community_name, introduction, status, visibility, sort_order, slow_mode, create_time
 	from community
 	where community_id = ?`
 	err = db.Get(community, sqlStr, id)
@@ -61,3 +67,109 @@ func GetCommunityByID(id uint64) (community *models.CommunityDetail, err error)
 	}
 	return community,err
 }
+
+// GetAllCommunities 查询包括已归档在内的全部社区(管理后台)
+func GetAllCommunities() (list []*models.CommunityDetail, err error) {
+	sqlStr := `select community_id, community_name, introduction, status, visibility, sort_order, slow_mode, create_time
+	from community
+	order by sort_order, community_id`
+	list = make([]*models.CommunityDetail, 0)
+	err = db.Select(&list, sqlStr)
+	return
+}
+
+// CheckCommunityNameExist 社区名称是否已被其他社区使用
+func CheckCommunityNameExist(name string, excludeID uint64) (bool, error) {
+	var count int64
+	sqlStr := `select count(*) from community where community_name = ? and community_id != ?`
+	if err := db.Get(&count, sqlStr, name, excludeID); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// createCommunityRetry 同时创建社区时community_id可能冲突, 冲突时重试的次数
+const createCommunityRetry = 3
+
+// CreateCommunity 创建社区, community_id在现有最大值的基础上递增, 新社区排在最后
+// 并发创建时community_id冲突则重新取最大值, 只有名称冲突才返回ErrorCommunityExist
+func CreateCommunity(p *models.CommunityForm) (id uint64, err error) {
+	sqlStr := `insert into community(community_id, community_name, introduction, visibility, sort_order)
+	select ifnull(max(community_id), 0) + 1, ?, ?, ?, ifnull(max(sort_order), 0) + 1 from community`
+	for i := 0; i < createCommunityRetry; i++ {
+		if _, err = db.Exec(sqlStr, p.CommunityName, p.Introduction, p.Visibility); err == nil || !isDuplicateKey(err, "idx_community_id") {
+			break
+		}
+	}
+	if err != nil {
+		if isDuplicateKey(err, "idx_community_name") {
+			return 0, ErrorCommunityExist
+		}
+		zap.L().Error("insert community failed", zap.Error(err))
+		return 0, ErrorInsertFailed
+	}
+	err = db.Get(&id, `select community_id from community where community_name = ?`, p.CommunityName)
+	return
+}
+
+// UpdateCommunity 修改社区名称及简介
+func UpdateCommunity(id uint64, p *models.CommunityForm) (err error) {
+	sqlStr := `update community set community_name = ?, introduction = ?, visibility = ? where community_id = ?`
+	if _, err = db.Exec(sqlStr, p.CommunityName, p.Introduction, p.Visibility, id); err != nil && isDuplicateEntry(err) {
+		err = ErrorCommunityExist
+	}
+	return
+}
+
+// UpdateCommunityStatus 归档/恢复社区
+func UpdateCommunityStatus(id uint64, status int8) (err error) {
+	_, err = db.Exec(`update community set status = ? where community_id = ?`, status, id)
+	return
+}
+
+// UpdateCommunityOrder 按给定的顺序重新设置社区的排序, 未给出的社区排在后面
+func UpdateCommunityOrder(ids []uint64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`update community set sort_order = sort_order + ?`, len(ids)); err != nil {
+		return
+	}
+	for i, id := range ids {
+		if _, err = tx.Exec(`update community set sort_order = ? where community_id = ?`, i, id); err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// isDuplicateEntry 是否违反了唯一索引
+func isDuplicateEntry(err error) bool {
+	var e *driver.MySQLError
+	return errors.As(err, &e) && e.Number == 1062
+}
+
+// isDuplicateKey 是否违反了指定的唯一索引, 错误信息形如 Duplicate entry 'x' for key 'community.idx_community_name'
+func isDuplicateKey(err error, key string) bool {
+	var e *driver.MySQLError
+	return errors.As(err, &e) && e.Number == 1062 && strings.Contains(e.Message, key+"'")
+}
+
+// GetPrivateCommunityIDs 查询所有私有社区的id
+func GetPrivateCommunityIDs() (ids []uint64, err error) {
+	ids = make([]uint64, 0)
+	err = db.Select(&ids, `select community_id from community where visibility = ?`, models.CommunityPrivate)
+	return
+}
+
+// UpdateCommunitySlowMode 修改社区的慢速模式
+func UpdateCommunitySlowMode(id uint64, minutes int) (err error) {
+	_, err = db.Exec(`update community set slow_mode = ? where community_id = ?`, minutes, id)
+	return
+}
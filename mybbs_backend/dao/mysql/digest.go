@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+	"time"
+)
+
+// GetDigestSetting 查询用户的邮件摘要设置
+func GetDigestSetting(userID uint64) (setting *models.DigestSetting, err error) {
+	setting = new(models.DigestSetting)
+	err = db.Get(setting, `select digest_frequency from user where user_id = ?`, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorUserNotExit
+	}
+	return
+}
+
+// UpdateDigestFrequency 修改邮件摘要的发送频率
+func UpdateDigestFrequency(userID uint64, frequency int8) (err error) {
+	_, err = db.Exec(`update user set digest_frequency = ? where user_id = ?`, frequency, userID)
+	return
+}
+
+// GetDigestRecipients 按user_id顺序查询到期需要发送邮件摘要的用户, 从afterID之后开始
+// 上次发送时间早于before或从未发送过的用户到期, 没有邮箱的用户不发送
+func GetDigestRecipients(frequency int8, before time.Time, afterID uint64, limit int) (list []*models.DigestRecipient, err error) {
+	sqlStr := `select user_id, email, ` + nicknameColumn + `, digest_frequency
+	from user
+	where digest_frequency = ? and user_id > ?
+	and (digest_sent_time is null or digest_sent_time <= ?)
+	and email is not null and email != ''
+	order by user_id
+	limit ?`
+	list = make([]*models.DigestRecipient, 0)
+	err = db.Select(&list, sqlStr, frequency, afterID, before, limit)
+	return
+}
+
+// UpdateDigestSentTime 记录发送邮件摘要的时间
+func UpdateDigestSentTime(userID uint64, sentTime time.Time) (err error) {
+	_, err = db.Exec(`update user set digest_sent_time = ? where user_id = ?`, sentTime, userID)
+	return
+}
@@ -8,11 +8,16 @@ import "errors"
  * @Date 21:59 2022/2/10
  **/
 var (
-	ErrorUserExit      = errors.New("用户已存在")
-	ErrorUserNotExit   = errors.New("用户不已存在")
-	ErrorPasswordWrong = errors.New("密码错误")
-	ErrorGenIDFailed   = errors.New("创建用户ID失败")
-	ErrorInvalidID     = errors.New("无效的ID")
-	ErrorQueryFailed   = errors.New("查询数据失败")
-	ErrorInsertFailed  = errors.New("插入数据失败")
+	ErrorUserExit          = errors.New("用户已存在")
+	ErrorUserNotExit       = errors.New("用户不已存在")
+	ErrorPasswordWrong     = errors.New("密码错误")
+	ErrorGenIDFailed       = errors.New("创建用户ID失败")
+	ErrorInvalidID         = errors.New("无效的ID")
+	ErrorQueryFailed       = errors.New("查询数据失败")
+	ErrorInsertFailed      = errors.New("插入数据失败")
+	ErrorCommunityExist    = errors.New("社区名称已存在")
+	ErrorFlairExist        = errors.New("flair名称已存在")
+	ErrorConversationExist = errors.New("会话已存在")
+	ErrorFolderExist       = errors.New("收藏夹名称已存在")
+	ErrorLastAdmin         = errors.New("不能撤销最后一个管理员")
 )
@@ -0,0 +1,111 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+// 社区的flair及帖子的flair、NSFW及剧透标记
+
+// GetFlairList 查询社区的flair
+func GetFlairList(communityID uint64) (list []*models.Flair, err error) {
+	sqlStr := `select flair_id, community_id, name, color, mod_only
+	from community_flair
+	where community_id = ?
+	order by flair_id`
+	list = make([]*models.Flair, 0)
+	err = db.Select(&list, sqlStr, communityID)
+	return
+}
+
+// GetFlairByID 查询flair
+func GetFlairByID(flairID uint64) (flair *models.Flair, err error) {
+	flair = new(models.Flair)
+	sqlStr := `select flair_id, community_id, name, color, mod_only
+	from community_flair
+	where flair_id = ?`
+	err = db.Get(flair, sqlStr, flairID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorInvalidID
+	}
+	if err != nil {
+		zap.L().Error("query community_flair failed", zap.Uint64("flair_id", flairID), zap.Error(err))
+		return nil, ErrorQueryFailed
+	}
+	return
+}
+
+// CreateFlair 创建flair, 同一社区内名称不能重复
+func CreateFlair(communityID uint64, p *models.FlairForm) (id uint64, err error) {
+	sqlStr := `insert into community_flair(community_id, name, color, mod_only) values(?,?,?,?)`
+	res, err := db.Exec(sqlStr, communityID, p.Name, p.Color, p.ModOnly)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return 0, ErrorFlairExist
+		}
+		zap.L().Error("insert community_flair failed", zap.Error(err))
+		return 0, ErrorInsertFailed
+	}
+	lastID, err := res.LastInsertId()
+	return uint64(lastID), err
+}
+
+// UpdateFlair 修改flair的名称、颜色及是否只有版主可以使用
+func UpdateFlair(flairID uint64, p *models.FlairForm) (err error) {
+	sqlStr := `update community_flair set name = ?, color = ?, mod_only = ? where flair_id = ?`
+	if _, err = db.Exec(sqlStr, p.Name, p.Color, p.ModOnly, flairID); err != nil && isDuplicateEntry(err) {
+		err = ErrorFlairExist
+	}
+	return
+}
+
+// DeleteFlair 删除flair, 使用该flair的帖子改为没有flair
+func DeleteFlair(flairID uint64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`update post set flair_id = 0 where flair_id = ?`, flairID); err != nil {
+		return
+	}
+	if _, err = tx.Exec(`delete from community_flair where flair_id = ?`, flairID); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// UpdatePostFlair 修改帖子的flair
+func UpdatePostFlair(postID, flairID uint64) (err error) {
+	_, err = db.Exec(`update post set flair_id = ? where post_id = ?`, flairID, postID)
+	return
+}
+
+// UpdatePostFlags 修改帖子的NSFW及剧透标记
+func UpdatePostFlags(postID uint64, nsfw, spoiler bool) (err error) {
+	_, err = db.Exec(`update post set nsfw = ?, spoiler = ? where post_id = ?`, nsfw, spoiler, postID)
+	return
+}
+
+// GetUserPreferences 查询用户的浏览偏好
+func GetUserPreferences(userID uint64) (prefs *models.Preferences, err error) {
+	prefs = new(models.Preferences)
+	err = db.Get(prefs, `select hide_nsfw, blur_spoiler from user where user_id = ?`, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorUserNotExit
+	}
+	return
+}
+
+// UpdateUserPreferences 修改用户的浏览偏好
+func UpdateUserPreferences(userID uint64, p *models.Preferences) (err error) {
+	sqlStr := `update user set hide_nsfw = ?, blur_spoiler = ? where user_id = ?`
+	_, err = db.Exec(sqlStr, p.HideNSFW, p.BlurSpoiler, userID)
+	return
+}
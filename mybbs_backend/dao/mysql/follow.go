@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+)
+
+// 用户关注
+
+// Follow 关注用户, 重复关注不报错
+func Follow(userID, followeeID uint64) (err error) {
+	_, err = db.Exec(`insert ignore into user_follow(user_id, followee_id) values(?,?)`, userID, followeeID)
+	return
+}
+
+// Unfollow 取消关注, 未关注时不报错
+func Unfollow(userID, followeeID uint64) (err error) {
+	_, err = db.Exec(`delete from user_follow where user_id = ? and followee_id = ?`, userID, followeeID)
+	return
+}
+
+// IsFollowing userID是否关注了followeeID
+func IsFollowing(userID, followeeID uint64) (bool, error) {
+	var count int64
+	err := db.Get(&count, `select count(*) from user_follow where user_id = ? and followee_id = ?`, userID, followeeID)
+	return count > 0, err
+}
+
+// CountFollows 查询用户的粉丝数及关注数
+func CountFollows(userID uint64) (followers, following int64, err error) {
+	if err = db.Get(&followers, `select count(*) from user_follow where followee_id = ?`, userID); err != nil {
+		return
+	}
+	err = db.Get(&following, `select count(*) from user_follow where user_id = ?`, userID)
+	return
+}
+
+// GetFollowingIDs 查询用户关注的全部用户
+func GetFollowingIDs(userID uint64) (ids []uint64, err error) {
+	ids = make([]uint64, 0)
+	err = db.Select(&ids, `select followee_id from user_follow where user_id = ?`, userID)
+	return
+}
+
+// GetFollowerList 分页查询用户的粉丝, 最近关注的在前
+func GetFollowerList(userID uint64, page, size int64) (list []*models.UserBrief, err error) {
+	sqlStr := `select u.user_id, ` + nicknameColumn + `, u.avatar
+	from user_follow f
+	join user u on u.user_id = f.user_id
+	where f.followee_id = ?
+	order by f.create_time desc
+	limit ?,?`
+	list = make([]*models.UserBrief, 0, size)
+	err = db.Select(&list, sqlStr, userID, (page-1)*size, size)
+	return
+}
+
+// GetFollowingList 分页查询用户关注的人, 最近关注的在前
+func GetFollowingList(userID uint64, page, size int64) (list []*models.UserBrief, err error) {
+	sqlStr := `select u.user_id, ` + nicknameColumn + `, u.avatar
+	from user_follow f
+	join user u on u.user_id = f.followee_id
+	where f.user_id = ?
+	order by f.create_time desc
+	limit ?,?`
+	list = make([]*models.UserBrief, 0, size)
+	err = db.Select(&list, sqlStr, userID, (page-1)*size, size)
+	return
+}
+
+// GetRecentPostsByAuthor 查询用户最近发布的帖子的id及发布时间, 用于重建作者的帖子zset
+func GetRecentPostsByAuthor(userID uint64, limit int64) (posts []*models.Post, err error) {
+	sqlStr := `select post_id, create_time
+	from post
+	where author_id = ? and status = 1
+	order by create_time desc
+	limit ?`
+	posts = make([]*models.Post, 0)
+	err = db.Select(&posts, sqlStr, userID, limit)
+	return
+}
@@ -0,0 +1,74 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+// GetUserByIdentity 查询第三方账号绑定的用户, 未绑定时返回nil
+func GetUserByIdentity(issuer, subject string) (user *models.User2, err error) {
+	user = new(models.User2)
+	sqlStr := `select u.user_id, u.email from user_identity i
+	join user u on u.user_id = i.user_id
+	where i.issuer = ? and i.subject = ?`
+	err = db.Get(user, sqlStr, issuer, subject)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		zap.L().Error("query user_identity failed", zap.String("issuer", issuer), zap.Error(err))
+		return nil, ErrorQueryFailed
+	}
+	return
+}
+
+// GetUserByEmailAddr 根据邮箱查询用户
+func GetUserByEmailAddr(email string) (user *models.User2, err error) {
+	user = new(models.User2)
+	sqlStr := `select user_id, email from user where email = ?`
+	err = db.Get(user, sqlStr, email)
+	if err == sql.ErrNoRows {
+		return nil, ErrorUserNotExit
+	}
+	if err != nil {
+		zap.L().Error("query user by email failed", zap.Error(err))
+		return nil, ErrorQueryFailed
+	}
+	return
+}
+
+// InsertUserIdentity 将第三方账号绑定到已有用户
+func InsertUserIdentity(i *models.UserIdentity) (err error) {
+	sqlStr := `insert into user_identity(user_id, issuer, subject, email) values(?,?,?,?)`
+	if _, err = db.Exec(sqlStr, i.UserID, i.Issuer, i.Subject, i.Email); err != nil {
+		zap.L().Error("insert user_identity failed", zap.Uint64("user_id", i.UserID), zap.Error(err))
+		err = ErrorInsertFailed
+	}
+	return
+}
+
+// InsertUserWithIdentity 第三方账号首次登录时创建用户并绑定, 用户没有密码只能通过第三方登录
+func InsertUserWithIdentity(user *models.User2, i *models.UserIdentity) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			zap.L().Error("insert user with identity failed", zap.Error(err))
+			err = ErrorInsertFailed
+		}
+	}()
+	sqlStr := `insert into user(user_id,email,password,nickname) values(?,?,?,?)`
+	if _, err = tx.Exec(sqlStr, user.UserID, user.Email, "", user.NickName); err != nil {
+		return
+	}
+	sqlStr = `insert into user_identity(user_id, issuer, subject, email) values(?,?,?,?)`
+	if _, err = tx.Exec(sqlStr, user.UserID, i.Issuer, i.Subject, i.Email); err != nil {
+		return
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+// InsertCommunityMember 加入社区, 返回是否为新加入
+func InsertCommunityMember(userID, communityID uint64, status int8) (bool, error) {
+	sqlStr := `insert ignore into community_member(user_id, community_id, status) values(?,?,?)`
+	ret, err := db.Exec(sqlStr, userID, communityID, status)
+	if err != nil {
+		zap.L().Error("insert community_member failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return false, ErrorInsertFailed
+	}
+	n, err := ret.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteCommunityMember 退出社区或拒绝加入申请, 返回之前是否已加入或申请
+func DeleteCommunityMember(userID, communityID uint64) (bool, error) {
+	sqlStr := `delete from community_member where user_id = ? and community_id = ?`
+	ret, err := db.Exec(sqlStr, userID, communityID)
+	if err != nil {
+		return false, err
+	}
+	n, err := ret.RowsAffected()
+	return n > 0, err
+}
+
+// GetMemberStatus 查询用户在社区的成员状态, 未加入时ok为false
+func GetMemberStatus(userID, communityID uint64) (status int8, ok bool, err error) {
+	sqlStr := `select status from community_member where user_id = ? and community_id = ?`
+	err = db.Get(&status, sqlStr, userID, communityID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return status, err == nil, err
+}
+
+// ApproveCommunityMember 通过加入申请, 返回是否存在待审核的申请
+func ApproveCommunityMember(userID, communityID uint64) (bool, error) {
+	sqlStr := `update community_member set status = ? where user_id = ? and community_id = ? and status = ?`
+	ret, err := db.Exec(sqlStr, models.MemberApproved, userID, communityID, models.MemberPending)
+	if err != nil {
+		return false, err
+	}
+	n, err := ret.RowsAffected()
+	return n > 0, err
+}
+
+// GetPendingMemberIDs 查询社区待审核的加入申请, 先申请的在前
+func GetPendingMemberIDs(communityID uint64) (ids []uint64, err error) {
+	sqlStr := `select user_id from community_member where community_id = ? and status = ? order by create_time`
+	ids = make([]uint64, 0)
+	err = db.Select(&ids, sqlStr, communityID, models.MemberPending)
+	return
+}
+
+// GetUserCommunityIDs 查询用户已通过审核的社区id
+func GetUserCommunityIDs(userID uint64) (ids []uint64, err error) {
+	sqlStr := `select community_id from community_member where user_id = ? and status = 1`
+	ids = make([]uint64, 0)
+	err = db.Select(&ids, sqlStr, userID)
+	return
+}
+
+// GetUserCommunityList 查询用户加入的未归档社区
+func GetUserCommunityList(userID uint64) (list []*models.Community, err error) {
+	sqlStr := `select c.community_id, c.community_name, c.visibility
+	from community_member m
+	join community c on c.community_id = m.community_id
+	where m.user_id = ? and m.status = 1 and c.status = 1
+	order by c.sort_order, c.community_id`
+	list = make([]*models.Community, 0)
+	err = db.Select(&list, sqlStr, userID)
+	return
+}
+
+// CountCommunityMembers 统计社区已通过审核的成员数
+func CountCommunityMembers(communityID uint64) (count int64, err error) {
+	err = db.Get(&count, `select count(*) from community_member where community_id = ? and status = 1`, communityID)
+	return
+}
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 帖子及评论中提到的用户
+
+// GetUsersByNames 根据用户名查询用户id
+func GetUsersByNames(names []string) (list []*models.Mention, err error) {
+	list = make([]*models.Mention, 0, len(names))
+	if len(names) == 0 {
+		return
+	}
+	query, args, err := sqlx.In(`select user_id, username from user where username in (?)`, names)
+	if err != nil {
+		return
+	}
+	err = db.Select(&list, db.Rebind(query), args...)
+	return
+}
+
+// InsertMentions 保存帖子或评论中提到的用户
+func InsertMentions(authorID, postID, commentID uint64, userIDs []uint64) (err error) {
+	for _, id := range userIDs {
+		sqlStr := `insert ignore into mention(user_id, author_id, post_id, comment_id) values(?,?,?,?)`
+		if _, err = db.Exec(sqlStr, id, authorID, postID, commentID); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// GetPostMentions 查询帖子正文中提到的用户
+func GetPostMentions(postID uint64) (list []*models.Mention, err error) {
+	sqlStr := `select m.comment_id, m.user_id, u.username
+	from mention m join user u on u.user_id = m.user_id
+	where m.post_id = ? and m.comment_id = 0`
+	list = make([]*models.Mention, 0)
+	err = db.Select(&list, sqlStr, postID)
+	return
+}
+
+// GetCommentMentions 查询评论中提到的用户
+func GetCommentMentions(commentIDs []uint64) (list []*models.Mention, err error) {
+	list = make([]*models.Mention, 0)
+	if len(commentIDs) == 0 {
+		return
+	}
+	query, args, err := sqlx.In(`select m.comment_id, m.user_id, u.username
+	from mention m join user u on u.user_id = m.user_id
+	where m.comment_id in (?)`, commentIDs)
+	if err != nil {
+		return
+	}
+	err = db.Select(&list, db.Rebind(query), args...)
+	return
+}
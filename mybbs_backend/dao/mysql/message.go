@@ -0,0 +1,176 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 私信会话及消息
+
+// GetDirectConversationID 查询两个用户之间的一对一会话, 不存在时返回0
+func GetDirectConversationID(directKey string) (id uint64, err error) {
+	err = db.Get(&id, `select conversation_id from conversation where direct_key = ?`, directKey)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return
+}
+
+// CreateConversation 创建会话及其成员, directKey为空时为群聊
+// 并发创建同一个一对一会话时返回ErrorConversationExist, 调用方重新查询即可
+func CreateConversation(c *models.Conversation, creatorID uint64, directKey string, memberIDs []uint64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var key interface{}
+	if len(directKey) > 0 {
+		key = directKey
+	}
+	sqlStr := `insert into conversation(conversation_id, is_group, title, creator_id, direct_key) values(?,?,?,?,?)`
+	if _, err = tx.Exec(sqlStr, c.ConversationID, c.IsGroup, c.Title, creatorID, key); err != nil {
+		if isDuplicateEntry(err) {
+			err = ErrorConversationExist
+		}
+		return
+	}
+	for _, uid := range memberIDs {
+		if _, err = tx.Exec(`insert into conversation_member(conversation_id, user_id) values(?,?)`, c.ConversationID, uid); err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// GetConversation 查询会话, 不存在时返回ErrorInvalidID
+func GetConversation(conversationID uint64) (c *models.Conversation, err error) {
+	c = new(models.Conversation)
+	sqlStr := `select conversation_id, is_group, title, last_message_id, update_time
+	from conversation where conversation_id = ?`
+	err = db.Get(c, sqlStr, conversationID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorInvalidID
+	}
+	return
+}
+
+// GetConversationMemberIDs 查询会话的全部成员
+func GetConversationMemberIDs(conversationID uint64) (ids []uint64, err error) {
+	err = db.Select(&ids, `select user_id from conversation_member where conversation_id = ? order by create_time`, conversationID)
+	return
+}
+
+// IsConversationMember 用户是否为会话的成员
+func IsConversationMember(conversationID, userID uint64) (bool, error) {
+	var count int64
+	err := db.Get(&count, `select count(*) from conversation_member where conversation_id = ? and user_id = ?`,
+		conversationID, userID)
+	return count > 0, err
+}
+
+// InsertMessage 写入消息并更新会话的最后一条消息, 自己发送的消息视为已读
+func InsertMessage(m *models.Message) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := `insert into message(message_id, conversation_id, sender_id, content) values(?,?,?,?)`
+	if _, err = tx.Exec(sqlStr, m.MessageID, m.ConversationID, m.SenderID, m.Content); err != nil {
+		return
+	}
+	sqlStr = `update conversation set last_message_id = ? where conversation_id = ? and last_message_id < ?`
+	if _, err = tx.Exec(sqlStr, m.MessageID, m.ConversationID, m.MessageID); err != nil {
+		return
+	}
+	sqlStr = `update conversation_member set last_read_message_id = ?
+	where conversation_id = ? and user_id = ? and last_read_message_id < ?`
+	if _, err = tx.Exec(sqlStr, m.MessageID, m.ConversationID, m.SenderID, m.MessageID); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// GetConversationList 分页查询用户的会话及未读数, 最近有新消息的在前
+func GetConversationList(userID uint64, page, size int64) (list []*models.Conversation, err error) {
+	sqlStr := `select c.conversation_id, c.is_group, c.title, c.last_message_id, m.last_read_message_id, c.update_time,
+	(select count(*) from message
+		where conversation_id = c.conversation_id and message_id > m.last_read_message_id and sender_id != m.user_id) as unread_count
+	from conversation_member m
+	join conversation c on c.conversation_id = m.conversation_id
+	where m.user_id = ?
+	order by c.update_time desc, c.conversation_id desc
+	limit ?,?`
+	list = make([]*models.Conversation, 0, size)
+	err = db.Select(&list, sqlStr, userID, (page-1)*size, size)
+	return
+}
+
+// GetMessagesByIDs 根据id批量查询消息
+func GetMessagesByIDs(ids []uint64) (list []*models.Message, err error) {
+	if len(ids) == 0 {
+		return
+	}
+	query, args, err := sqlx.In(`select message_id, conversation_id, sender_id, content, create_time
+	from message where message_id in (?)`, ids)
+	if err != nil {
+		return
+	}
+	err = db.Select(&list, db.Rebind(query), args...)
+	return
+}
+
+// GetMessageList 查询会话中id小于before的消息, 最新的在前; before为0时从最新的消息开始
+func GetMessageList(conversationID, before uint64, size int64) (list []*models.Message, err error) {
+	sqlStr := `select message_id, conversation_id, sender_id, content, create_time
+	from message
+	where conversation_id = ?`
+	args := []interface{}{conversationID}
+	if before > 0 {
+		sqlStr += ` and message_id < ?`
+		args = append(args, before)
+	}
+	sqlStr += ` order by message_id desc limit ?`
+	list = make([]*models.Message, 0, size)
+	err = db.Select(&list, sqlStr, append(args, size)...)
+	return
+}
+
+// GetMessageByID 查询会话中的消息, 不存在时返回ErrorInvalidID
+func GetMessageByID(conversationID, messageID uint64) (m *models.Message, err error) {
+	m = new(models.Message)
+	sqlStr := `select message_id, conversation_id, sender_id, content, create_time
+	from message where message_id = ? and conversation_id = ?`
+	err = db.Get(m, sqlStr, messageID, conversationID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorInvalidID
+	}
+	return
+}
+
+// GetReadReceipts 查询会话成员的已读回执
+func GetReadReceipts(conversationID uint64) (list []*models.ReadReceipt, err error) {
+	list = make([]*models.ReadReceipt, 0)
+	err = db.Select(&list, `select user_id, last_read_message_id from conversation_member where conversation_id = ?`,
+		conversationID)
+	return
+}
+
+// UpdateLastRead 更新用户在会话中读到的最后一条消息, 只前进不后退
+func UpdateLastRead(conversationID, userID, messageID uint64) (err error) {
+	sqlStr := `update conversation_member set last_read_message_id = ?
+	where conversation_id = ? and user_id = ? and last_read_message_id < ?`
+	_, err = db.Exec(sqlStr, messageID, conversationID, userID, messageID)
+	return
+}
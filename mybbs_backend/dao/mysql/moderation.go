@@ -0,0 +1,119 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/rbac"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 社区管理: 删除、置顶、锁定帖子, 删除评论, 禁言
+
+// UpdatePostStatus 修改帖子状态
+func UpdatePostStatus(postID uint64, status int32) (err error) {
+	_, err = db.Exec(`update post set status = ? where post_id = ?`, status, postID)
+	return
+}
+
+// UpdatePostPinned 置顶/取消置顶帖子
+func UpdatePostPinned(postID uint64, pinned bool) (err error) {
+	_, err = db.Exec(`update post set pinned = ? where post_id = ?`, pinned, postID)
+	return
+}
+
+// UpdatePostLocked 锁定/解锁帖子
+func UpdatePostLocked(postID uint64, locked bool) (err error) {
+	_, err = db.Exec(`update post set locked = ? where post_id = ?`, locked, postID)
+	return
+}
+
+// GetPinnedPostIDs 查询社区内置顶的帖子id, 最新置顶的在前
+func GetPinnedPostIDs(communityID uint64) (ids []string, err error) {
+	sqlStr := `select post_id from post
+	where community_id = ? and pinned = 1 and status = 1
+	order by update_time desc`
+	ids = make([]string, 0)
+	err = db.Select(&ids, sqlStr, communityID)
+	return
+}
+
+// GetCommentByID 查询评论
+func GetCommentByID(commentID uint64) (comment *models.Comment, err error) {
+	comment = new(models.Comment)
+	sqlStr := `select comment_id, content, post_id, author_id, parent_id, create_time
+	from comment
+	where comment_id = ? and status = 1`
+	err = db.Get(comment, sqlStr, commentID)
+	if err == sql.ErrNoRows {
+		err = ErrorInvalidID
+	}
+	return
+}
+
+// UpdateCommentStatus 修改评论状态
+func UpdateCommentStatus(commentID uint64, status int32) (err error) {
+	_, err = db.Exec(`update comment set status = ? where comment_id = ?`, status, commentID)
+	return
+}
+
+// UpsertCommunityBan 禁言用户, 已被禁言时覆盖原来的原因及期限
+func UpsertCommunityBan(ban *models.CommunityBan) (err error) {
+	sqlStr := `insert into community_ban(community_id, user_id, moderator_id, reason, expire_time)
+	values(?,?,?,?,?)
+	on duplicate key update moderator_id = values(moderator_id), reason = values(reason),
+	expire_time = values(expire_time), create_time = CURRENT_TIMESTAMP`
+	_, err = db.Exec(sqlStr, ban.CommunityID, ban.UserID, ban.ModeratorID, ban.Reason, ban.ExpireTime)
+	if err != nil {
+		zap.L().Error("insert community_ban failed", zap.Uint64("user_id", ban.UserID), zap.Error(err))
+		err = ErrorInsertFailed
+	}
+	return
+}
+
+// DeleteCommunityBan 解除禁言, 返回之前是否被禁言
+func DeleteCommunityBan(communityID, userID uint64) (bool, error) {
+	ret, err := db.Exec(`delete from community_ban where community_id = ? and user_id = ?`, communityID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := ret.RowsAffected()
+	return n > 0, err
+}
+
+// GetActiveBan 查询用户在社区内未到期的禁言, 没有时返回nil
+func GetActiveBan(communityID, userID uint64) (*models.CommunityBan, error) {
+	ban := new(models.CommunityBan)
+	sqlStr := `select community_id, user_id, moderator_id, reason, expire_time, create_time
+	from community_ban
+	where community_id = ? and user_id = ? and (expire_time is null or expire_time > ?)`
+	err := db.Get(ban, sqlStr, communityID, userID, time.Now())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		zap.L().Error("query community_ban failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, ErrorQueryFailed
+	}
+	return ban, nil
+}
+
+// GetCommunityBanList 查询社区内未到期的禁言
+func GetCommunityBanList(communityID uint64) (list []*models.CommunityBan, err error) {
+	sqlStr := `select community_id, user_id, moderator_id, reason, expire_time, create_time
+	from community_ban
+	where community_id = ? and (expire_time is null or expire_time > ?)
+	order by create_time desc`
+	list = make([]*models.CommunityBan, 0)
+	err = db.Select(&list, sqlStr, communityID, time.Now())
+	return
+}
+
+// GetCommunityModeratorIDs 查询社区的版主
+func GetCommunityModeratorIDs(communityID uint64) (ids []uint64, err error) {
+	sqlStr := `select user_id from user_role where role = ? and community_id = ? order by create_time`
+	ids = make([]uint64, 0)
+	err = db.Select(&ids, sqlStr, rbac.RoleModerator, communityID)
+	return
+}
@@ -0,0 +1,55 @@
+package mysql
+
+import "bluebell_backend/models"
+
+// 不想看的社区及关键词
+
+// MuteCommunity 屏蔽社区, 重复屏蔽不报错
+func MuteCommunity(userID, communityID uint64) (err error) {
+	_, err = db.Exec(`insert ignore into community_mute(user_id, community_id) values(?,?)`, userID, communityID)
+	return
+}
+
+// UnmuteCommunity 取消屏蔽社区
+func UnmuteCommunity(userID, communityID uint64) (err error) {
+	_, err = db.Exec(`delete from community_mute where user_id = ? and community_id = ?`, userID, communityID)
+	return
+}
+
+// GetMutedCommunityIDs 查询用户屏蔽的社区id
+func GetMutedCommunityIDs(userID uint64) (ids []uint64, err error) {
+	ids = make([]uint64, 0)
+	err = db.Select(&ids, `select community_id from community_mute where user_id = ?`, userID)
+	return
+}
+
+// GetMutedCommunityList 查询用户屏蔽的社区, 最近屏蔽的在前
+func GetMutedCommunityList(userID uint64) (list []*models.Community, err error) {
+	sqlStr := `select c.community_id, c.community_name, c.visibility
+	from community_mute m
+	join community c on c.community_id = m.community_id
+	where m.user_id = ?
+	order by m.create_time desc`
+	list = make([]*models.Community, 0)
+	err = db.Select(&list, sqlStr, userID)
+	return
+}
+
+// MuteKeyword 屏蔽关键词, 重复屏蔽不报错
+func MuteKeyword(userID uint64, keyword string) (err error) {
+	_, err = db.Exec(`insert ignore into keyword_mute(user_id, keyword) values(?,?)`, userID, keyword)
+	return
+}
+
+// UnmuteKeyword 取消屏蔽关键词
+func UnmuteKeyword(userID uint64, keyword string) (err error) {
+	_, err = db.Exec(`delete from keyword_mute where user_id = ? and keyword = ?`, userID, keyword)
+	return
+}
+
+// GetMutedKeywords 查询用户屏蔽的关键词, 最近屏蔽的在前
+func GetMutedKeywords(userID uint64) (keywords []string, err error) {
+	keywords = make([]string, 0)
+	err = db.Select(&keywords, `select keyword from keyword_mute where user_id = ? order by create_time desc`, userID)
+	return
+}
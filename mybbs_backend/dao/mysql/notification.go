@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 站内通知
+
+// AddNotification 写入通知, 未读的同类通知(相同group_key)聚合为一条, 同一用户只计数一次
+func AddNotification(e *models.NotificationEvent) error {
+	err := addNotification(e)
+	if err != nil && isDuplicateEntry(err) {
+		// 并发创建同一条聚合通知, 重试时会聚合到已创建的通知上
+		err = addNotification(e)
+	}
+	return err
+}
+
+func addNotification(e *models.NotificationEvent) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var groupKey interface{}
+	var id int64
+	if len(e.GroupKey) > 0 {
+		groupKey = e.GroupKey
+		err = tx.Get(&id, `select notification_id from notification
+		where user_id = ? and group_key = ? for update`, e.UserID, e.GroupKey)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+		err = nil
+	}
+	if id == 0 {
+		sqlStr := `insert into notification(user_id, type, post_id, comment_id, actor_id, group_key)
+		values(?,?,?,?,?,?)`
+		var res sql.Result
+		if res, err = tx.Exec(sqlStr, e.UserID, e.Type, e.PostID, e.CommentID, e.ActorID, groupKey); err != nil {
+			return
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return
+		}
+		if _, err = tx.Exec(`insert into notification_actor(notification_id, actor_id) values(?,?)`, id, e.ActorID); err != nil {
+			return
+		}
+		return tx.Commit()
+	}
+	res, err := tx.Exec(`insert ignore into notification_actor(notification_id, actor_id) values(?,?)`, id, e.ActorID)
+	if err != nil {
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		// 新的用户: 计数加一并更新为最近的用户, 通知排到最前面
+		sqlStr := `update notification set actor_id = ?, actor_count = actor_count + 1, comment_id = ?, update_time = now()
+		where notification_id = ?`
+		if _, err = tx.Exec(sqlStr, e.ActorID, e.CommentID, id); err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// notBlockedActor 不包括屏蔽的用户触发的通知(屏蔽之前已经产生的)
+const notBlockedActor = ` and actor_id not in (select blocked_id from user_block where user_id = notification.user_id)`
+
+// GetNotificationList 分页查询用户的通知, 最近更新的在前
+func GetNotificationList(userID uint64, unreadOnly bool, page, size int64) (list []*models.Notification, err error) {
+	sqlStr := `select notification_id, user_id, type, post_id, comment_id, actor_id, actor_count, is_read, update_time
+	from notification
+	where user_id = ?` + notBlockedActor
+	if unreadOnly {
+		sqlStr += ` and is_read = 0`
+	}
+	sqlStr += ` order by update_time desc, notification_id desc limit ?,?`
+	list = make([]*models.Notification, 0, size)
+	err = db.Select(&list, sqlStr, userID, (page-1)*size, size)
+	return
+}
+
+// CountUnreadNotifications 查询用户的未读通知数
+func CountUnreadNotifications(userID uint64) (count int64, err error) {
+	err = db.Get(&count, `select count(*) from notification where user_id = ? and is_read = 0`+notBlockedActor, userID)
+	return
+}
+
+// MarkNotificationsRead 标记通知为已读, ids为空时标记用户的全部通知
+// 已读的通知不再聚合新的事件, 之后的事件生成新的通知
+func MarkNotificationsRead(userID uint64, ids []uint64) (err error) {
+	sqlStr := `update notification set is_read = 1, group_key = NULL, update_time = update_time
+	where user_id = ? and is_read = 0`
+	args := []interface{}{userID}
+	if len(ids) > 0 {
+		var query string
+		if query, args, err = sqlx.In(` and notification_id in (?)`, ids); err != nil {
+			return
+		}
+		sqlStr += query
+		args = append([]interface{}{userID}, args...)
+	}
+	_, err = db.Exec(sqlStr, args...)
+	return
+}
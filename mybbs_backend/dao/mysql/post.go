@@ -18,10 +18,11 @@ import (
 // CreatePost 创建帖子
 func CreatePost(post *models.Post) (err error) {
 	sqlStr := `insert into post(
-	post_id, title, content, author_id, community_id)
-	values(?,?,?,?,?)`
+	post_id, title, content, author_id, community_id, tags, attachments, fields, flair_id, nsfw, spoiler)
+	values(?,?,?,?,?,?,?,?,?,?,?)`
 	_, err = db.Exec(sqlStr, post.PostID, post.Title,
-		post.Content, post.AuthorId, post.CommunityID)
+		post.Content, post.AuthorId, post.CommunityID, post.Tags, post.Attachments, post.Fields,
+		post.FlairID, post.NSFW, post.Spoiler)
 	if err != nil {
 		zap.L().Error("insert post failed", zap.Error(err))
 		err = ErrorInsertFailed
@@ -37,9 +38,9 @@ func CreatePost(post *models.Post) (err error) {
  **/
 func GetPostByID(pid int64) (post *models.Post, err error) {
 	post = new(models.Post)
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time
+	sqlStr := `select post_id, title, content, author_id, community_id, pinned, locked, tags, attachments, fields, flair_id, nsfw, spoiler, create_time
 	from post
-	where post_id = ?`
+	where post_id = ? and status = 1`
 	err = db.Get(post, sqlStr, pid)
 	if err == sql.ErrNoRows {
 		err = ErrorInvalidID
@@ -59,9 +60,9 @@ func GetPostByID(pid int64) (post *models.Post, err error) {
  * @Date 22:55 2022/2/15
  **/
 func GetPostListByIDs(ids []string) (postList []*models.Post, err error) {
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time
+	sqlStr := `select post_id, title, content, author_id, community_id, pinned, locked, tags, attachments, fields, flair_id, nsfw, spoiler, create_time
 	from post
-	where post_id in (?)
+	where post_id in (?) and status = 1
 	order by FIND_IN_SET(post_id, ?)`
 	// 动态填充id
 	query, args, err := sqlx.In(sqlStr, ids, strings.Join(ids, ","))
@@ -79,15 +80,33 @@ func GetPostListByIDs(ids []string) (postList []*models.Post, err error) {
  * @Description //TODO 获取帖子列表
  * @Date 22:58 2022/2/12
  **/
-func GetPostList(page, size int64) (posts []*models.Post, err error) {
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time
+// GetPostIDList 按发帖时间从新到旧分页查询帖子id, 私有社区、屏蔽及NSFW等过滤由logic层的postFilter处理
+func GetPostIDList(page, size int64) (ids []string, err error) {
+	sqlStr := `select post_id
 	from post
+	where status = 1
 	ORDER BY create_time
 	DESC 
 	limit ?,?
 	`
-	posts = make([]*models.Post, 0, 2)	// 0：长度  2：容量
-	err = db.Select(&posts, sqlStr,(page-1)*size,size)
+	ids = make([]string, 0, size)
+	err = db.Select(&ids, sqlStr, (page-1)*size, size)
 	return
 
 }
+
+// notInCommunities 生成排除指定社区(无权浏览的私有社区)的查询条件, 需要配合sqlx.In使用
+func notInCommunities(column string, hidden []uint64) (string, []interface{}) {
+	if len(hidden) == 0 {
+		return "", nil
+	}
+	return " and " + column + " not in (?)", []interface{}{hidden}
+}
+
+// notNSFW 按浏览偏好排除NSFW帖子的查询条件
+func notNSFW(column string, hide bool) string {
+	if !hide {
+		return ""
+	}
+	return " and " + column + " = 0"
+}
@@ -0,0 +1,113 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// 没有设置昵称的老用户展示用户名
+const nicknameColumn = `if(nickname = '', username, nickname) as nickname`
+
+// GetUserProfile 查询用户的公开资料
+func GetUserProfile(userID uint64) (profile *models.UserProfile, err error) {
+	profile = new(models.UserProfile)
+	sqlStr := `select user_id, ` + nicknameColumn + `, bio, avatar, gender, create_time
+	from user
+	where user_id = ?`
+	err = db.Get(profile, sqlStr, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorUserNotExit
+	}
+	if err != nil {
+		zap.L().Error("query user profile failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, ErrorQueryFailed
+	}
+	return
+}
+
+// GetUserBrief 查询作者的昵称及头像
+func GetUserBrief(userID uint64) (user *models.UserBrief, err error) {
+	user = new(models.UserBrief)
+	sqlStr := `select user_id, ` + nicknameColumn + `, avatar from user where user_id = ?`
+	err = db.Get(user, sqlStr, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorUserNotExit
+	}
+	return
+}
+
+// UpdateUserProfile 修改昵称及个人简介
+func UpdateUserProfile(userID uint64, p *models.ProfileForm) (err error) {
+	sqlStr := `update user set nickname = ?, bio = ? where user_id = ?`
+	_, err = db.Exec(sqlStr, p.NickName, p.Bio, userID)
+	return
+}
+
+// UpdateUserAvatar 修改头像地址, 为空时恢复为自动生成的头像
+func UpdateUserAvatar(userID uint64, avatar string) (err error) {
+	sqlStr := `update user set avatar = ? where user_id = ?`
+	_, err = db.Exec(sqlStr, avatar, userID)
+	return
+}
+
+// GetPostListByAuthor 分页查询用户发布的帖子, 最新的在前
+func GetPostListByAuthor(userID uint64, page, size int64, hidden []uint64, hideNSFW bool) (posts []*models.Post, err error) {
+	cond, args := notInCommunities("community_id", hidden)
+	cond += notNSFW("nsfw", hideNSFW)
+	sqlStr := `select post_id, title, content, author_id, community_id, pinned, locked, tags, attachments, fields, flair_id, nsfw, spoiler, create_time
+	from post
+	where author_id = ? and status = 1` + cond + `
+	order by create_time desc
+	limit ?,?`
+	args = append([]interface{}{userID}, append(args, (page-1)*size, size)...)
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return
+	}
+	posts = make([]*models.Post, 0, size)
+	err = db.Select(&posts, db.Rebind(query), args...)
+	return
+}
+
+// GetCommentListByAuthor 分页查询用户发表的评论, 最新的在前
+func GetCommentListByAuthor(userID uint64, page, size int64, hidden []uint64) (comments []*models.Comment, err error) {
+	cond, args := notInCommunities("p.community_id", hidden)
+	sqlStr := `select c.comment_id, c.content, c.post_id, c.author_id, c.parent_id, c.create_time
+	from comment c
+	join post p on p.post_id = c.post_id
+	where c.author_id = ? and c.status = 1 and p.status = 1` + cond + `
+	order by c.create_time desc
+	limit ?,?`
+	args = append([]interface{}{userID}, append(args, (page-1)*size, size)...)
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return
+	}
+	comments = make([]*models.Comment, 0, size)
+	err = db.Select(&comments, db.Rebind(query), args...)
+	return
+}
+
+// CountUserPostsAndComments 统计用户发布的帖子数及评论数
+func CountUserPostsAndComments(userID uint64, hidden []uint64) (posts, comments int64, err error) {
+	cond, args := notInCommunities("community_id", hidden)
+	query, args, err := sqlx.In(`select count(*) from post where author_id = ? and status = 1`+cond,
+		append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return
+	}
+	if err = db.Get(&posts, db.Rebind(query), args...); err != nil {
+		return
+	}
+	cond, args = notInCommunities("p.community_id", hidden)
+	query, args, err = sqlx.In(`select count(*) from comment c join post p on p.post_id = c.post_id
+	where c.author_id = ? and c.status = 1 and p.status = 1`+cond, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return
+	}
+	err = db.Get(&comments, db.Rebind(query), args...)
+	return
+}
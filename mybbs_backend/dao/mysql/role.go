@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/rbac"
+
+	"go.uber.org/zap"
+)
+
+// GetUserRoleList 查询用户的全部角色
+func GetUserRoleList(userID uint64) (roles []*models.UserRole, err error) {
+	sqlStr := `select user_id, role, community_id from user_role where user_id = ? order by role, community_id`
+	roles = make([]*models.UserRole, 0)
+	if err = db.Select(&roles, sqlStr, userID); err != nil {
+		zap.L().Error("query user_role failed", zap.Uint64("user_id", userID), zap.Error(err))
+		err = ErrorQueryFailed
+	}
+	return
+}
+
+// GetUserRoles 查询用户的角色并编码成token中携带的格式, 实现jwt.RoleLoader
+func GetUserRoles(userID uint64) ([]string, error) {
+	list, err := GetUserRoleList(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, 0, len(list))
+	for _, r := range list {
+		roles = append(roles, rbac.Encode(r.Role, r.CommunityID))
+	}
+	return roles, nil
+}
+
+// InsertUserRole 授予角色, 已经拥有时忽略
+func InsertUserRole(r *models.UserRole) (err error) {
+	sqlStr := `insert ignore into user_role(user_id, role, community_id) values(?,?,?)`
+	if _, err = db.Exec(sqlStr, r.UserID, r.Role, r.CommunityID); err != nil {
+		zap.L().Error("insert user_role failed", zap.Uint64("user_id", r.UserID), zap.Error(err))
+		err = ErrorInsertFailed
+	}
+	return
+}
+
+// DeleteUserRole 撤销角色, 返回是否确实拥有该角色
+// 撤销管理员时在同一事务中锁定全部管理员记录再删除, 只剩一个管理员时返回ErrorLastAdmin, 并发撤销不会删光管理员
+func DeleteUserRole(r *models.UserRole) (ok bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if r.Role == rbac.RoleAdmin {
+		var admins []uint64
+		sqlStr := `select user_id from user_role where role = ? and community_id = 0 for update`
+		if err = tx.Select(&admins, sqlStr, rbac.RoleAdmin); err != nil {
+			return
+		}
+		isAdmin := false
+		for _, id := range admins {
+			if id == r.UserID {
+				isAdmin = true
+			}
+		}
+		// 目标用户不是管理员时没有可撤销的角色, 不受最后一个管理员的限制
+		if !isAdmin || r.CommunityID != 0 {
+			return false, tx.Commit()
+		}
+		if len(admins) <= 1 {
+			return false, ErrorLastAdmin
+		}
+	}
+	sqlStr := `delete from user_role where user_id = ? and role = ? and community_id = ?`
+	ret, err := tx.Exec(sqlStr, r.UserID, r.Role, r.CommunityID)
+	if err != nil {
+		return
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return
+	}
+	return n > 0, tx.Commit()
+}
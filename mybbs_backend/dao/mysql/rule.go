@@ -0,0 +1,42 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+	"encoding/json"
+
+	"go.uber.org/zap"
+)
+
+// GetCommunityRule 查询社区的发帖规则, 没有设置时返回nil
+func GetCommunityRule(communityID uint64) (*models.CommunityRule, error) {
+	var data []byte
+	err := db.Get(&data, `select rule from community_rule where community_id = ?`, communityID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		zap.L().Error("query community_rule failed", zap.Uint64("community_id", communityID), zap.Error(err))
+		return nil, ErrorQueryFailed
+	}
+	rule := new(models.CommunityRule)
+	if err := json.Unmarshal(data, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// SaveCommunityRule 保存社区的发帖规则
+func SaveCommunityRule(communityID uint64, rule *models.CommunityRule) error {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	sqlStr := `insert into community_rule(community_id, rule) values(?,?)
+	on duplicate key update rule = values(rule)`
+	if _, err := db.Exec(sqlStr, communityID, data); err != nil {
+		zap.L().Error("save community_rule failed", zap.Uint64("community_id", communityID), zap.Error(err))
+		return ErrorInsertFailed
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+// GetUserTOTP 查询用户的两步验证配置, 未配置时返回nil
+func GetUserTOTP(userID uint64) (t *models.UserTOTP, err error) {
+	t = new(models.UserTOTP)
+	sqlStr := `select user_id, secret, enabled from user_totp where user_id = ?`
+	err = db.Get(t, sqlStr, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		zap.L().Error("query user_totp failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, ErrorQueryFailed
+	}
+	return
+}
+
+// SaveUserTOTP 保存(或替换尚未启用的)TOTP密钥, 保存后处于未启用状态
+func SaveUserTOTP(userID uint64, secret string) (err error) {
+	sqlStr := `insert into user_totp(user_id, secret, enabled) values(?,?,0)
+	on duplicate key update secret = values(secret), enabled = 0`
+	if _, err = db.Exec(sqlStr, userID, secret); err != nil {
+		zap.L().Error("save user_totp failed", zap.Uint64("user_id", userID), zap.Error(err))
+		err = ErrorInsertFailed
+	}
+	return
+}
+
+// EnableUserTOTP 启用两步验证并替换全部恢复码
+func EnableUserTOTP(userID uint64, codeHashes []string) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`update user_totp set enabled = 1 where user_id = ?`, userID); err != nil {
+		return
+	}
+	if _, err = tx.Exec(`delete from user_recovery_code where user_id = ?`, userID); err != nil {
+		return
+	}
+	for _, h := range codeHashes {
+		if _, err = tx.Exec(`insert into user_recovery_code(user_id, code_hash) values(?,?)`, userID, h); err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteUserTOTP 关闭两步验证, 同时删除恢复码
+func DeleteUserTOTP(userID uint64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`delete from user_totp where user_id = ?`, userID); err != nil {
+		return
+	}
+	if _, err = tx.Exec(`delete from user_recovery_code where user_id = ?`, userID); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode 使用一个恢复码, 每个恢复码只能使用一次
+func UseRecoveryCode(userID uint64, codeHash string) (ok bool, err error) {
+	sqlStr := `update user_recovery_code set used = 1 where user_id = ? and code_hash = ? and used = 0`
+	res, err := db.Exec(sqlStr, userID, codeHash)
+	if err != nil {
+		return
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
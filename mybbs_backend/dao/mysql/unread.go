@@ -0,0 +1,88 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 阅读记录及未读评论数
+
+// GetPostReads 查询用户在帖子中读到的最后一条评论
+func GetPostReads(userID uint64, postIDs []uint64) (list []*models.PostRead, err error) {
+	if len(postIDs) == 0 {
+		return
+	}
+	query, args, err := sqlx.In(`select post_id, last_comment_id from post_read where user_id = ? and post_id in (?)`,
+		userID, postIDs)
+	if err != nil {
+		return
+	}
+	err = db.Select(&list, db.Rebind(query), args...)
+	return
+}
+
+// SavePostRead 写入用户在帖子中读到的最后一条评论, 只前进不后退
+func SavePostRead(userID, postID, lastCommentID uint64) (err error) {
+	sqlStr := `insert into post_read(user_id, post_id, last_comment_id) values(?,?,?)
+	on duplicate key update last_comment_id = greatest(last_comment_id, values(last_comment_id))`
+	_, err = db.Exec(sqlStr, userID, postID, lastCommentID)
+	return
+}
+
+// GetCommunityVisits 查询用户浏览社区的时间
+func GetCommunityVisits(userID uint64, communityIDs []uint64) (list []*models.CommunityVisit, err error) {
+	if len(communityIDs) == 0 {
+		return
+	}
+	query, args, err := sqlx.In(`select community_id, last_visit, prev_visit from community_visit
+	where user_id = ? and community_id in (?)`, userID, communityIDs)
+	if err != nil {
+		return
+	}
+	err = db.Select(&list, db.Rebind(query), args...)
+	return
+}
+
+// SaveCommunityVisit 写入用户浏览社区的时间
+func SaveCommunityVisit(userID uint64, v *models.CommunityVisit) (err error) {
+	sqlStr := `insert into community_visit(user_id, community_id, last_visit, prev_visit) values(?,?,?,?)
+	on duplicate key update last_visit = values(last_visit), prev_visit = values(prev_visit)`
+	_, err = db.Exec(sqlStr, userID, v.CommunityID, v.LastVisit, v.PrevVisit)
+	return
+}
+
+// GetLastCommentID 查询帖子的最后一条评论id, 没有评论时返回0
+func GetLastCommentID(postID uint64) (id uint64, err error) {
+	err = db.Get(&id, `select ifnull(max(comment_id), 0) from comment where post_id = ? and status = 1`, postID)
+	return
+}
+
+// CountUnreadComments 查询每个帖子在读到的评论之后的新评论数
+func CountUnreadComments(reads []*models.PostRead) (counts map[uint64]int64, err error) {
+	counts = make(map[uint64]int64, len(reads))
+	if len(reads) == 0 {
+		return
+	}
+	conds := make([]string, 0, len(reads))
+	args := make([]interface{}, 0, 2*len(reads))
+	for _, r := range reads {
+		conds = append(conds, `(post_id = ? and comment_id > ?)`)
+		args = append(args, r.PostID, r.LastCommentID)
+	}
+	sqlStr := `select post_id, count(*) as n from comment
+	where status = 1 and (` + strings.Join(conds, " or ") + `)
+	group by post_id`
+	var rows []struct {
+		PostID uint64 `db:"post_id"`
+		N      int64  `db:"n"`
+	}
+	if err = db.Select(&rows, sqlStr, args...); err != nil {
+		return
+	}
+	for _, row := range rows {
+		counts[row.PostID] = row.N
+	}
+	return
+}
@@ -0,0 +1,179 @@
+package mysql
+
+import (
+	"bluebell_backend/models"
+	"database/sql"
+	"time"
+)
+
+const webhookColumns = `webhook_id, url, secret, event, community_id, active, creator_id, create_time`
+
+const deliveryColumns = `delivery_id, webhook_id, event, payload, status, attempts, next_attempt_time,
+	response_code, response_body, replay_of, create_time, update_time`
+
+// CreateWebhook 创建webhook
+func CreateWebhook(creatorID uint64, p *models.WebhookForm, active bool, secret string) (id uint64, err error) {
+	sqlStr := `insert into webhook(url, secret, event, community_id, active, creator_id) values(?,?,?,?,?,?)`
+	res, err := db.Exec(sqlStr, p.URL, secret, p.Event, p.CommunityID, active, creatorID)
+	if err != nil {
+		return
+	}
+	lastID, err := res.LastInsertId()
+	return uint64(lastID), err
+}
+
+// UpdateWebhook 修改webhook的地址、事件、社区及是否启用
+func UpdateWebhook(webhookID uint64, p *models.WebhookForm, active bool) (err error) {
+	sqlStr := `update webhook set url = ?, event = ?, community_id = ?, active = ? where webhook_id = ?`
+	_, err = db.Exec(sqlStr, p.URL, p.Event, p.CommunityID, active, webhookID)
+	return
+}
+
+// DeleteWebhook 删除webhook及其投递记录
+func DeleteWebhook(webhookID uint64) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`delete from webhook_delivery where webhook_id = ?`, webhookID); err != nil {
+		return
+	}
+	if _, err = tx.Exec(`delete from webhook where webhook_id = ?`, webhookID); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// GetWebhook 查询webhook, 不存在时返回ErrorInvalidID
+func GetWebhook(webhookID uint64) (hook *models.Webhook, err error) {
+	hook = new(models.Webhook)
+	err = db.Get(hook, `select `+webhookColumns+` from webhook where webhook_id = ?`, webhookID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorInvalidID
+	}
+	return
+}
+
+// GetWebhookList 查询全部webhook
+func GetWebhookList() (list []*models.Webhook, err error) {
+	list = make([]*models.Webhook, 0)
+	err = db.Select(&list, `select `+webhookColumns+` from webhook order by webhook_id`)
+	return
+}
+
+// GetActiveWebhooks 查询接收社区内该事件的已启用的webhook
+func GetActiveWebhooks(event string, communityID uint64) (list []*models.Webhook, err error) {
+	sqlStr := `select ` + webhookColumns + ` from webhook
+	where event = ? and community_id in (0, ?) and active = 1`
+	err = db.Select(&list, sqlStr, event, communityID)
+	return
+}
+
+// InsertWebhookDeliveries 给每个webhook创建一条待投递的记录
+func InsertWebhookDeliveries(webhookIDs []uint64, event string, payload []byte) (err error) {
+	if len(webhookIDs) == 0 {
+		return
+	}
+	tx, err := db.Beginx()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	for _, id := range webhookIDs {
+		sqlStr := `insert into webhook_delivery(webhook_id, event, payload) values(?,?,?)`
+		if _, err = tx.Exec(sqlStr, id, event, string(payload)); err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimDueDelivery 取出一条到期的待投递记录, 并把下次投递时间推迟lease防止被其他实例重复投递, 没有时返回nil
+func ClaimDueDelivery(now time.Time, lease time.Duration) (*models.WebhookDelivery, error) {
+	var ids []uint64
+	sqlStr := `select delivery_id from webhook_delivery
+	where status = ? and next_attempt_time <= ?
+	order by next_attempt_time limit 10`
+	if err := db.Select(&ids, sqlStr, models.DeliveryPending, now); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		ret, err := db.Exec(`update webhook_delivery set next_attempt_time = ?
+		where delivery_id = ? and status = ? and next_attempt_time <= ?`,
+			now.Add(lease), id, models.DeliveryPending, now)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := ret.RowsAffected(); n == 0 {
+			// 已被其他实例取走
+			continue
+		}
+		return GetWebhookDelivery(id)
+	}
+	return nil, nil
+}
+
+// UpdateDeliveryResult 记录一次投递的结果
+func UpdateDeliveryResult(d *models.WebhookDelivery) (err error) {
+	sqlStr := `update webhook_delivery set status = ?, attempts = ?, next_attempt_time = ?,
+	response_code = ?, response_body = ?
+	where delivery_id = ?`
+	_, err = db.Exec(sqlStr, d.Status, d.Attempts, d.NextAttemptTime, d.ResponseCode, d.ResponseBody, d.DeliveryID)
+	return
+}
+
+// GetWebhookDelivery 查询投递记录, 不存在时返回ErrorInvalidID
+func GetWebhookDelivery(deliveryID uint64) (d *models.WebhookDelivery, err error) {
+	d = new(models.WebhookDelivery)
+	err = db.Get(d, `select `+deliveryColumns+` from webhook_delivery where delivery_id = ?`, deliveryID)
+	if err == sql.ErrNoRows {
+		return nil, ErrorInvalidID
+	}
+	return
+}
+
+// GetWebhookDeliveryList 分页查询webhook的投递记录, 新的在前; status小于0时查询全部状态
+func GetWebhookDeliveryList(webhookID uint64, status int8, page, size int64) (list []*models.WebhookDelivery, err error) {
+	sqlStr := `select ` + deliveryColumns + ` from webhook_delivery
+	where webhook_id = ? and (? < 0 or status = ?)
+	order by delivery_id desc
+	limit ?,?`
+	list = make([]*models.WebhookDelivery, 0)
+	err = db.Select(&list, sqlStr, webhookID, status, status, (page-1)*size, size)
+	return
+}
+
+// ReplayDelivery 重新投递, 复制原记录的请求体创建一条新的待投递记录
+func ReplayDelivery(deliveryID uint64) (id uint64, err error) {
+	sqlStr := `insert into webhook_delivery(webhook_id, event, payload, replay_of)
+	select webhook_id, event, payload, delivery_id from webhook_delivery where delivery_id = ?`
+	res, err := db.Exec(sqlStr, deliveryID)
+	if err != nil {
+		return
+	}
+	lastID, err := res.LastInsertId()
+	return uint64(lastID), err
+}
+
+// ReplayFailedDeliveries 重新投递webhook所有失败且还没有重新投递过的记录, 返回重新投递的条数
+func ReplayFailedDeliveries(webhookID uint64) (int64, error) {
+	sqlStr := `insert into webhook_delivery(webhook_id, event, payload, replay_of)
+	select d.webhook_id, d.event, d.payload, d.delivery_id from webhook_delivery d
+	where d.webhook_id = ? and d.status = ?
+	and not exists (select 1 from webhook_delivery r where r.replay_of = d.delivery_id)
+	order by d.delivery_id`
+	ret, err := db.Exec(sqlStr, webhookID, models.DeliveryFailed)
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
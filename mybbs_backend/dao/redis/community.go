@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"encoding/json"
+	"time"
+)
+
+// GetCommunityListCache 读取社区列表缓存, 未命中时返回nil
+func GetCommunityListCache() ([]*models.Community, error) {
+	data, err := client.Get(KeyCommunityListCache).Bytes()
+	if err == Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*models.Community, 0)
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SetCommunityListCache 缓存社区列表
+func SetCommunityListCache(list []*models.Community, ttl time.Duration) error {
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return client.Set(KeyCommunityListCache, data, ttl).Err()
+}
+
+// DeleteCommunityListCache 社区变更后删除列表缓存
+func DeleteCommunityListCache() error {
+	return client.Del(KeyCommunityListCache).Err()
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const digestCacheExpire = 10 * time.Minute // 一次发送任务内各用户共用社区帖子的缓存
+
+// GetDigestPostIDs 查询社区在since之后发布的帖子, 按bluebell:post:score的分数从高到低返回最多limit个
+// 先把社区的帖子set与发帖时间做zinterstore并按社区缓存, 再按时间范围取出帖子查询分数
+func GetDigestPostIDs(communityIDs []uint64, since time.Time, limit int64) ([]string, error) {
+	if len(communityIDs) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(communityIDs))
+	pipeline := client.Pipeline()
+	for _, id := range communityIDs {
+		cid := strconv.FormatUint(id, 10)
+		key := KeyDigestCommunityZSetPrefix + cid
+		keys = append(keys, key)
+		if client.Exists(key).Val() < 1 {
+			// set的分数为1, 权重设为0只保留发帖时间
+			pipeline.ZInterStore(key, redis.ZStore{
+				Weights: []float64{0, 1},
+			}, KeyCommunityPostSetPrefix+cid, KeyPostTimeZSet)
+			pipeline.Expire(key, digestCacheExpire)
+		}
+	}
+	rangeCmds := make([]*redis.StringSliceCmd, 0, len(keys))
+	for _, key := range keys {
+		rangeCmds = append(rangeCmds, pipeline.ZRangeByScore(key, redis.ZRangeBy{
+			Min: strconv.FormatInt(since.Unix(), 10),
+			Max: "+inf",
+		}))
+	}
+	if _, err := pipeline.Exec(); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0)
+	for _, cmd := range rangeCmds {
+		ids = append(ids, cmd.Val()...)
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	pipeline = client.Pipeline()
+	scoreCmds := make([]*redis.FloatCmd, 0, len(ids))
+	for _, id := range ids {
+		scoreCmds = append(scoreCmds, pipeline.ZScore(KeyPostScoreZSet, id))
+	}
+	if _, err := pipeline.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	// 没有分数的帖子已被删除
+	scores := make(map[string]float64, len(ids))
+	res := make([]string, 0, len(ids))
+	for i, cmd := range scoreCmds {
+		if cmd.Err() == nil {
+			scores[ids[i]] = cmd.Val()
+			res = append(res, ids[i])
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return scores[res[i]] > scores[res[j]]
+	})
+	if int64(len(res)) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// releaseLockScript 锁的值仍是自己的token时才删除, 避免发送超时后删除其他实例拿到的锁
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// AcquireDigestLock 获取发送邮件摘要的锁, 多个实例同时只有一个发送; 拿到锁时返回释放锁用的token
+func AcquireDigestLock(ttl time.Duration) (token string, ok bool, err error) {
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	token = hex.EncodeToString(b)
+	ok, err = client.SetNX(KeyDigestLock, token, ttl).Result()
+	return
+}
+
+// ReleaseDigestLock 发送完成后释放锁, 锁已过期或被其他实例拿到时不做任何操作
+func ReleaseDigestLock(token string) error {
+	return releaseLockScript.Run(client, []string{KeyDigestLock}, token).Err()
+}
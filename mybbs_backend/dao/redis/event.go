@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/go-redis/redis"
+)
+
+// 实时事件通过redis pub/sub分发, 每个实例只推送给连接到自己的客户端, 部署在负载均衡后面时也能收到
+
+// publish 发布实时事件
+func publish(channel, event string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	msg, err := json.Marshal(&models.Event{Event: event, Data: raw})
+	if err != nil {
+		return err
+	}
+	return client.Publish(channel, msg).Err()
+}
+
+// PublishUserEvent 推送给用户的实时事件
+func PublishUserEvent(userID uint64, event string, data interface{}) error {
+	return publish(KeyEventUserChannelPrefix+strconv.FormatUint(userID, 10), event, data)
+}
+
+// PublishPostEvent 帖子的实时事件, 推送给正在浏览该帖子的客户端
+func PublishPostEvent(postID uint64, event string, data interface{}) error {
+	return publish(KeyEventPostChannelPrefix+strconv.FormatUint(postID, 10), event, data)
+}
+
+// EventSubscription 订阅的实时事件, 使用完需要Close
+type EventSubscription struct {
+	C      <-chan *models.Event
+	pubsub *redis.PubSub
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Close 取消订阅
+func (s *EventSubscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.pubsub.Close()
+}
+
+// SubscribeEvents 订阅用户的实时事件, postID不为0时同时订阅该帖子的事件
+func SubscribeEvents(userID, postID uint64) (*EventSubscription, error) {
+	channels := []string{KeyEventUserChannelPrefix + strconv.FormatUint(userID, 10)}
+	if postID > 0 {
+		channels = append(channels, KeyEventPostChannelPrefix+strconv.FormatUint(postID, 10))
+	}
+	pubsub := client.Subscribe(channels...)
+	// 等待订阅确认, 避免订阅成功前发布的事件丢失
+	if _, err := pubsub.Receive(); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+	ch := make(chan *models.Event, 16)
+	sub := &EventSubscription{C: ch, pubsub: pubsub, done: make(chan struct{})}
+	go func() {
+		defer close(ch)
+		for msg := range pubsub.Channel() {
+			e := new(models.Event)
+			if err := json.Unmarshal([]byte(msg.Payload), e); err != nil {
+				continue
+			}
+			select {
+			case ch <- e:
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
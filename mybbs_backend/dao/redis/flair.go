@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"strconv"
+
+	"github.com/go-redis/redis"
+)
+
+// SetPostFlair 修改帖子的flair, 从旧flair的帖子set移到新flair的帖子set, 0表示没有flair
+func SetPostFlair(postID, oldFlairID, newFlairID uint64) error {
+	pipeline := client.TxPipeline()
+	if oldFlairID > 0 {
+		pipeline.SRem(KeyFlairPostSetPrefix+strconv.FormatUint(oldFlairID, 10), postID)
+	}
+	if newFlairID > 0 {
+		pipeline.SAdd(KeyFlairPostSetPrefix+strconv.FormatUint(newFlairID, 10), postID)
+	}
+	_, err := pipeline.Exec()
+	return err
+}
+
+// DeleteFlair 删除flair的帖子set
+func DeleteFlair(flairID uint64) error {
+	return client.Del(KeyFlairPostSetPrefix + strconv.FormatUint(flairID, 10)).Err()
+}
+
+// GetFlairPostIDsInOrder 按flair查询ids(已经根据order从大到小排序)
+// 与按社区查询一样, 把flair的帖子set与orderkey做zinterstore并缓存结果
+func GetFlairPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	orderkey := KeyPostTimeZSet // 默认是时间
+	if p.Order == models.OrderScore {
+		orderkey = KeyPostScoreZSet
+	}
+	fid := strconv.FormatUint(p.FlairID, 10)
+	key := orderkey + ":flair:" + fid
+	if client.Exists(key).Val() < 1 {
+		pipeline := client.Pipeline()
+		pipeline.ZInterStore(key, redis.ZStore{
+			Aggregate: "MAX",
+		}, KeyFlairPostSetPrefix+fid, orderkey)
+		pipeline.Expire(key, feedCacheExpire)
+		if _, err := pipeline.Exec(); err != nil {
+			return nil, err
+		}
+	}
+	return getIDsFormKey(key, p.Page, p.Size)
+}
@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 关注的人的帖子feed: 每个作者维护最近发布的帖子zset, 查询时合并关注的人的zset(fan-out-on-read)
+// 发帖时不需要写入每个粉丝的feed, 大V发帖的开销与粉丝数无关; 只保留最近的帖子, 关注很多人时合并的开销也有上限
+
+const (
+	UserPostZSetMax   = 500       // 每个作者保留的最近帖子数
+	userNoPostsExpire = time.Hour // 作者没有帖子的标记的有效期
+)
+
+// addUserPost 在发帖的事务中把帖子加入作者的帖子zset, 去掉超出数量的旧帖子及没有帖子的标记
+func addUserPost(pipeline redis.Pipeliner, userID, postID uint64, now float64) {
+	key := KeyUserPostZSetPrefix + strconv.FormatUint(userID, 10)
+	pipeline.ZAdd(key, redis.Z{
+		Score:  now,
+		Member: postID,
+	})
+	pipeline.ZRemRangeByRank(key, 0, -UserPostZSetMax-1)
+	pipeline.Del(KeyUserNoPostsPrefix + strconv.FormatUint(userID, 10))
+}
+
+// GetMissingUserPosts 返回帖子zset及没有帖子的标记都不存在的作者, 这些作者需要从mysql重建
+func GetMissingUserPosts(userIDs []uint64) ([]uint64, error) {
+	pipeline := client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(userIDs))
+	for _, id := range userIDs {
+		uid := strconv.FormatUint(id, 10)
+		cmds = append(cmds, pipeline.Exists(KeyUserPostZSetPrefix+uid, KeyUserNoPostsPrefix+uid))
+	}
+	if _, err := pipeline.Exec(); err != nil {
+		return nil, err
+	}
+	missing := make([]uint64, 0)
+	for i, cmd := range cmds {
+		if cmd.Val() < 1 {
+			missing = append(missing, userIDs[i])
+		}
+	}
+	return missing, nil
+}
+
+// SetUserPosts 用mysql中最近的帖子重建作者的帖子zset, 没有帖子时记录标记, 有效期内不再重建
+func SetUserPosts(userID uint64, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return client.Set(KeyUserNoPostsPrefix+strconv.FormatUint(userID, 10), 1, userNoPostsExpire).Err()
+	}
+	members := make([]redis.Z, 0, len(posts))
+	for _, post := range posts {
+		members = append(members, redis.Z{
+			Score:  float64(post.CreateTime.Unix()),
+			Member: post.PostID,
+		})
+	}
+	return client.ZAdd(KeyUserPostZSetPrefix+strconv.FormatUint(userID, 10), members...).Err()
+}
+
+// HasFollowingFeedCache 用户关注的人的帖子合并结果是否仍在缓存中
+func HasFollowingFeedCache(userID uint64) (bool, error) {
+	n, err := client.Exists(KeyFollowingUnionZSetPrefix + strconv.FormatUint(userID, 10)).Result()
+	return n > 0, err
+}
+
+// DeleteFollowingFeedCache 关注/取消关注后删除用户的feed缓存
+func DeleteFollowingFeedCache(userID uint64) error {
+	uid := strconv.FormatUint(userID, 10)
+	return client.Del(KeyFollowingUnionZSetPrefix+uid, KeyFollowingScoreZSetPrefix+uid).Err()
+}
+
+// GetFollowingPostIDsInOrder 查询用户关注的人的帖子ids(已经根据order从大到小排序)
+// 用zunionstore合并各作者的帖子zset, 分数即发帖时间; 按分数排序时再与KeyPostScoreZSet做zinterstore, 结果都按用户缓存
+func GetFollowingPostIDsInOrder(userID uint64, followingIDs []uint64, p *models.ParamPostList) ([]string, error) {
+	uid := strconv.FormatUint(userID, 10)
+	unionKey := KeyFollowingUnionZSetPrefix + uid
+	key := unionKey
+	if p.Order == models.OrderScore {
+		key = KeyFollowingScoreZSetPrefix + uid
+	}
+	if client.Exists(key).Val() < 1 {
+		pipeline := client.Pipeline()
+		if client.Exists(unionKey).Val() < 1 {
+			keys := make([]string, 0, len(followingIDs))
+			for _, id := range followingIDs {
+				keys = append(keys, KeyUserPostZSetPrefix+strconv.FormatUint(id, 10))
+			}
+			pipeline.ZUnionStore(unionKey, redis.ZStore{}, keys...)
+			pipeline.Expire(unionKey, feedCacheExpire)
+		}
+		if key != unionKey {
+			pipeline.ZInterStore(key, redis.ZStore{
+				Weights: []float64{0, 1},
+			}, unionKey, KeyPostScoreZSet)
+			pipeline.Expire(key, feedCacheExpire)
+		}
+		if _, err := pipeline.Exec(); err != nil {
+			return nil, err
+		}
+	}
+	return getIDsFormKey(key, p.Page, p.Size)
+}
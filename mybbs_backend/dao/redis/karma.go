@@ -0,0 +1,12 @@
+package redis
+
+import "strconv"
+
+// GetUserKarma 查询用户的karma
+func GetUserKarma(userID uint64) (int64, error) {
+	karma, err := client.ZScore(KeyUserKarmaZSet, strconv.FormatUint(userID, 10)).Result()
+	if err == Nil {
+		return 0, nil
+	}
+	return int64(karma), err
+}
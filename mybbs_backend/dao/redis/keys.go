@@ -13,4 +13,39 @@ const (
 	KeyPostVotedZSetPrefix = "bluebell:post:voted:"	// zset;记录用户及投票类型;参数是post_id
 
 	KeyCommunityPostSetPrefix = "bluebell:community:"	// set保存每个分区下帖子的id
+	KeyCommunityListCache     = "bluebell:cache:community:list"	// string;社区列表缓存(json),社区变更时删除
+	KeyFlairPostSetPrefix     = "bluebell:flair:"	// set;使用该flair的帖子id;参数是flair_id
+
+	KeyUserCommunitySetPrefix = "bluebell:user:communities:"	// set;用户加入的社区id,mysql的缓存,加入/退出时删除;参数是user_id
+	KeyFeedUnionZSetPrefix    = "bluebell:feed:union:"	// zset;用户加入的所有社区的帖子(ZUNIONSTORE缓存);参数是user_id
+	KeyFeedZSetPrefix         = "bluebell:feed:"	// zset;用户的首页feed按时间或分数排序(ZINTERSTORE缓存);参数是order:user_id
+
+	KeyUserPostZSetPrefix       = "bluebell:user:posts:"	// zset;作者最近发布的帖子及发帖时间;参数是user_id
+	KeyUserNoPostsPrefix        = "bluebell:user:noposts:"	// string;作者没有帖子的标记, 避免每次都从mysql重建;参数是user_id
+	KeyFollowingUnionZSetPrefix = "bluebell:feed:following:union:"	// zset;用户关注的人的帖子按时间排序(ZUNIONSTORE缓存);参数是user_id
+	KeyFollowingScoreZSetPrefix = "bluebell:feed:following:score:"	// zset;用户关注的人的帖子按分数排序(ZINTERSTORE缓存);参数是user_id
+
+	KeyTokenRevokedPrefix = "bluebell:token:revoked:"	// string;已吊销的token;参数是jti
+	KeyTokenFamilyPrefix  = "bluebell:token:family:"	// hash;refresh token家族即设备会话(user_id,current,revoked,user_agent,ip,create_time,last_seen);参数是family_id
+	KeyUserSessionsPrefix = "bluebell:user:sessions:"	// zset;用户的设备会话及创建时间;参数是user_id
+
+	KeyTOTPUsedPrefix         = "bluebell:totp:used:"	// string;已使用过的TOTP时间周期,防止验证码重放;参数是user_id:step
+	KeyTOTPChallengePrefix    = "bluebell:totp:challenge:"	// string;两步验证临时凭证的失败次数;参数是jti
+
+	KeyUserKarmaZSet = "bluebell:user:karma"	// zset;用户的karma(其帖子获得的赞成票减反对票);成员是user_id
+
+	KeyRateLimitPrefix = "bluebell:ratelimit:"	// string;时间窗口内的请求次数;参数是action:user:user_id或action:ip:ip
+	KeySlowModePrefix  = "bluebell:slowmode:"	// string;社区慢速模式下用户下次可以发帖前存在;参数是community_id:user_id
+
+	KeyEventUserChannelPrefix = "bluebell:events:user:"	// pub/sub频道;推送给用户的实时事件(新通知);参数是user_id
+	KeyEventPostChannelPrefix = "bluebell:events:post:"	// pub/sub频道;帖子的实时事件(新评论、投票数);参数是post_id
+
+	KeyPostReadHashPrefix       = "bluebell:read:posts:"	// hash;用户在每个帖子中读到的最后一条评论id;参数是user_id
+	KeyCommunityVisitHashPrefix = "bluebell:visit:communities:"	// hash;用户浏览每个社区的时间("last:prev");参数是user_id
+	KeyReadDirtySet             = "bluebell:read:dirty"	// set;待写入mysql的阅读记录;成员是p:user_id:post_id或c:user_id:community_id
+
+	KeyDigestCommunityZSetPrefix = "bluebell:digest:community:"	// zset;社区的帖子及发帖时间(ZINTERSTORE缓存),用于生成邮件摘要;参数是community_id
+	KeyDigestLock                = "bluebell:digest:lock"	// string;发送邮件摘要任务的锁,多个实例同时只有一个发送
+
+	KeyOIDCStatePrefix = "bluebell:oidc:state:"	// string;第三方登录发起时的nonce及PKCE verifier(json),回调时一次性取出;参数是state
 )
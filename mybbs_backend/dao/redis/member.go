@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	userCommunityExpire = 24 * time.Hour   // 用户加入的社区缓存的有效期
+	feedCacheExpire     = 60 * time.Second // 与按社区查询帖子的缓存时间一致
+)
+
+// GetUserCommunityIDs 读取用户加入的社区缓存, 未缓存时返回nil
+func GetUserCommunityIDs(userID uint64) ([]string, error) {
+	key := KeyUserCommunitySetPrefix + strconv.FormatUint(userID, 10)
+	ids, err := client.SMembers(key).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// SetUserCommunityIDs 缓存用户加入的社区
+func SetUserCommunityIDs(userID uint64, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	key := KeyUserCommunitySetPrefix + strconv.FormatUint(userID, 10)
+	members := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, id)
+	}
+	pipeline := client.TxPipeline()
+	pipeline.Del(key)
+	pipeline.SAdd(key, members...)
+	pipeline.Expire(key, userCommunityExpire)
+	_, err := pipeline.Exec()
+	return err
+}
+
+// DeleteUserFeedCache 加入/退出社区后删除用户的社区缓存及feed缓存
+func DeleteUserFeedCache(userID uint64) error {
+	uid := strconv.FormatUint(userID, 10)
+	return client.Del(
+		KeyUserCommunitySetPrefix+uid,
+		KeyFeedUnionZSetPrefix+uid,
+		KeyFeedZSetPrefix+models.OrderTime+":"+uid,
+		KeyFeedZSetPrefix+models.OrderScore+":"+uid,
+	).Err()
+}
+
+// GetFeedPostIDsInOrder 查询用户加入的社区的帖子ids(已经根据order从大到小排序)
+// 先用zunionstore合并各社区的帖子set, 再与orderkey做zinterstore, 两步的结果都按用户缓存
+func GetFeedPostIDsInOrder(userID uint64, communityIDs []string, p *models.ParamPostList) ([]string, error) {
+	order, orderkey := models.OrderTime, KeyPostTimeZSet // 默认是时间
+	if p.Order == models.OrderScore {
+		order, orderkey = models.OrderScore, KeyPostScoreZSet
+	}
+	uid := strconv.FormatUint(userID, 10)
+	unionKey := KeyFeedUnionZSetPrefix + uid
+	key := KeyFeedZSetPrefix + order + ":" + uid
+	if client.Exists(key).Val() < 1 {
+		pipeline := client.Pipeline()
+		if client.Exists(unionKey).Val() < 1 {
+			cKeys := make([]string, 0, len(communityIDs))
+			for _, id := range communityIDs {
+				cKeys = append(cKeys, KeyCommunityPostSetPrefix+id)
+			}
+			pipeline.ZUnionStore(unionKey, redis.ZStore{}, cKeys...)
+			pipeline.Expire(unionKey, feedCacheExpire)
+		}
+		// union的分数是帖子出现的次数, 权重设为0只保留orderkey中的时间或分数
+		pipeline.ZInterStore(key, redis.ZStore{
+			Weights: []float64{0, 1},
+		}, unionKey, orderkey)
+		pipeline.Expire(key, feedCacheExpire)
+		if _, err := pipeline.Exec(); err != nil {
+			return nil, err
+		}
+	}
+	return getIDsFormKey(key, p.Page, p.Size)
+}
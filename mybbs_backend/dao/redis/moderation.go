@@ -0,0 +1,18 @@
+package redis
+
+import "strconv"
+
+// RemovePost 版主删除帖子后从帖子列表、社区、flair及作者的帖子中移除
+func RemovePost(postID, authorID, communityID, flairID uint64) error {
+	pid := strconv.FormatUint(postID, 10)
+	pipeline := client.TxPipeline()
+	pipeline.ZRem(KeyPostTimeZSet, pid)
+	pipeline.ZRem(KeyPostScoreZSet, pid)
+	pipeline.SRem(KeyCommunityPostSetPrefix+strconv.FormatUint(communityID, 10), pid)
+	pipeline.ZRem(KeyUserPostZSetPrefix+strconv.FormatUint(authorID, 10), pid)
+	if flairID > 0 {
+		pipeline.SRem(KeyFlairPostSetPrefix+strconv.FormatUint(flairID, 10), pid)
+	}
+	_, err := pipeline.Exec()
+	return err
+}
@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"encoding/json"
+	"time"
+)
+
+// SaveOIDCState 保存发起第三方登录时的nonce及PKCE verifier
+func SaveOIDCState(state string, s *models.OIDCState, ttl time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return client.Set(KeyOIDCStatePrefix+state, data, ttl).Err()
+}
+
+// TakeOIDCState 取出并删除state对应的参数, state不存在(过期或已使用)时返回nil
+func TakeOIDCState(state string) (*models.OIDCState, error) {
+	key := KeyOIDCStatePrefix + state
+	pipeline := client.TxPipeline()
+	get := pipeline.Get(key)
+	pipeline.Del(key)
+	if _, err := pipeline.Exec(); err != nil && err != Nil {
+		return nil, err
+	}
+	data, err := get.Bytes()
+	if err == Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := new(models.OIDCState)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
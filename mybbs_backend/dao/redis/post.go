@@ -56,7 +56,7 @@ func GetPostVoteData(ids []string) (data []int64, err error)  {
 	// 使用 pipeline一次发送多条命令减少RTT
 	pipeline := client.Pipeline()
 	for _, id := range ids{
-		key := KeyCommunityPostSetPrefix + id
+		key := KeyPostVotedZSetPrefix + id
 		pipeline.ZCount(key, "1", "1")
 	}
 	cmders, err := pipeline.Exec()
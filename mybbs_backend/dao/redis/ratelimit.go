@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// rateLimitScript 固定窗口计数, 窗口内第一次请求时设置过期时间
+// 返回0表示放行, 否则为距离窗口结束的毫秒数
+var rateLimitScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+if n > tonumber(ARGV[2]) then
+	local ttl = redis.call('PTTL', KEYS[1])
+	if ttl < 0 then
+		redis.call('PEXPIRE', KEYS[1], ARGV[1])
+		ttl = tonumber(ARGV[1])
+	end
+	return ttl
+end
+return 0
+`)
+
+// TakeRateLimit 在时间窗口内计数一次, 超出limit时返回需要等待的时间
+func TakeRateLimit(key string, limit int64, window time.Duration) (time.Duration, error) {
+	ms, err := rateLimitScript.Run(client, []string{KeyRateLimitPrefix + key},
+		int64(window/time.Millisecond), limit).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// TakeSlowMode 社区慢速模式下占用用户的发帖间隔, 间隔未到时返回需要等待的时间
+func TakeSlowMode(communityID, userID uint64, interval time.Duration) (time.Duration, error) {
+	key := KeySlowModePrefix + strconv.FormatUint(communityID, 10) + ":" + strconv.FormatUint(userID, 10)
+	ok, err := client.SetNX(key, 1, interval).Result()
+	if err != nil || ok {
+		return 0, err
+	}
+	ttl, err := client.PTTL(key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl <= 0 {
+		// key恰好过期, 按等待1秒处理
+		ttl = time.Second
+	}
+	return ttl, nil
+}
+
+// ReleaseSlowMode 发帖失败时释放占用的发帖间隔
+func ReleaseSlowMode(communityID, userID uint64) error {
+	key := KeySlowModePrefix + strconv.FormatUint(communityID, 10) + ":" + strconv.FormatUint(userID, 10)
+	return client.Del(key).Err()
+}
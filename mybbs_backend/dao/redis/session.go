@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"strconv"
+	"time"
+)
+
+// GetUserSessions 查询用户所有有效的设备会话, 按创建时间倒序
+// 已过期或已吊销的会话顺带从用户的会话列表中清理掉
+func GetUserSessions(userID uint64) (sessions []*models.Session, err error) {
+	userKey := KeyUserSessionsPrefix + strconv.FormatUint(userID, 10)
+	ids, err := client.ZRevRange(userKey, 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return
+	}
+	pipeline := client.Pipeline()
+	for _, id := range ids {
+		pipeline.HMGet(KeyTokenFamilyPrefix+id, "revoked", "user_agent", "ip", "create_time", "last_seen")
+	}
+	cmders, err := pipeline.Exec()
+	if err != nil {
+		return nil, err
+	}
+	sessions = make([]*models.Session, 0, len(ids))
+	stale := make([]interface{}, 0)
+	for idx, cmder := range cmders {
+		vals := cmder.(*SliceCmd).Val()
+		if vals[0] == nil || vals[0] == "1" {
+			stale = append(stale, ids[idx])
+			continue
+		}
+		sessions = append(sessions, &models.Session{
+			SessionID:  ids[idx],
+			UserAgent:  toString(vals[1]),
+			IP:         toString(vals[2]),
+			CreateTime: toTime(vals[3]),
+			LastSeen:   toTime(vals[4]),
+		})
+	}
+	if len(stale) > 0 {
+		client.ZRem(userKey, stale...)
+	}
+	return
+}
+
+// SessionBelongsTo 判断会话是否属于指定用户
+func SessionBelongsTo(userID uint64, sessionID string) (bool, error) {
+	_, err := client.ZScore(KeyUserSessionsPrefix+strconv.FormatUint(userID, 10), sessionID).Result()
+	if err == Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RemoveUserSession 吊销会话并从用户的会话列表中移除
+func RemoveUserSession(userID uint64, sessionID string) error {
+	if err := (TokenStore{}).RevokeFamily(sessionID); err != nil {
+		return err
+	}
+	return client.ZRem(KeyUserSessionsPrefix+strconv.FormatUint(userID, 10), sessionID).Err()
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func toTime(v interface{}) time.Time {
+	sec, _ := strconv.ParseInt(toString(v), 10, 64)
+	return time.Unix(sec, 0)
+}
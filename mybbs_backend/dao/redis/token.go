@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"bluebell_backend/pkg/jwt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// TokenStore 基于redis的token吊销名单及refresh token家族, 实现jwt.TokenStore
+type TokenStore struct{}
+
+// rotateRefreshScript 原子地轮换refresh token
+// 返回 1:轮换成功 0:家族不存在或已吊销 -1:旧token被重复使用, 已吊销整个家族
+var rotateRefreshScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], 'current')
+if not cur or redis.call('HGET', KEYS[1], 'revoked') == '1' then
+	return 0
+end
+if cur ~= ARGV[1] then
+	redis.call('HSET', KEYS[1], 'revoked', '1')
+	return -1
+end
+redis.call('HSET', KEYS[1], 'current', ARGV[2])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// revokeFamilyScript 只标记仍存在的家族, 避免为已过期的家族创建永不过期的key
+var revokeFamilyScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HSET', KEYS[1], 'revoked', '1')
+end
+return 1
+`)
+
+// checkTokenScript 一次往返完成吊销校验, 家族有效时刷新会话的最近活跃时间
+// last_seen 距今超过一分钟才写入, 避免每个请求都产生写操作
+// 返回 1:已吊销 0:有效
+var checkTokenScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 1
+end
+if KEYS[2] == '' then
+	return 0
+end
+local fam = redis.call('HMGET', KEYS[2], 'current', 'revoked', 'last_seen')
+if not fam[1] or fam[2] == '1' then
+	return 1
+end
+if not fam[3] or tonumber(ARGV[1]) - tonumber(fam[3]) >= 60 then
+	redis.call('HSET', KEYS[2], 'last_seen', ARGV[1])
+end
+return 0
+`)
+
+// Revoke 吊销token, key在token原本过期时自动删除
+func (TokenStore) Revoke(jti string, ttl time.Duration) error {
+	return client.Set(KeyTokenRevokedPrefix+jti, 1, ttl).Err()
+}
+
+// IsRevoked 判断token是否已被吊销: jti在吊销名单中, 或所属家族已吊销/已过期
+func (TokenStore) IsRevoked(jti, familyID string) (bool, error) {
+	familyKey := ""
+	if familyID != "" {
+		familyKey = KeyTokenFamilyPrefix + familyID
+	}
+	res, err := checkTokenScript.Run(client, []string{KeyTokenRevokedPrefix + jti, familyKey},
+		time.Now().Unix()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// SaveRefresh 创建refresh token家族, 同时记录为用户的一个设备会话
+func (TokenStore) SaveRefresh(familyID, jti string, userID uint64, device *jwt.Device, ttl time.Duration) error {
+	key := KeyTokenFamilyPrefix + familyID
+	now := time.Now().Unix()
+	pipeline := client.TxPipeline()
+	pipeline.HMSet(key, map[string]interface{}{
+		"user_id":     strconv.FormatUint(userID, 10),
+		"current":     jti,
+		"revoked":     "0",
+		"user_agent":  device.UserAgent,
+		"ip":          device.IP,
+		"create_time": now,
+		"last_seen":   now,
+	})
+	pipeline.Expire(key, ttl)
+	pipeline.ZAdd(KeyUserSessionsPrefix+strconv.FormatUint(userID, 10), redis.Z{
+		Score:  float64(now),
+		Member: familyID,
+	})
+	_, err := pipeline.Exec()
+	return err
+}
+
+// RotateRefresh 轮换家族当前的refresh token
+func (TokenStore) RotateRefresh(familyID, oldJti, newJti string, ttl time.Duration) error {
+	res, err := rotateRefreshScript.Run(client, []string{KeyTokenFamilyPrefix + familyID},
+		oldJti, newJti, int64(ttl/time.Second)).Int64()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 1:
+		return nil
+	case -1:
+		return jwt.ErrorRefreshTokenReused
+	default:
+		return jwt.ErrorRefreshTokenInvalid
+	}
+}
+
+// RevokeFamily 吊销整个家族, 保留key直到过期以便继续识别重复使用
+func (TokenStore) RevokeFamily(familyID string) error {
+	return revokeFamilyScript.Run(client, []string{KeyTokenFamilyPrefix + familyID}).Err()
+}
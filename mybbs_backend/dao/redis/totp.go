@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+)
+
+// MarkTOTPStepUsed 标记用户某个时间周期的验证码已使用, 返回false表示该验证码已经用过(重放)
+func MarkTOTPStepUsed(userID uint64, step int64, ttl time.Duration) (bool, error) {
+	key := KeyTOTPUsedPrefix + strconv.FormatUint(userID, 10) + ":" + strconv.FormatInt(step, 10)
+	return client.SetNX(key, 1, ttl).Result()
+}
+
+// IncrTOTPChallengeAttempts 记录一次两步验证失败, 返回该临时凭证累计的失败次数
+func IncrTOTPChallengeAttempts(jti string, ttl time.Duration) (int64, error) {
+	key := KeyTOTPChallengePrefix + jti
+	pipeline := client.TxPipeline()
+	incr := pipeline.Incr(key)
+	pipeline.Expire(key, ttl)
+	if _, err := pipeline.Exec(); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
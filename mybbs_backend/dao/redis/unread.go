@@ -0,0 +1,226 @@
+package redis
+
+import (
+	"bluebell_backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 阅读记录: 帖子中读到的最后一条评论及社区的浏览时间
+// 先写入redis并记录到待写入集合, 由后台任务定期写入mysql; redis中没有的记录从mysql读取后回填
+
+const readMarkerExpire = 30 * 24 * time.Hour // 用户的阅读记录在redis中的有效期, 每次写入时延长
+
+// postReadScript 记录读到的最后一条评论, 只前进不后退, 有变化时加入待写入集合
+// 评论id超出lua数字的精度, 按长度及字符串比较大小
+var postReadScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], ARGV[1])
+local id = ARGV[2]
+if cur and (#cur > #id or (#cur == #id and cur >= id)) then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], id)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+redis.call('SADD', KEYS[2], ARGV[4])
+return 1
+`)
+
+// communityVisitScript 记录浏览社区的时间, 距上次浏览超过gap时算作新的一次浏览, 返回"last:prev"
+var communityVisitScript = redis.NewScript(`
+local v = redis.call('HGET', KEYS[1], ARGV[1])
+local now = tonumber(ARGV[2])
+local last, prev = 0, 0
+if v then
+	local i = string.find(v, ':')
+	last = tonumber(string.sub(v, 1, i - 1))
+	prev = tonumber(string.sub(v, i + 1))
+end
+if now - last > tonumber(ARGV[3]) then
+	prev = last
+end
+v = now .. ':' .. prev
+redis.call('HSET', KEYS[1], ARGV[1], v)
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+redis.call('SADD', KEYS[2], ARGV[5])
+return v
+`)
+
+// DirtyMember 待写入mysql的阅读记录
+func DirtyMember(kind string, userID, targetID uint64) string {
+	return kind + ":" + strconv.FormatUint(userID, 10) + ":" + strconv.FormatUint(targetID, 10)
+}
+
+// ParseDirtyMember 解析待写入的阅读记录
+func ParseDirtyMember(member string) (kind string, userID, targetID uint64, ok bool) {
+	parts := strings.Split(member, ":")
+	if len(parts) != 3 {
+		return
+	}
+	var err error
+	if userID, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return
+	}
+	if targetID, err = strconv.ParseUint(parts[2], 10, 64); err != nil {
+		return
+	}
+	return parts[0], userID, targetID, true
+}
+
+// GetPostReads 查询用户在帖子中读到的最后一条评论, redis中没有的帖子不在结果中
+func GetPostReads(userID uint64, postIDs []uint64) (map[uint64]uint64, error) {
+	reads := make(map[uint64]uint64, len(postIDs))
+	if len(postIDs) == 0 {
+		return reads, nil
+	}
+	fields := make([]string, 0, len(postIDs))
+	for _, id := range postIDs {
+		fields = append(fields, strconv.FormatUint(id, 10))
+	}
+	vals, err := client.HMGet(KeyPostReadHashPrefix+strconv.FormatUint(userID, 10), fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+			reads[postIDs[i]] = id
+		}
+	}
+	return reads, nil
+}
+
+// SetPostRead 记录用户在帖子中读到的最后一条评论
+func SetPostRead(userID, postID, commentID uint64) error {
+	return postReadScript.Run(client,
+		[]string{KeyPostReadHashPrefix + strconv.FormatUint(userID, 10), KeyReadDirtySet},
+		postID, commentID, int64(readMarkerExpire/time.Millisecond), DirtyMember("p", userID, postID)).Err()
+}
+
+// FillPostReads 回填从mysql读取的阅读记录, 不需要再写入mysql
+func FillPostReads(userID uint64, reads []*models.PostRead) error {
+	if len(reads) == 0 {
+		return nil
+	}
+	key := KeyPostReadHashPrefix + strconv.FormatUint(userID, 10)
+	fields := make(map[string]interface{}, len(reads))
+	for _, r := range reads {
+		fields[strconv.FormatUint(r.PostID, 10)] = r.LastCommentID
+	}
+	pipeline := client.Pipeline()
+	pipeline.HMSet(key, fields)
+	pipeline.Expire(key, readMarkerExpire)
+	_, err := pipeline.Exec()
+	return err
+}
+
+// parseVisit 解析"last:prev"格式的浏览时间
+func parseVisit(communityID uint64, v string) (*models.CommunityVisit, bool) {
+	i := strings.IndexByte(v, ':')
+	if i < 0 {
+		return nil, false
+	}
+	last, err1 := strconv.ParseInt(v[:i], 10, 64)
+	prev, err2 := strconv.ParseInt(v[i+1:], 10, 64)
+	if err1 != nil || err2 != nil {
+		return nil, false
+	}
+	return &models.CommunityVisit{CommunityID: communityID, LastVisit: last, PrevVisit: prev}, true
+}
+
+// GetCommunityVisits 查询用户浏览社区的时间, redis中没有的社区不在结果中
+func GetCommunityVisits(userID uint64, communityIDs []uint64) (map[uint64]*models.CommunityVisit, error) {
+	visits := make(map[uint64]*models.CommunityVisit, len(communityIDs))
+	if len(communityIDs) == 0 {
+		return visits, nil
+	}
+	fields := make([]string, 0, len(communityIDs))
+	for _, id := range communityIDs {
+		fields = append(fields, strconv.FormatUint(id, 10))
+	}
+	vals, err := client.HMGet(KeyCommunityVisitHashPrefix+strconv.FormatUint(userID, 10), fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if visit, ok := parseVisit(communityIDs[i], s); ok {
+			visits[communityIDs[i]] = visit
+		}
+	}
+	return visits, nil
+}
+
+// RecordCommunityVisit 记录浏览社区, 返回更新后的浏览时间
+func RecordCommunityVisit(userID, communityID uint64, now time.Time, gap time.Duration) (*models.CommunityVisit, error) {
+	v, err := communityVisitScript.Run(client,
+		[]string{KeyCommunityVisitHashPrefix + strconv.FormatUint(userID, 10), KeyReadDirtySet},
+		communityID, now.Unix(), int64(gap/time.Second), int64(readMarkerExpire/time.Millisecond),
+		DirtyMember("c", userID, communityID)).String()
+	if err != nil {
+		return nil, err
+	}
+	visit, _ := parseVisit(communityID, v)
+	return visit, nil
+}
+
+// FillCommunityVisits 回填从mysql读取的浏览时间, 不需要再写入mysql
+func FillCommunityVisits(userID uint64, visits []*models.CommunityVisit) error {
+	if len(visits) == 0 {
+		return nil
+	}
+	key := KeyCommunityVisitHashPrefix + strconv.FormatUint(userID, 10)
+	fields := make(map[string]interface{}, len(visits))
+	for _, v := range visits {
+		fields[strconv.FormatUint(v.CommunityID, 10)] = strconv.FormatInt(v.LastVisit, 10) + ":" + strconv.FormatInt(v.PrevVisit, 10)
+	}
+	pipeline := client.Pipeline()
+	pipeline.HMSet(key, fields)
+	pipeline.Expire(key, readMarkerExpire)
+	_, err := pipeline.Exec()
+	return err
+}
+
+// PopReadDirty 取出最多count条待写入mysql的阅读记录
+func PopReadDirty(count int64) ([]string, error) {
+	return client.SPopN(KeyReadDirtySet, count).Result()
+}
+
+// RestoreReadDirty 写入mysql失败时放回待写入集合
+func RestoreReadDirty(members []string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		values = append(values, m)
+	}
+	return client.SAdd(KeyReadDirtySet, values...).Err()
+}
+
+// GetPostRead 查询redis中一条帖子阅读记录, 用于写入mysql
+func GetPostRead(userID, postID uint64) (uint64, bool, error) {
+	reads, err := GetPostReads(userID, []uint64{postID})
+	if err != nil {
+		return 0, false, err
+	}
+	id, ok := reads[postID]
+	return id, ok, nil
+}
+
+// GetCommunityVisit 查询redis中一条社区浏览记录, 用于写入mysql
+func GetCommunityVisit(userID, communityID uint64) (*models.CommunityVisit, error) {
+	visits, err := GetCommunityVisits(userID, []uint64{communityID})
+	if err != nil {
+		return nil, err
+	}
+	return visits[communityID], nil
+}
@@ -66,14 +66,16 @@ func VoteForPost(userID string, postID string, v float64) (err error) {
 		op = -1
 	}
 	diffAbs := math.Abs(ov - v)		// 计算两次投票的差值
+	// 帖子作者的karma随之变化, 给自己的帖子投票不计入
+	authorID := client.HGet(KeyPostInfoHashPrefix+postID, "user:id").Val()
 	pipeline := client.TxPipeline()	// 事务操作
-	_, err = pipeline.ZIncrBy(KeyPostScoreZSet, VoteScore*diffAbs*op, postID).Result() // 更新分数
-	if ErrorVoteTimeExpire != nil {
-		return err
+	pipeline.ZIncrBy(KeyPostScoreZSet, VoteScore*diffAbs*op, postID) // 更新分数
+	if len(authorID) > 0 && authorID != userID {
+		pipeline.ZIncrBy(KeyUserKarmaZSet, v-ov, authorID)
 	}
 	// 3、记录用户为该帖子投票的数据
 	if v ==0 {
-		_, err = client.ZRem(key, postID).Result()
+		pipeline.ZRem(key, userID)
 	} else {
 		pipeline.ZAdd(key, redis.Z{ // 记录已投票
 			Score:  v,		// 赞成票还是反对票
@@ -139,6 +141,7 @@ func CreatePost(postID, userID uint64, title, summary string, CommunityID uint64
 		Member: postID,
 	})
 	pipeline.SAdd(communityKey, postID) // 添加到对应版块  把帖子添加到社区的set
+	addUserPost(pipeline, userID, postID, now)
 	_, err = pipeline.Exec()
 	return
 }
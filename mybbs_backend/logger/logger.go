@@ -70,8 +70,9 @@ func GinLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
 		c.Next()
+		// 处理完再读取query, 中间件可能去掉了其中的token
+		query := c.Request.URL.RawQuery
 
 		cost := time.Since(start)
 		lg.Info(path,
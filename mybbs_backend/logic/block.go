@@ -0,0 +1,320 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 屏蔽用户及不想看的社区、关键词
+// 屏蔽的用户的帖子、评论及通知不再展示, 双方不能私信、@提到及关注对方
+// 屏蔽的社区及关键词只在帖子列表及feed中过滤, 直接浏览屏蔽的社区时仍然展示
+// 过滤掉的帖子由后面的帖子补上, 每页的数量不变
+
+const (
+	filterBatchMin   = 20   // 过滤时每批最少取出的帖子数
+	filterBatchMax   = 500  // 过滤时每批最多取出的帖子数
+	filterScanMin    = 1000 // 过滤时一次请求至少可以扫描的帖子数
+	filterScanFactor = 5    // 过滤时一次请求最多扫描page*size的多少倍, 超出后返回已凑到的帖子
+)
+
+var (
+	ErrorBlockSelf       = errors.New("不能屏蔽自己")
+	ErrorUserBlocked     = errors.New("对方已屏蔽你或你已屏蔽对方")
+	ErrorTooManyKeywords = errors.New("屏蔽的关键词数量超出限制")
+	ErrorKeywordEmpty    = errors.New("关键词不能为空")
+)
+
+// BlockUser 屏蔽用户, 同时解除双方的关注关系
+func BlockUser(userID, targetID uint64) error {
+	if userID == targetID {
+		return ErrorBlockSelf
+	}
+	if _, err := mysql.GetUserBrief(targetID); err != nil {
+		return err
+	}
+	if err := mysql.BlockUser(userID, targetID); err != nil {
+		return err
+	}
+	for _, id := range []uint64{userID, targetID} {
+		if err := redis.DeleteFollowingFeedCache(id); err != nil {
+			zap.L().Warn("redis.DeleteFollowingFeedCache failed", zap.Uint64("user_id", id), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// UnblockUser 取消屏蔽用户
+func UnblockUser(userID, targetID uint64) error {
+	return mysql.UnblockUser(userID, targetID)
+}
+
+// MuteCommunity 屏蔽社区
+func MuteCommunity(userID, communityID uint64) error {
+	if _, err := mysql.GetCommunityByID(communityID); err != nil {
+		return err
+	}
+	return mysql.MuteCommunity(userID, communityID)
+}
+
+// UnmuteCommunity 取消屏蔽社区
+func UnmuteCommunity(userID, communityID uint64) error {
+	return mysql.UnmuteCommunity(userID, communityID)
+}
+
+// normalizeKeyword 关键词不区分大小写
+func normalizeKeyword(keyword string) string {
+	return strings.ToLower(strings.TrimSpace(keyword))
+}
+
+// MuteKeyword 屏蔽关键词
+func MuteKeyword(userID uint64, keyword string) error {
+	keyword = normalizeKeyword(keyword)
+	if len(keyword) == 0 {
+		return ErrorKeywordEmpty
+	}
+	keywords, err := mysql.GetMutedKeywords(userID)
+	if err != nil {
+		return err
+	}
+	for _, k := range keywords {
+		if k == keyword {
+			return nil
+		}
+	}
+	if len(keywords) >= models.MaxMutedKeywords {
+		return ErrorTooManyKeywords
+	}
+	return mysql.MuteKeyword(userID, keyword)
+}
+
+// UnmuteKeyword 取消屏蔽关键词
+func UnmuteKeyword(userID uint64, keyword string) error {
+	return mysql.UnmuteKeyword(userID, normalizeKeyword(keyword))
+}
+
+// GetBlockList 查询屏蔽的用户、社区及关键词
+func GetBlockList(userID uint64) (*models.BlockList, error) {
+	users, err := mysql.GetBlockedUserList(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		user.Avatar = avatarURL(user.UserID, user.Avatar)
+	}
+	communities, err := mysql.GetMutedCommunityList(userID)
+	if err != nil {
+		return nil, err
+	}
+	keywords, err := mysql.GetMutedKeywords(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BlockList{Users: users, Communities: communities, Keywords: keywords}, nil
+}
+
+// isBlockedBy 当前用户是否屏蔽了userID, 未登录时为false
+func isBlockedBy(v *Viewer, userID uint64) (bool, error) {
+	if v == nil || v.UserID == userID {
+		return false, nil
+	}
+	return mysql.IsBlocked(v.UserID, userID)
+}
+
+// isBlockedEither 两个用户中是否有一方屏蔽了另一方
+func isBlockedEither(a, b uint64) (bool, error) {
+	blocked, err := mysql.IsBlocked(a, b)
+	if err != nil || blocked {
+		return blocked, err
+	}
+	return mysql.IsBlocked(b, a)
+}
+
+// filterBlockedComments 去掉当前用户屏蔽的用户的评论
+func filterBlockedComments(v *Viewer, comments []*models.Comment) ([]*models.Comment, error) {
+	if v == nil || len(comments) == 0 {
+		return comments, nil
+	}
+	blocked, err := mysql.GetBlockedIDs(v.UserID)
+	if err != nil || len(blocked) == 0 {
+		return comments, err
+	}
+	skip := make(map[uint64]bool, len(blocked))
+	for _, id := range blocked {
+		skip[id] = true
+	}
+	res := comments[:0]
+	for _, comment := range comments {
+		if !skip[comment.AuthorID] {
+			res = append(res, comment)
+		}
+	}
+	return res, nil
+}
+
+// postFilter 帖子列表的过滤条件: 无权浏览的私有社区、屏蔽的用户、社区及关键词, 以及浏览偏好隐藏的NSFW帖子
+type postFilter struct {
+	communities map[uint64]bool
+	authors     map[uint64]bool
+	keywords    []string
+	hideNSFW    bool
+}
+
+// newPostFilter 查询当前用户的过滤条件, muteCommunities为false时不按屏蔽的社区过滤
+func newPostFilter(v *Viewer, muteCommunities bool) (*postFilter, error) {
+	f := &postFilter{
+		communities: make(map[uint64]bool),
+		authors:     make(map[uint64]bool),
+	}
+	prefs, err := GetPreferences(v)
+	if err != nil {
+		return nil, err
+	}
+	f.hideNSFW = prefs.HideNSFW
+	hidden, err := hiddenCommunities(v)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range hidden {
+		f.communities[id] = true
+	}
+	if v == nil {
+		return f, nil
+	}
+	blocked, err := mysql.GetBlockedIDs(v.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range blocked {
+		f.authors[id] = true
+	}
+	if muteCommunities {
+		muted, err := mysql.GetMutedCommunityIDs(v.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range muted {
+			f.communities[id] = true
+		}
+	}
+	if f.keywords, err = mysql.GetMutedKeywords(v.UserID); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// empty 没有任何过滤条件
+func (f *postFilter) empty() bool {
+	return len(f.communities) == 0 && len(f.authors) == 0 && len(f.keywords) == 0 && !f.hideNSFW
+}
+
+// keep 帖子是否展示
+func (f *postFilter) keep(post *models.Post) bool {
+	if f.communities[post.CommunityID] || f.authors[post.AuthorId] || (post.NSFW && f.hideNSFW) {
+		return false
+	}
+	if len(f.keywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(post.Title + "\n" + post.Content)
+	for _, k := range f.keywords {
+		if strings.Contains(text, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter 从帖子列表中去掉不展示的帖子
+func (f *postFilter) filter(data []*models.ApiPostDetail) []*models.ApiPostDetail {
+	res := data[:0]
+	for _, post := range data {
+		if f.keep(post.Post) {
+			res = append(res, post)
+		}
+	}
+	return res
+}
+
+// idFetcher 分页查询排好序的帖子id
+type idFetcher func(page, size int64) ([]string, error)
+
+// postLoader 按id查询帖子本身, 不填充详情
+type postLoader func(ids []string) ([]*models.Post, error)
+
+// pageOf 复制查询参数并修改分页
+func pageOf(p *models.ParamPostList, page, size int64) *models.ParamPostList {
+	q := *p
+	q.Page, q.Size = page, size
+	return &q
+}
+
+// listPosts 分页查询帖子并按过滤条件去掉不展示的帖子, 去掉的帖子由后面的帖子补上
+// 凑满一页后再填充投票数、作者等详情
+func listPosts(page, size int64, fetch idFetcher, f *postFilter) ([]*models.ApiPostDetail, error) {
+	if f.empty() {
+		ids, err := fetch(page, size)
+		if err != nil {
+			return nil, err
+		}
+		return getPostListByIDs(ids)
+	}
+	kept, err := scanPosts(page, size, fetch, mysql.GetPostListByIDs, f.keep)
+	if err != nil {
+		return nil, err
+	}
+	return getPostDetails(kept)
+}
+
+// scanPosts 从头按批次取出id, 只查询帖子本身判断是否展示, 跳过前面各页展示的帖子后返回第page页
+// 每批的大小及最多扫描的帖子数随page*size增大, 翻到后面的页时不会因为扫描数量不够而返回空页
+func scanPosts(page, size int64, fetch idFetcher, load postLoader, keep func(*models.Post) bool) ([]*models.Post, error) {
+	want := page * size
+	batch := want + size
+	if batch < filterBatchMin {
+		batch = filterBatchMin
+	}
+	if batch > filterBatchMax {
+		batch = filterBatchMax
+	}
+	scanMax := want * filterScanFactor
+	if scanMax < filterScanMin {
+		scanMax = filterScanMin
+	}
+	skip := want - size
+	kept := make([]*models.Post, 0, size)
+	for n := int64(1); (n-1)*batch < scanMax; n++ {
+		ids, err := fetch(n, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		posts, err := load(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			if !keep(post) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			kept = append(kept, post)
+			if int64(len(kept)) == size {
+				return kept, nil
+			}
+		}
+		if int64(len(ids)) < batch {
+			break
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,101 @@
+package logic
+
+import (
+	"bluebell_backend/models"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePosts 按顺序排好的帖子, 提供分页查询id及按id查询帖子, 记录查询的次数
+type fakePosts struct {
+	posts   map[string]*models.Post
+	order   []string
+	fetches int
+}
+
+// newFakePosts 创建n个帖子, id从n到1排序, nsfw返回true的帖子标记为NSFW
+func newFakePosts(n int, nsfw func(id int) bool) *fakePosts {
+	fp := &fakePosts{posts: make(map[string]*models.Post, n)}
+	for id := n; id > 0; id-- {
+		key := strconv.Itoa(id)
+		fp.posts[key] = &models.Post{PostID: uint64(id), NSFW: nsfw(id)}
+		fp.order = append(fp.order, key)
+	}
+	return fp
+}
+
+func (fp *fakePosts) fetch(page, size int64) ([]string, error) {
+	fp.fetches++
+	start := (page - 1) * size
+	if start >= int64(len(fp.order)) {
+		return nil, nil
+	}
+	end := start + size
+	if end > int64(len(fp.order)) {
+		end = int64(len(fp.order))
+	}
+	return fp.order[start:end], nil
+}
+
+func (fp *fakePosts) load(ids []string) ([]*models.Post, error) {
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, fp.posts[id])
+	}
+	return posts, nil
+}
+
+func postIDs(posts []*models.Post) []uint64 {
+	ids := make([]uint64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.PostID)
+	}
+	return ids
+}
+
+func TestScanPostsRefill(t *testing.T) {
+	// 每3个帖子中有1个NSFW, 被去掉的帖子由后面的帖子补上
+	fp := newFakePosts(100, func(id int) bool { return id%3 == 0 })
+	f := &postFilter{hideNSFW: true}
+
+	page1, err := scanPosts(1, 5, fp.fetch, fp.load, f.keep)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{100, 98, 97, 95, 94}, postIDs(page1))
+
+	// 第二页跳过第一页展示的帖子
+	page2, err := scanPosts(2, 5, fp.fetch, fp.load, f.keep)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{92, 91, 89, 88, 86}, postIDs(page2))
+
+	// 最后一页不满一页, 再往后为空
+	last, err := scanPosts(14, 5, fp.fetch, fp.load, f.keep)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{2, 1}, postIDs(last))
+	empty, err := scanPosts(15, 5, fp.fetch, fp.load, f.keep)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(empty))
+}
+
+func TestScanPostsDeepPage(t *testing.T) {
+	// 翻到扫描数量超过filterScanMin的页时仍然返回完整的一页
+	fp := newFakePosts(5000, func(id int) bool { return id%2 == 0 })
+	f := &postFilter{hideNSFW: true}
+	posts, err := scanPosts(60, 10, fp.fetch, fp.load, f.keep)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(posts))
+	// 第60页从第591个展示的帖子开始, 只有奇数id展示
+	assert.Equal(t, uint64(5000-2*590-1), posts[0].PostID)
+	for _, post := range posts {
+		assert.False(t, post.NSFW)
+	}
+
+	// 全部被过滤时扫描数量有上限
+	fp = newFakePosts(100000, func(id int) bool { return true })
+	fp.fetches = 0
+	posts, err = scanPosts(1, 10, fp.fetch, fp.load, f.keep)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(posts))
+	assert.Equal(t, filterScanMin/filterBatchMin, fp.fetches)
+}
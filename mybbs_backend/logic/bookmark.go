@@ -0,0 +1,151 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 收藏帖子及收藏夹
+// 收藏的帖子可以放到自己创建的收藏夹中, 不指定收藏夹时为未分类; 删除收藏夹时其中的帖子移到未分类
+
+var (
+	ErrorFolderNotExist  = errors.New("收藏夹不存在")
+	ErrorTooManyFolders  = errors.New("收藏夹数量超出限制")
+	ErrorFolderNameEmpty = errors.New("收藏夹名称不能为空")
+)
+
+// getBookmarkFolder 查询当前用户的收藏夹, 0表示未分类
+func getBookmarkFolder(userID, folderID uint64) error {
+	if folderID == 0 {
+		return nil
+	}
+	_, err := mysql.GetBookmarkFolder(userID, folderID)
+	if errors.Is(err, mysql.ErrorInvalidID) {
+		return ErrorFolderNotExist
+	}
+	return err
+}
+
+// SavePost 收藏帖子, 已收藏时移动到指定的收藏夹
+func SavePost(v *Viewer, postID, folderID uint64) error {
+	if _, _, err := readablePost(v, int64(postID)); err != nil {
+		return err
+	}
+	if err := getBookmarkFolder(v.UserID, folderID); err != nil {
+		return err
+	}
+	return mysql.SavePost(v.UserID, postID, folderID)
+}
+
+// UnsavePost 取消收藏
+func UnsavePost(v *Viewer, postID uint64) error {
+	return mysql.UnsavePost(v.UserID, postID)
+}
+
+// GetSavedPostList 分页查询收藏的帖子, folderID为nil时查询全部
+// 之后无权浏览的私有社区(如退出了社区)的帖子不展示
+func GetSavedPostList(v *Viewer, folderID *uint64, page, size int64) ([]*models.ApiPostDetail, error) {
+	if folderID != nil {
+		if err := getBookmarkFolder(v.UserID, *folderID); err != nil {
+			return nil, err
+		}
+	}
+	hidden, err := hiddenCommunities(v)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := GetPreferences(v)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := mysql.GetSavedPostList(v.UserID, folderID, page, size, hidden, prefs.HideNSFW)
+	if err != nil {
+		return nil, err
+	}
+	data, err := getPostDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+	return applyPreferences(v, data)
+}
+
+// GetBookmarkFolderList 查询我的收藏夹
+func GetBookmarkFolderList(userID uint64) ([]*models.BookmarkFolder, error) {
+	return mysql.GetBookmarkFolderList(userID)
+}
+
+// CreateBookmarkFolder 创建收藏夹
+func CreateBookmarkFolder(userID uint64, name string) (*models.BookmarkFolder, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return nil, ErrorFolderNameEmpty
+	}
+	folders, err := mysql.GetBookmarkFolderList(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(folders) >= models.MaxBookmarkFolders {
+		return nil, ErrorTooManyFolders
+	}
+	id, err := mysql.CreateBookmarkFolder(userID, name)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.GetBookmarkFolder(userID, id)
+}
+
+// RenameBookmarkFolder 重命名收藏夹
+func RenameBookmarkFolder(userID, folderID uint64, name string) error {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return ErrorFolderNameEmpty
+	}
+	if folderID == 0 {
+		return ErrorFolderNotExist
+	}
+	if err := getBookmarkFolder(userID, folderID); err != nil {
+		return err
+	}
+	return mysql.RenameBookmarkFolder(userID, folderID, name)
+}
+
+// DeleteBookmarkFolder 删除收藏夹, 其中的帖子移到未分类
+func DeleteBookmarkFolder(userID, folderID uint64) error {
+	if folderID == 0 {
+		return ErrorFolderNotExist
+	}
+	if err := getBookmarkFolder(userID, folderID); err != nil {
+		return err
+	}
+	return mysql.DeleteBookmarkFolder(userID, folderID)
+}
+
+// fillSaved 填充当前用户是否收藏了帖子及所在的收藏夹, 查询失败不影响帖子列表
+func fillSaved(v *Viewer, data []*models.ApiPostDetail) {
+	if v == nil || len(data) == 0 {
+		return
+	}
+	ids := make([]uint64, 0, len(data))
+	for _, post := range data {
+		ids = append(ids, post.PostID)
+	}
+	bookmarks, err := mysql.GetBookmarks(v.UserID, ids)
+	if err != nil {
+		zap.L().Warn("mysql.GetBookmarks failed", zap.Uint64("user_id", v.UserID), zap.Error(err))
+		return
+	}
+	folders := make(map[uint64]uint64, len(bookmarks))
+	for _, b := range bookmarks {
+		folders[b.PostID] = b.FolderID
+	}
+	for _, post := range data {
+		if folder, ok := folders[post.PostID]; ok {
+			post.Saved = true
+			post.SavedFolderID = folder
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/snowflake"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 评论
+
+// CreateComment 创建评论, 锁定的帖子及被禁言的用户不能评论, 私有社区只有成员可以评论
+func CreateComment(v *Viewer, comment *models.Comment) error {
+	post, _, err := readablePost(v, int64(comment.PostID))
+	if err != nil {
+		return err
+	}
+	if post.Locked {
+		return ErrorPostLocked
+	}
+	if err := checkBan(comment.AuthorID, post.CommunityID); err != nil {
+		return err
+	}
+	// 生成评论ID
+	if comment.CommentID, err = snowflake.GetID(); err != nil {
+		zap.L().Error("snowflake.GetID() failed", zap.Error(err))
+		return err
+	}
+	if err := mysql.CreateComment(comment); err != nil {
+		return err
+	}
+	comment.CreateTime = time.Now()
+	// 自己的评论不算作未读
+	setPostRead(comment.AuthorID, comment.PostID, comment.CommentID)
+	pushComment(comment)
+	notifyComment(post, comment)
+	handleMentions(comment.AuthorID, post, comment.CommentID, comment.Content)
+	triggerCommentWebhooks(post.CommunityID, comment)
+	return nil
+}
+
+// GetCommentList 根据ids查询评论, 去掉无权浏览的私有社区的评论及屏蔽的用户的评论
+func GetCommentList(v *Viewer, ids []string) ([]*models.Comment, error) {
+	hidden, err := hiddenCommunities(v)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := mysql.GetCommentListByIDs(ids, hidden)
+	if err != nil {
+		return nil, err
+	}
+	if comments, err = filterBlockedComments(v, comments); err != nil {
+		return nil, err
+	}
+	fillCommentMentions(comments)
+	return comments, nil
+}
@@ -7,7 +7,13 @@ package logic
 
 import (
 	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
 	"bluebell_backend/models"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 /**
@@ -15,9 +21,28 @@ import (
  * @Description //TODO 查询分类社区列表
  * @Date 16:42 2022/2/12
  **/
-func GetCommunityList() ([] *models.Community,error) {
-	// 查数据库 查找到所有的community 并返回
-	return mysql.GetCommunityList()
+func GetCommunityList(v *Viewer) ([] *models.Community,error) {
+	// 查数据库 查找到所有的community 并返回, 社区变化很少, 优先读缓存
+	list, err := GetCachedCommunityList()
+	if err != nil {
+		return nil, err
+	}
+	// 私有社区只对成员、版主及管理员展示
+	hidden, err := hiddenCommunities(v)
+	if err != nil || len(hidden) == 0 {
+		return list, err
+	}
+	skip := make(map[uint64]bool, len(hidden))
+	for _, id := range hidden {
+		skip[id] = true
+	}
+	visible := make([]*models.Community, 0, len(list))
+	for _, c := range list {
+		if !skip[c.CommunityID] {
+			visible = append(visible, c)
+		}
+	}
+	return visible, nil
 }
 
 /**
@@ -25,6 +50,129 @@ func GetCommunityList() ([] *models.Community,error) {
  * @Description //TODO 根据ID查询分类社区详情 
  * @Date 17:08 2022/2/12
  **/
-func GetCommunityDetailByID(id uint64) (*models.CommunityDetail,error) {
+func GetCommunityDetailByID(v *Viewer, id uint64) (*models.CommunityDetail,error) {
+	// 无权浏览的私有社区与不存在的社区一样返回ErrorInvalidID
+	community, err := readableCommunity(v, id)
+	if err != nil {
+		return nil, err
+	}
+	// 社区的成员数
+	if community.MemberCount, err = mysql.CountCommunityMembers(id); err != nil {
+		zap.L().Error("mysql.CountCommunityMembers failed", zap.Uint64("community_id", id), zap.Error(err))
+		return nil, err
+	}
+	return community, nil
+}
+
+// communityListCacheExpire 社区列表缓存的有效期, 社区变更时会主动删除缓存
+const communityListCacheExpire = 10 * time.Minute
+
+var ErrorCommunityArchived = errors.New("社区已归档")
+
+// GetCachedCommunityList 查询社区列表, 优先读取redis缓存
+func GetCachedCommunityList() ([]*models.Community, error) {
+	list, err := redis.GetCommunityListCache()
+	if err != nil {
+		zap.L().Warn("redis.GetCommunityListCache failed", zap.Error(err))
+	}
+	if list != nil {
+		return list, nil
+	}
+	if list, err = mysql.GetCommunityList(); err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = make([]*models.Community, 0)
+	}
+	if err := redis.SetCommunityListCache(list, communityListCacheExpire); err != nil {
+		zap.L().Warn("redis.SetCommunityListCache failed", zap.Error(err))
+	}
+	return list, nil
+}
+
+// invalidateCommunityCache 社区变更后删除列表缓存
+func invalidateCommunityCache() {
+	if err := redis.DeleteCommunityListCache(); err != nil {
+		zap.L().Error("redis.DeleteCommunityListCache failed", zap.Error(err))
+	}
+}
+
+// GetAllCommunities 查询包括已归档在内的全部社区(管理后台)
+func GetAllCommunities() ([]*models.CommunityDetail, error) {
+	return mysql.GetAllCommunities()
+}
+
+// CreateCommunity 创建社区
+func CreateCommunity(p *models.CommunityForm) (*models.CommunityDetail, error) {
+	p.CommunityName = strings.TrimSpace(p.CommunityName)
+	p.Introduction = strings.TrimSpace(p.Introduction)
+	exist, err := mysql.CheckCommunityNameExist(p.CommunityName, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		return nil, mysql.ErrorCommunityExist
+	}
+	id, err := mysql.CreateCommunity(p)
+	if err != nil {
+		return nil, err
+	}
+	invalidateCommunityCache()
 	return mysql.GetCommunityByID(id)
 }
+
+// UpdateCommunity 修改社区名称及简介
+func UpdateCommunity(id uint64, p *models.CommunityForm) error {
+	if _, err := mysql.GetCommunityByID(id); err != nil {
+		return err
+	}
+	p.CommunityName = strings.TrimSpace(p.CommunityName)
+	p.Introduction = strings.TrimSpace(p.Introduction)
+	exist, err := mysql.CheckCommunityNameExist(p.CommunityName, id)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return mysql.ErrorCommunityExist
+	}
+	if err := mysql.UpdateCommunity(id, p); err != nil {
+		return err
+	}
+	invalidateCommunityCache()
+	return nil
+}
+
+// ArchiveCommunity 归档/恢复社区, 归档后社区只读且不在列表中展示
+func ArchiveCommunity(id uint64, archived bool) error {
+	if _, err := mysql.GetCommunityByID(id); err != nil {
+		return err
+	}
+	status := models.CommunityStatusNormal
+	if archived {
+		status = models.CommunityStatusArchived
+	}
+	if err := mysql.UpdateCommunityStatus(id, status); err != nil {
+		return err
+	}
+	invalidateCommunityCache()
+	return nil
+}
+
+// ReorderCommunities 调整社区的排序
+func ReorderCommunities(ids []uint64) error {
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return mysql.ErrorInvalidID
+		}
+		seen[id] = true
+		if _, err := mysql.GetCommunityByID(id); err != nil {
+			return err
+		}
+	}
+	if err := mysql.UpdateCommunityOrder(ids); err != nil {
+		return err
+	}
+	invalidateCommunityCache()
+	return nil
+}
@@ -0,0 +1,319 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/jwt"
+	"bluebell_backend/pkg/mailer"
+	"bluebell_backend/settings"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	htmltemplate "html/template"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 订阅社区的热门帖子邮件摘要
+// 用户可以设置每天或每周接收, 默认不发送; 后台任务每digestInterval检查一次到期的用户,
+// 从用户加入的社区中取出这段时间内发布的帖子, 按bluebell:post:score的分数排序, 渲染HTML及纯文本模板后发送
+// 每封邮件带有签名的退订链接, 不需要登录即可退订; 打开链接(GET)只显示确认页面, 确认或邮件客户端一键退订(POST)后才退订,
+// 避免邮件安全扫描及预加载链接时误退订
+
+var (
+	ErrorDigestDisabled     = errors.New("未开启邮件摘要")
+	ErrorInvalidUnsubscribe = errors.New("退订链接无效")
+)
+
+const (
+	digestInterval     = time.Hour // 检查到期用户的间隔
+	digestBatch        = 100       // 每批查询的用户数
+	digestScanFactor   = 5         // 每封邮件从redis多取的帖子倍数, 用于过滤屏蔽及NSFW的帖子
+	defaultDigestLimit = 10        // 未配置时每封邮件的帖子数
+)
+
+// digestPeriods 每种频率对应的时间范围
+var digestPeriods = map[int8]time.Duration{
+	models.DigestDaily:  24 * time.Hour,
+	models.DigestWeekly: 7 * 24 * time.Hour,
+}
+
+// digestPeriodNames 邮件中的时间范围名称
+var digestPeriodNames = map[int8]string{
+	models.DigestDaily:  "今日",
+	models.DigestWeekly: "本周",
+}
+
+var (
+	digestCfg       *settings.DigestConfig
+	digestHTML      *htmltemplate.Template
+	digestText      *texttemplate.Template
+	unsubscribeHTML *htmltemplate.Template
+)
+
+// InitDigest 根据配置加载邮件摘要的模板, 未开启时不发送邮件摘要
+func InitDigest(cfg *settings.DigestConfig) error {
+	if cfg == nil || !cfg.Enabled {
+		digestCfg = nil
+		return nil
+	}
+	if len(cfg.Secret) == 0 || len(cfg.BaseURL) == 0 || len(cfg.TemplateDir) == 0 {
+		return errors.New("邮件摘要缺少secret、base_url或template_dir")
+	}
+	if err := settings.CheckSecret(cfg.Secret); err != nil {
+		return err
+	}
+	// 与JWT的HS256密钥共用时, 泄露其中一个就能伪造另一个
+	if settings.Conf.AuthConfig != nil {
+		for _, kc := range settings.Conf.AuthConfig.Keys {
+			if kc.Alg == jwt.AlgHS256 && kc.Secret == cfg.Secret {
+				return errors.New("邮件摘要的secret不能与JWT密钥相同")
+			}
+		}
+	}
+	html, err := htmltemplate.ParseFiles(filepath.Join(cfg.TemplateDir, "digest.html"))
+	if err != nil {
+		return err
+	}
+	text, err := texttemplate.ParseFiles(filepath.Join(cfg.TemplateDir, "digest.txt"))
+	if err != nil {
+		return err
+	}
+	page, err := htmltemplate.ParseFiles(filepath.Join(cfg.TemplateDir, "unsubscribe.html"))
+	if err != nil {
+		return err
+	}
+	digestCfg, digestHTML, digestText, unsubscribeHTML = cfg, html, text, page
+	return nil
+}
+
+// GetDigestSetting 查询邮件摘要设置
+func GetDigestSetting(userID uint64) (*models.DigestSetting, error) {
+	return mysql.GetDigestSetting(userID)
+}
+
+// UpdateDigestSetting 修改邮件摘要的发送频率
+func UpdateDigestSetting(userID uint64, p *models.DigestSetting) error {
+	return mysql.UpdateDigestFrequency(userID, p.Frequency)
+}
+
+// unsubscribeToken 退订链接的签名
+func unsubscribeToken(userID uint64) string {
+	mac := hmac.New(sha256.New, []byte(digestCfg.Secret))
+	mac.Write([]byte("digest-unsubscribe:" + strconv.FormatUint(userID, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// unsubscribeURL 邮件中的退订链接
+func unsubscribeURL(userID uint64) string {
+	q := url.Values{}
+	q.Set("uid", strconv.FormatUint(userID, 10))
+	q.Set("token", unsubscribeToken(userID))
+	return strings.TrimRight(digestCfg.BaseURL, "/") + "/api/v1/digest/unsubscribe?" + q.Encode()
+}
+
+// CheckUnsubscribe 校验退订链接的签名
+func CheckUnsubscribe(userID uint64, token string) error {
+	if digestCfg == nil {
+		return ErrorInvalidUnsubscribe
+	}
+	if !hmac.Equal([]byte(token), []byte(unsubscribeToken(userID))) {
+		return ErrorInvalidUnsubscribe
+	}
+	return nil
+}
+
+// Unsubscribe 通过邮件中的退订链接停止发送邮件摘要
+func Unsubscribe(userID uint64, token string) error {
+	if err := CheckUnsubscribe(userID, token); err != nil {
+		return err
+	}
+	err := mysql.UpdateDigestFrequency(userID, models.DigestOff)
+	if err != nil {
+		zap.L().Error("mysql.UpdateDigestFrequency failed", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	return err
+}
+
+// SendDigests 给到期的用户发送邮件摘要, 返回发送的邮件数
+// 距上次发送不足一个周期的用户不发送, 留出半个检查间隔避免发送时间逐渐推迟
+func SendDigests(now time.Time) (int, error) {
+	if digestCfg == nil {
+		return 0, ErrorDigestDisabled
+	}
+	sent := 0
+	for _, frequency := range []int8{models.DigestDaily, models.DigestWeekly} {
+		period := digestPeriods[frequency]
+		before := now.Add(-period + digestInterval/2)
+		var afterID uint64
+		for {
+			list, err := mysql.GetDigestRecipients(frequency, before, afterID, digestBatch)
+			if err != nil {
+				return sent, err
+			}
+			for _, r := range list {
+				afterID = r.UserID
+				ok, err := sendDigest(r, now.Add(-period), now)
+				if err != nil {
+					zap.L().Error("sendDigest failed", zap.Uint64("user_id", r.UserID), zap.Error(err))
+					continue
+				}
+				if ok {
+					sent++
+				}
+			}
+			if len(list) < digestBatch {
+				break
+			}
+		}
+	}
+	return sent, nil
+}
+
+// sendDigest 给一个用户发送since之后的热门帖子, 没有帖子时不发送, 返回是否发送了邮件
+func sendDigest(r *models.DigestRecipient, since, now time.Time) (bool, error) {
+	posts, err := getDigestPosts(r.UserID, since)
+	if err != nil {
+		return false, err
+	}
+	if len(posts) > 0 {
+		d := &models.Digest{
+			NickName:       r.NickName,
+			Period:         digestPeriodNames[r.Frequency],
+			Posts:          posts,
+			UnsubscribeURL: unsubscribeURL(r.UserID),
+		}
+		msg, err := renderDigest(d)
+		if err != nil {
+			return false, err
+		}
+		msg.To = r.Email
+		if err := mailer.Send(msg); err != nil {
+			return false, err
+		}
+	}
+	// 没有帖子时同样记录, 到下一个周期再检查
+	if err := mysql.UpdateDigestSentTime(r.UserID, now); err != nil {
+		return false, err
+	}
+	return len(posts) > 0, nil
+}
+
+// getDigestPosts 查询用户加入的社区在since之后发布的热门帖子, 去掉屏蔽的及NSFW的帖子
+func getDigestPosts(userID uint64, since time.Time) ([]*models.DigestPost, error) {
+	communityIDs, err := mysql.GetUserCommunityIDs(userID)
+	if err != nil || len(communityIDs) == 0 {
+		return nil, err
+	}
+	limit := digestCfg.PostLimit
+	if limit <= 0 {
+		limit = defaultDigestLimit
+	}
+	ids, err := redis.GetDigestPostIDs(communityIDs, since, int64(limit*digestScanFactor))
+	if err != nil {
+		return nil, err
+	}
+	data, err := getPostListByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	v := &Viewer{UserID: userID}
+	f, err := newPostFilter(v, true)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimRight(digestCfg.BaseURL, "/")
+	posts := make([]*models.DigestPost, 0, limit)
+	for _, post := range f.filter(data) {
+		posts = append(posts, &models.DigestPost{
+			Title:         post.Title,
+			Summary:       TruncateByWords(post.Content, 60),
+			URL:           base + "/post/" + strconv.FormatUint(post.PostID, 10),
+			CommunityName: post.CommunityName,
+			AuthorName:    post.AuthorName,
+			VoteNum:       post.VoteNum,
+		})
+		if len(posts) == limit {
+			break
+		}
+	}
+	return posts, nil
+}
+
+// RenderUnsubscribePage 渲染退订链接打开的页面, done为false时显示确认退订的按钮, 为true时显示已退订
+func RenderUnsubscribePage(done bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := unsubscribeHTML.Execute(&buf, struct{ Done bool }{done}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderDigest 渲染邮件摘要, 带上一键退订的邮件头
+func renderDigest(d *models.Digest) (*mailer.Message, error) {
+	var html, text bytes.Buffer
+	if err := digestHTML.Execute(&html, d); err != nil {
+		return nil, err
+	}
+	if err := digestText.Execute(&text, d); err != nil {
+		return nil, err
+	}
+	return &mailer.Message{
+		Subject: "你订阅的社区" + d.Period + "热门帖子",
+		HTML:    html.String(),
+		Text:    text.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + d.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
+
+// StartDigestScheduler 启动定期发送邮件摘要的后台任务, 未开启邮件摘要或未配置邮件服务器时不启动
+func StartDigestScheduler() {
+	if digestCfg == nil {
+		return
+	}
+	if !mailer.Enabled() {
+		zap.L().Warn("未配置邮件服务器, 不发送邮件摘要")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(digestInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			runDigests(now)
+		}
+	}()
+}
+
+// runDigests 多个实例时只有拿到锁的实例发送
+func runDigests(now time.Time) {
+	token, ok, err := redis.AcquireDigestLock(digestInterval)
+	if err != nil || !ok {
+		if err != nil {
+			zap.L().Error("redis.AcquireDigestLock failed", zap.Error(err))
+		}
+		return
+	}
+	defer func() {
+		if err := redis.ReleaseDigestLock(token); err != nil {
+			zap.L().Error("redis.ReleaseDigestLock failed", zap.Error(err))
+		}
+	}()
+	n, err := SendDigests(now)
+	if err != nil {
+		zap.L().Error("SendDigests failed", zap.Int("sent", n), zap.Error(err))
+		return
+	}
+	zap.L().Info("SendDigests done", zap.Int("sent", n))
+}
@@ -0,0 +1,96 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"encoding/json"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// 实时事件: 新通知推送给接收的用户, 新评论及投票数推送给正在浏览帖子的客户端
+// 推送失败只记录日志, 客户端重新连接后通过普通接口获取最新数据
+
+// SubscribeEvents 订阅当前用户的实时事件, postID不为0时同时订阅正在浏览的帖子
+func SubscribeEvents(v *Viewer, postID uint64) (*redis.EventSubscription, error) {
+	if postID > 0 {
+		// 无权浏览的私有社区的帖子按不存在处理
+		if _, _, err := readablePost(v, int64(postID)); err != nil {
+			return nil, err
+		}
+	}
+	return redis.SubscribeEvents(v.UserID, postID)
+}
+
+// EventFilter 推送前按当前用户的屏蔽名单过滤事件, 与评论列表一样不展示屏蔽的用户的评论
+type EventFilter struct {
+	blocked map[uint64]bool
+}
+
+// NewEventFilter 建立连接时查询一次当前用户屏蔽的用户
+func NewEventFilter(v *Viewer) (*EventFilter, error) {
+	blocked, err := mysql.GetBlockedIDs(v.UserID)
+	if err != nil {
+		return nil, err
+	}
+	f := &EventFilter{blocked: make(map[uint64]bool, len(blocked))}
+	for _, id := range blocked {
+		f.blocked[id] = true
+	}
+	return f, nil
+}
+
+// Keep 事件是否推送给当前用户
+func (f *EventFilter) Keep(e *models.Event) bool {
+	if e.Event != models.EventComment || len(f.blocked) == 0 {
+		return true
+	}
+	var comment struct {
+		AuthorID uint64 `json:"author_id"`
+	}
+	if err := json.Unmarshal(e.Data, &comment); err != nil {
+		return true
+	}
+	return !f.blocked[comment.AuthorID]
+}
+
+// pushNotification 推送新通知及最新的未读数
+func pushNotification(e *models.NotificationEvent) {
+	unread, err := mysql.CountUnreadNotifications(e.UserID)
+	if err != nil {
+		zap.L().Warn("mysql.CountUnreadNotifications failed", zap.Uint64("user_id", e.UserID), zap.Error(err))
+		return
+	}
+	push := &models.NotificationPush{
+		Type:        e.Type,
+		PostID:      e.PostID,
+		CommentID:   e.CommentID,
+		ActorID:     e.ActorID,
+		UnreadCount: unread,
+	}
+	if err := redis.PublishUserEvent(e.UserID, models.EventNotification, push); err != nil {
+		zap.L().Warn("redis.PublishUserEvent failed", zap.Uint64("user_id", e.UserID), zap.Error(err))
+	}
+}
+
+// pushComment 推送帖子的新评论
+func pushComment(comment *models.Comment) {
+	if err := redis.PublishPostEvent(comment.PostID, models.EventComment, comment); err != nil {
+		zap.L().Warn("redis.PublishPostEvent failed", zap.Uint64("post_id", comment.PostID), zap.Error(err))
+	}
+}
+
+// pushVote 推送帖子最新的投票数
+func pushVote(postID uint64) {
+	votes, err := redis.GetPostVoteData([]string{strconv.FormatUint(postID, 10)})
+	if err != nil || len(votes) == 0 {
+		zap.L().Warn("redis.GetPostVoteData failed", zap.Uint64("post_id", postID), zap.Error(err))
+		return
+	}
+	e := &models.VoteEvent{PostID: postID, VoteNum: votes[0]}
+	if err := redis.PublishPostEvent(postID, models.EventVote, e); err != nil {
+		zap.L().Warn("redis.PublishPostEvent failed", zap.Uint64("post_id", postID), zap.Error(err))
+	}
+}
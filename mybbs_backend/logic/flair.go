@@ -0,0 +1,236 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/rbac"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 社区的flair及帖子的NSFW、剧透标记
+// 作者或版主可以修改帖子的flair及标记, 只有版主可以使用的flair作者不能添加也不能去掉
+// 列表按用户的浏览偏好隐藏NSFW帖子, 需要模糊显示的帖子设置blur由客户端处理
+
+var (
+	ErrorFlairNotExist  = errors.New("flair不存在")
+	ErrorFlairModOnly   = errors.New("只有版主可以使用该flair")
+	ErrorNotPostAuthor  = errors.New("只有作者或版主可以修改帖子")
+	ErrorFlairNameEmpty = errors.New("flair名称不能为空")
+)
+
+// defaultPreferences 未登录用户的浏览偏好
+var defaultPreferences = models.Preferences{HideNSFW: true, BlurSpoiler: true}
+
+// canModeratePost 是否为帖子所在社区的版主或管理员
+func canModeratePost(v *Viewer, communityID uint64) bool {
+	return v != nil && rbac.Can(v.Roles, rbac.PermModeratePost, communityID)
+}
+
+// getCommunityFlair 查询社区的flair, 不属于该社区的flair视为不存在
+func getCommunityFlair(communityID, flairID uint64) (*models.Flair, error) {
+	flair, err := mysql.GetFlairByID(flairID)
+	if errors.Is(err, mysql.ErrorInvalidID) {
+		return nil, ErrorFlairNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if flair.CommunityID != communityID {
+		return nil, ErrorFlairNotExist
+	}
+	return flair, nil
+}
+
+// checkFlair 检查用户能否给社区内的帖子使用flair, 0表示没有flair
+func checkFlair(v *Viewer, communityID, flairID uint64) error {
+	if flairID == 0 {
+		return nil
+	}
+	flair, err := getCommunityFlair(communityID, flairID)
+	if err != nil {
+		return err
+	}
+	if flair.ModOnly && !canModeratePost(v, communityID) {
+		return ErrorFlairModOnly
+	}
+	return nil
+}
+
+// GetFlairList 查询社区的flair, 无权浏览的私有社区按不存在处理
+func GetFlairList(v *Viewer, communityID uint64) ([]*models.Flair, error) {
+	if _, err := readableCommunity(v, communityID); err != nil {
+		return nil, err
+	}
+	return mysql.GetFlairList(communityID)
+}
+
+// CreateFlair 创建flair
+func CreateFlair(communityID uint64, p *models.FlairForm) (*models.Flair, error) {
+	if _, err := mysql.GetCommunityByID(communityID); err != nil {
+		return nil, err
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if len(p.Name) == 0 {
+		return nil, ErrorFlairNameEmpty
+	}
+	id, err := mysql.CreateFlair(communityID, p)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Flair{
+		FlairID:     id,
+		CommunityID: communityID,
+		Name:        p.Name,
+		Color:       p.Color,
+		ModOnly:     p.ModOnly,
+	}, nil
+}
+
+// UpdateFlair 修改flair
+func UpdateFlair(communityID, flairID uint64, p *models.FlairForm) error {
+	if _, err := getCommunityFlair(communityID, flairID); err != nil {
+		return err
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if len(p.Name) == 0 {
+		return ErrorFlairNameEmpty
+	}
+	return mysql.UpdateFlair(flairID, p)
+}
+
+// DeleteFlair 删除flair, 使用该flair的帖子改为没有flair
+func DeleteFlair(communityID, flairID uint64) error {
+	if _, err := getCommunityFlair(communityID, flairID); err != nil {
+		return err
+	}
+	if err := mysql.DeleteFlair(flairID); err != nil {
+		return err
+	}
+	if err := redis.DeleteFlair(flairID); err != nil {
+		zap.L().Error("redis.DeleteFlair failed", zap.Uint64("flair_id", flairID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// editablePost 查询当前用户可以修改的帖子, 只有作者或版主可以修改
+func editablePost(v *Viewer, postID uint64) (*models.Post, error) {
+	post, _, err := readablePost(v, int64(postID))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || (post.AuthorId != v.UserID && !canModeratePost(v, post.CommunityID)) {
+		return nil, ErrorNotPostAuthor
+	}
+	return post, nil
+}
+
+// SetPostFlair 修改帖子的flair
+func SetPostFlair(v *Viewer, postID, flairID uint64) error {
+	post, err := editablePost(v, postID)
+	if err != nil {
+		return err
+	}
+	if post.FlairID == flairID {
+		return nil
+	}
+	// 版主添加的flair作者不能去掉或替换
+	if post.FlairID > 0 && !canModeratePost(v, post.CommunityID) {
+		old, err := mysql.GetFlairByID(post.FlairID)
+		if err != nil && !errors.Is(err, mysql.ErrorInvalidID) {
+			return err
+		}
+		if old != nil && old.ModOnly {
+			return ErrorFlairModOnly
+		}
+	}
+	if err := checkFlair(v, post.CommunityID, flairID); err != nil {
+		return err
+	}
+	if err := mysql.UpdatePostFlair(postID, flairID); err != nil {
+		return err
+	}
+	if err := redis.SetPostFlair(postID, post.FlairID, flairID); err != nil {
+		zap.L().Error("redis.SetPostFlair failed", zap.Uint64("post_id", postID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetPostFlags 修改帖子的NSFW及剧透标记, 没有传的标记保持不变
+func SetPostFlags(v *Viewer, postID uint64, p *models.PostFlagsForm) error {
+	post, err := editablePost(v, postID)
+	if err != nil {
+		return err
+	}
+	nsfw, spoiler := post.NSFW, post.Spoiler
+	if p.NSFW != nil {
+		nsfw = *p.NSFW
+	}
+	if p.Spoiler != nil {
+		spoiler = *p.Spoiler
+	}
+	return mysql.UpdatePostFlags(postID, nsfw, spoiler)
+}
+
+// GetPreferences 查询用户的浏览偏好, 未登录时使用默认设置
+func GetPreferences(v *Viewer) (*models.Preferences, error) {
+	if v == nil {
+		prefs := defaultPreferences
+		return &prefs, nil
+	}
+	return mysql.GetUserPreferences(v.UserID)
+}
+
+// UpdatePreferences 修改用户的浏览偏好
+func UpdatePreferences(userID uint64, p *models.Preferences) error {
+	return mysql.UpdateUserPreferences(userID, p)
+}
+
+// fillFlairs 填充帖子的flair, 同一页内相同的flair只查询一次
+func fillFlairs(data []*models.ApiPostDetail) {
+	flairs := make(map[uint64]*models.Flair)
+	for _, post := range data {
+		if post.Post == nil || post.FlairID == 0 {
+			continue
+		}
+		flair, ok := flairs[post.FlairID]
+		if !ok {
+			var err error
+			if flair, err = mysql.GetFlairByID(post.FlairID); err != nil && !errors.Is(err, mysql.ErrorInvalidID) {
+				zap.L().Warn("mysql.GetFlairByID failed", zap.Uint64("flair_id", post.FlairID), zap.Error(err))
+			}
+			flairs[post.FlairID] = flair
+		}
+		post.Flair = flair
+	}
+}
+
+// blurPost 按浏览偏好设置帖子是否需要模糊显示
+func blurPost(prefs *models.Preferences, post *models.ApiPostDetail) {
+	post.Blur = (post.NSFW && prefs.HideNSFW) || (post.Spoiler && prefs.BlurSpoiler)
+}
+
+// applyPreferences 按用户的浏览偏好设置需要模糊显示的帖子, 并填充当前用户的收藏状态及未读标记
+// 隐藏的NSFW帖子在分页查询时已经去掉(postFilter或查询条件), 不会让一页的数量变少
+func applyPreferences(v *Viewer, data []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	prefs, err := GetPreferences(v)
+	if err != nil {
+		return nil, err
+	}
+	res := data[:0]
+	for _, post := range data {
+		if post.Post == nil {
+			continue
+		}
+		blurPost(prefs, post)
+		res = append(res, post)
+	}
+	fillSaved(v, res)
+	fillUnread(v, res)
+	return res, nil
+}
@@ -0,0 +1,137 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// 关注用户及关注的人的帖子feed
+// 关注关系保存在mysql, feed由redis中每个作者的帖子zset在查询时合并得到
+
+var ErrorFollowSelf = errors.New("不能关注自己")
+
+// Follow 关注用户
+func Follow(userID, followeeID uint64) error {
+	if userID == followeeID {
+		return ErrorFollowSelf
+	}
+	if _, err := mysql.GetUserBrief(followeeID); err != nil {
+		return err
+	}
+	blocked, err := isBlockedEither(userID, followeeID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrorUserBlocked
+	}
+	if err := mysql.Follow(userID, followeeID); err != nil {
+		return err
+	}
+	if err := redis.DeleteFollowingFeedCache(userID); err != nil {
+		zap.L().Warn("redis.DeleteFollowingFeedCache failed", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	return nil
+}
+
+// Unfollow 取消关注
+func Unfollow(userID, followeeID uint64) error {
+	if err := mysql.Unfollow(userID, followeeID); err != nil {
+		return err
+	}
+	if err := redis.DeleteFollowingFeedCache(userID); err != nil {
+		zap.L().Warn("redis.DeleteFollowingFeedCache failed", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	return nil
+}
+
+// fillFollowInfo 填充资料中的粉丝数、关注数及当前用户是否已关注
+func fillFollowInfo(v *Viewer, profile *models.UserProfile) (err error) {
+	if profile.FollowerCount, profile.FollowingCount, err = mysql.CountFollows(profile.UserID); err != nil {
+		return
+	}
+	if v != nil && v.UserID != profile.UserID {
+		profile.IsFollowing, err = mysql.IsFollowing(v.UserID, profile.UserID)
+	}
+	return
+}
+
+// getFollowList 查询粉丝或关注的人, 补全头像地址
+func getFollowList(userID uint64, page, size int64, query func(uint64, int64, int64) ([]*models.UserBrief, error)) ([]*models.UserBrief, error) {
+	if _, err := mysql.GetUserBrief(userID); err != nil {
+		return nil, err
+	}
+	list, err := query(userID, page, size)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range list {
+		user.Avatar = avatarURL(user.UserID, user.Avatar)
+	}
+	return list, nil
+}
+
+// GetFollowerList 分页查询用户的粉丝
+func GetFollowerList(userID uint64, page, size int64) ([]*models.UserBrief, error) {
+	return getFollowList(userID, page, size, mysql.GetFollowerList)
+}
+
+// GetFollowingList 分页查询用户关注的人
+func GetFollowingList(userID uint64, page, size int64) ([]*models.UserBrief, error) {
+	return getFollowList(userID, page, size, mysql.GetFollowingList)
+}
+
+// rebuildUserPosts 从mysql重建关注的人中不存在的帖子zset(如redis数据丢失或在此之前发布的帖子)
+func rebuildUserPosts(userIDs []uint64) error {
+	missing, err := redis.GetMissingUserPosts(userIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range missing {
+		posts, err := mysql.GetRecentPostsByAuthor(id, redis.UserPostZSetMax)
+		if err != nil {
+			return err
+		}
+		if err = redis.SetUserPosts(id, posts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFollowingFeed 关注的人发布的帖子, 按时间或分数排序分页查询
+func GetFollowingFeed(v *Viewer, p *models.ParamPostList) ([]*models.ApiPostDetail, error) {
+	following, err := mysql.GetFollowingIDs(v.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(following) == 0 {
+		return make([]*models.ApiPostDetail, 0), nil
+	}
+	// 合并结果仍在缓存中时不需要检查各作者的帖子zset
+	cached, err := redis.HasFollowingFeedCache(v.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !cached {
+		if err = rebuildUserPosts(following); err != nil {
+			return nil, err
+		}
+	}
+	// 关注的人在私有社区发布的帖子只有能浏览该社区的用户可见
+	f, err := newPostFilter(v, true)
+	if err != nil {
+		return nil, err
+	}
+	data, err := listPosts(p.Page, p.Size, func(page, size int64) ([]string, error) {
+		return redis.GetFollowingPostIDsInOrder(v.UserID, following, pageOf(p, page, size))
+	}, f)
+	if err != nil {
+		return nil, err
+	}
+	return applyPreferences(v, data)
+}
@@ -0,0 +1,151 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"errors"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// 加入社区及首页feed
+
+var ErrorMemberNotPending = errors.New("没有待审核的加入申请")
+
+// JoinCommunity 加入社区, 已归档的社区不能加入
+// 受限及私有社区需要版主审核, 返回加入后的成员状态
+func JoinCommunity(v *Viewer, communityID uint64) (int8, error) {
+	community, err := mysql.GetCommunityByID(communityID)
+	if err != nil {
+		return 0, err
+	}
+	if community.Status == models.CommunityStatusArchived {
+		return 0, ErrorCommunityArchived
+	}
+	status := models.MemberApproved
+	if community.Visibility != models.CommunityPublic && !v.moderates(communityID) {
+		status = models.MemberPending
+	}
+	joined, err := mysql.InsertCommunityMember(v.UserID, communityID, status)
+	if err != nil {
+		return 0, err
+	}
+	if joined {
+		invalidateUserFeed(v.UserID)
+		return status, nil
+	}
+	// 重复加入时返回原来的状态
+	status, _, err = mysql.GetMemberStatus(v.UserID, communityID)
+	return status, err
+}
+
+// LeaveCommunity 退出社区或撤回加入申请
+func LeaveCommunity(v *Viewer, communityID uint64) error {
+	return RemoveMember(communityID, v.UserID)
+}
+
+// ApproveMember 版主通过加入申请
+func ApproveMember(communityID, userID uint64) error {
+	ok, err := mysql.ApproveCommunityMember(userID, communityID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorMemberNotPending
+	}
+	invalidateUserFeed(userID)
+	return nil
+}
+
+// RemoveMember 移除成员或拒绝加入申请
+func RemoveMember(communityID, userID uint64) error {
+	if _, err := mysql.GetCommunityByID(communityID); err != nil {
+		return err
+	}
+	left, err := mysql.DeleteCommunityMember(userID, communityID)
+	if err != nil {
+		return err
+	}
+	if left {
+		invalidateUserFeed(userID)
+	}
+	return nil
+}
+
+// GetPendingMemberList 查询社区待审核的加入申请
+func GetPendingMemberList(communityID uint64) ([]*models.UserBrief, error) {
+	ids, err := mysql.GetPendingMemberIDs(communityID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*models.UserBrief, 0, len(ids))
+	for _, id := range ids {
+		user, err := GetUserBrief(id)
+		if err != nil {
+			zap.L().Warn("GetUserBrief failed", zap.Uint64("user_id", id), zap.Error(err))
+			continue
+		}
+		list = append(list, user)
+	}
+	return list, nil
+}
+
+// invalidateUserFeed 删除用户加入的社区及feed缓存
+func invalidateUserFeed(userID uint64) {
+	if err := redis.DeleteUserFeedCache(userID); err != nil {
+		zap.L().Error("redis.DeleteUserFeedCache failed", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+}
+
+// GetUserCommunityList 查询用户加入的社区
+func GetUserCommunityList(userID uint64) ([]*models.Community, error) {
+	return mysql.GetUserCommunityList(userID)
+}
+
+// getUserCommunityIDs 查询用户加入的社区id, 优先读取redis缓存
+func getUserCommunityIDs(userID uint64) ([]string, error) {
+	ids, err := redis.GetUserCommunityIDs(userID)
+	if err != nil {
+		zap.L().Warn("redis.GetUserCommunityIDs failed", zap.Error(err))
+	}
+	if len(ids) > 0 {
+		return ids, nil
+	}
+	list, err := mysql.GetUserCommunityIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := redis.SetUserCommunityIDs(userID, list); err != nil {
+		zap.L().Warn("redis.SetUserCommunityIDs failed", zap.Error(err))
+	}
+	ids = make([]string, 0, len(list))
+	for _, id := range list {
+		ids = append(ids, strconv.FormatUint(id, 10))
+	}
+	return ids, nil
+}
+
+// GetFeed 首页feed: 只包含用户加入的社区的帖子, 按时间或分数排序
+func GetFeed(v *Viewer, p *models.ParamPostList) ([]*models.ApiPostDetail, error) {
+	userID := v.UserID
+	communityIDs, err := getUserCommunityIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(communityIDs) == 0 {
+		return make([]*models.ApiPostDetail, 0), nil
+	}
+	f, err := newPostFilter(v, true)
+	if err != nil {
+		return nil, err
+	}
+	data, err := listPosts(p.Page, p.Size, func(page, size int64) ([]string, error) {
+		return redis.GetFeedPostIDsInOrder(userID, communityIDs, pageOf(p, page, size))
+	}, f)
+	if err != nil {
+		return nil, err
+	}
+	return applyPreferences(v, data)
+}
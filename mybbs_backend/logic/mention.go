@@ -0,0 +1,128 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/mention"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 帖子及评论中的@用户名
+// 每条内容最多处理maxMentions个用户, 屏蔽了作者的用户不会被提到, 防止借此骚扰
+// 无权浏览私有社区的用户仍然渲染成链接, 但不发送通知, 避免泄露帖子内容
+
+const maxMentions = 10
+
+// mentionLink 用户主页地址
+func mentionLink(userID uint64) string {
+	return fmt.Sprintf("/api/v1/user/%d", userID)
+}
+
+// handleMentions 保存内容中提到的用户并发送通知, 失败只记录日志
+func handleMentions(authorID uint64, post *models.Post, commentID uint64, content string) {
+	names := mention.Parse(content, maxMentions)
+	if len(names) == 0 {
+		return
+	}
+	users, err := mysql.GetUsersByNames(names)
+	if err != nil {
+		zap.L().Error("mysql.GetUsersByNames failed", zap.Error(err))
+		return
+	}
+	ids := make([]uint64, 0, len(users))
+	for _, u := range users {
+		if u.UserID == authorID {
+			continue
+		}
+		blocked, err := mysql.IsBlocked(u.UserID, authorID)
+		if err != nil {
+			zap.L().Error("mysql.IsBlocked failed", zap.Uint64("user_id", u.UserID), zap.Error(err))
+			continue
+		}
+		if !blocked {
+			ids = append(ids, u.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := mysql.InsertMentions(authorID, post.PostID, commentID, ids); err != nil {
+		zap.L().Error("mysql.InsertMentions failed", zap.Uint64("post_id", post.PostID), zap.Error(err))
+		return
+	}
+	community, err := mysql.GetCommunityByID(post.CommunityID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityByID failed", zap.Uint64("community_id", post.CommunityID), zap.Error(err))
+		return
+	}
+	for _, id := range ids {
+		if ok, err := userCanRead(id, community); err != nil || !ok {
+			continue
+		}
+		notifyMention(id, authorID, post.PostID, commentID)
+	}
+}
+
+// userCanRead 被提到的用户能否浏览社区的帖子
+func userCanRead(userID uint64, community *models.CommunityDetail) (bool, error) {
+	if community.Visibility != models.CommunityPrivate {
+		return true, nil
+	}
+	roles, err := mysql.GetUserRoles(userID)
+	if err != nil {
+		return false, err
+	}
+	return canRead(&Viewer{UserID: userID, Roles: roles}, community)
+}
+
+// renderMentions 把内容中提到的用户渲染成链接
+func renderMentions(content string, mentions []*models.Mention) string {
+	links := make(map[string]string, len(mentions))
+	for _, m := range mentions {
+		m.Link = mentionLink(m.UserID)
+		links[strings.ToLower(m.UserName)] = m.Link
+	}
+	return mention.Render(content, func(name string) string {
+		return links[strings.ToLower(name)]
+	})
+}
+
+// fillPostMentions 填充帖子正文中提到的用户
+func fillPostMentions(data *models.ApiPostDetail) {
+	mentions, err := mysql.GetPostMentions(data.PostID)
+	if err != nil {
+		zap.L().Warn("mysql.GetPostMentions failed", zap.Uint64("post_id", data.PostID), zap.Error(err))
+		return
+	}
+	if len(mentions) == 0 {
+		return
+	}
+	data.Mentions = mentions
+	data.RenderedContent = renderMentions(data.Content, mentions)
+}
+
+// fillCommentMentions 填充评论中提到的用户
+func fillCommentMentions(comments []*models.Comment) {
+	ids := make([]uint64, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.CommentID)
+	}
+	mentions, err := mysql.GetCommentMentions(ids)
+	if err != nil {
+		zap.L().Warn("mysql.GetCommentMentions failed", zap.Error(err))
+		return
+	}
+	byComment := make(map[uint64][]*models.Mention)
+	for _, m := range mentions {
+		byComment[m.CommentID] = append(byComment[m.CommentID], m)
+	}
+	for _, c := range comments {
+		if list, ok := byComment[c.CommentID]; ok {
+			c.Mentions = list
+			c.RenderedContent = renderMentions(c.Content, list)
+		}
+	}
+}
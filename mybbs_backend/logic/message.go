@@ -0,0 +1,299 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/snowflake"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 私信: 一对一会话在第一次发消息时自动创建, 也可以创建不超过10人的小群聊
+// 成员读到的最后一条消息即已读回执, 未读数为之后别人发送的消息数
+// 屏蔽对方或被对方屏蔽时不能发送一对一私信, 也不能把屏蔽了自己的用户拉进群聊
+
+const maxMessagePageSize = 50
+
+var (
+	ErrorConversationNotExist = errors.New("会话不存在")
+	ErrorMessageBlocked       = errors.New("对方已屏蔽你或你已屏蔽对方")
+	ErrorMessageSelf          = errors.New("不能给自己发私信")
+	ErrorMessageNotExist      = errors.New("消息不存在")
+	ErrorMessageEmpty         = errors.New("消息内容不能为空")
+	ErrorNoRecipient          = errors.New("需要指定会话或接收者")
+	ErrorGroupSize            = errors.New("群聊的成员数超出限制")
+)
+
+// directKey 一对一会话的唯一标识, 小的用户id在前
+func directKey(a, b uint64) string {
+	if a > b {
+		a, b = b, a
+	}
+	return fmt.Sprintf("%d:%d", a, b)
+}
+
+// getDirectConversation 查询或创建两个用户之间的一对一会话
+func getDirectConversation(userID, recipientID uint64) (uint64, error) {
+	key := directKey(userID, recipientID)
+	id, err := mysql.GetDirectConversationID(key)
+	if err != nil || id > 0 {
+		return id, err
+	}
+	if _, err = GetUserBrief(recipientID); err != nil {
+		return 0, err
+	}
+	cid, err := snowflake.GetID()
+	if err != nil {
+		return 0, err
+	}
+	c := &models.Conversation{ConversationID: cid}
+	err = mysql.CreateConversation(c, userID, key, []uint64{userID, recipientID})
+	if errors.Is(err, mysql.ErrorConversationExist) {
+		// 对方同时发起了会话, 使用已创建的会话
+		return mysql.GetDirectConversationID(key)
+	}
+	return cid, err
+}
+
+// getMemberConversation 查询用户所在的会话, 不是成员时按不存在处理
+func getMemberConversation(conversationID, userID uint64) (*models.Conversation, error) {
+	c, err := mysql.GetConversation(conversationID)
+	if errors.Is(err, mysql.ErrorInvalidID) {
+		return nil, ErrorConversationNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := mysql.IsConversationMember(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrorConversationNotExist
+	}
+	return c, nil
+}
+
+// SendMessage 发送私信, 未指定会话时发给recipient_id
+func SendMessage(userID uint64, p *models.MessageForm) (*models.Message, error) {
+	p.Content = strings.TrimSpace(p.Content)
+	if len(p.Content) == 0 {
+		return nil, ErrorMessageEmpty
+	}
+	cid := p.ConversationID
+	if cid == 0 {
+		if p.RecipientID == 0 {
+			return nil, ErrorNoRecipient
+		}
+		if p.RecipientID == userID {
+			return nil, ErrorMessageSelf
+		}
+		blocked, err := isBlockedEither(userID, p.RecipientID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, ErrorMessageBlocked
+		}
+		if cid, err = getDirectConversation(userID, p.RecipientID); err != nil {
+			return nil, err
+		}
+	}
+	c, err := getMemberConversation(cid, userID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := mysql.GetConversationMemberIDs(cid)
+	if err != nil {
+		return nil, err
+	}
+	if !c.IsGroup && p.ConversationID > 0 {
+		// 通过会话id发送时同样检查一对一会话的屏蔽关系
+		for _, uid := range members {
+			if uid == userID {
+				continue
+			}
+			blocked, err := isBlockedEither(userID, uid)
+			if err != nil {
+				return nil, err
+			}
+			if blocked {
+				return nil, ErrorMessageBlocked
+			}
+		}
+	}
+	mid, err := snowflake.GetID()
+	if err != nil {
+		return nil, err
+	}
+	m := &models.Message{
+		MessageID:      mid,
+		ConversationID: cid,
+		SenderID:       userID,
+		Content:        p.Content,
+	}
+	if err = mysql.InsertMessage(m); err != nil {
+		return nil, err
+	}
+	m.CreateTime = time.Now()
+	pushMessage(m, members)
+	return m, nil
+}
+
+// CreateGroupConversation 创建群聊, 屏蔽了创建者的用户不能被拉进群
+func CreateGroupConversation(userID uint64, p *models.ConversationForm) (*models.Conversation, error) {
+	members := []uint64{userID}
+	seen := map[uint64]bool{userID: true}
+	for _, s := range p.UserIDs {
+		uid, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, ErrorNoRecipient
+		}
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		if _, err = GetUserBrief(uid); err != nil {
+			return nil, err
+		}
+		blocked, err := isBlockedEither(userID, uid)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, ErrorMessageBlocked
+		}
+		members = append(members, uid)
+	}
+	if len(members) < 2 || len(members) > models.MaxGroupMembers {
+		return nil, ErrorGroupSize
+	}
+	cid, err := snowflake.GetID()
+	if err != nil {
+		return nil, err
+	}
+	c := &models.Conversation{
+		ConversationID: cid,
+		IsGroup:        true,
+		Title:          strings.TrimSpace(p.Title),
+		UpdateTime:     time.Now(),
+	}
+	if err = mysql.CreateConversation(c, userID, "", members); err != nil {
+		return nil, err
+	}
+	c.Members = getMemberBriefs(members)
+	return c, nil
+}
+
+// getMemberBriefs 查询会话成员的昵称及头像, 查询失败的成员跳过
+func getMemberBriefs(ids []uint64) []*models.UserBrief {
+	briefs := make([]*models.UserBrief, 0, len(ids))
+	for _, uid := range ids {
+		user, err := GetUserBrief(uid)
+		if err != nil {
+			zap.L().Warn("GetUserBrief failed", zap.Uint64("user_id", uid), zap.Error(err))
+			continue
+		}
+		briefs = append(briefs, user)
+	}
+	return briefs
+}
+
+// GetConversationList 分页查询我的会话, 包括最后一条消息、成员及未读数
+func GetConversationList(userID uint64, page, size int64) ([]*models.Conversation, error) {
+	list, err := mysql.GetConversationList(userID, page, size)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(list))
+	for _, c := range list {
+		if c.LastMessageID > 0 {
+			ids = append(ids, c.LastMessageID)
+		}
+	}
+	messages, err := mysql.GetMessagesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]*models.Message, len(messages))
+	for _, m := range messages {
+		byID[m.MessageID] = m
+	}
+	for _, c := range list {
+		c.LastMessage = byID[c.LastMessageID]
+		members, err := mysql.GetConversationMemberIDs(c.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		c.Members = getMemberBriefs(members)
+	}
+	return list, nil
+}
+
+// GetMessageHistory 分页查询会话的历史消息及成员的已读回执, before为上一页最早的消息id
+func GetMessageHistory(userID, conversationID, before uint64, size int64) (*models.MessageHistory, error) {
+	if _, err := getMemberConversation(conversationID, userID); err != nil {
+		return nil, err
+	}
+	if size < 1 || size > maxMessagePageSize {
+		size = maxMessagePageSize
+	}
+	messages, err := mysql.GetMessageList(conversationID, before, size)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := mysql.GetReadReceipts(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MessageHistory{Messages: messages, ReadReceipts: receipts}, nil
+}
+
+// MarkConversationRead 标记会话已读到指定的消息, 并通知其他成员更新已读回执
+func MarkConversationRead(userID, conversationID, messageID uint64) error {
+	if _, err := getMemberConversation(conversationID, userID); err != nil {
+		return err
+	}
+	if _, err := mysql.GetMessageByID(conversationID, messageID); err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			return ErrorMessageNotExist
+		}
+		return err
+	}
+	if err := mysql.UpdateLastRead(conversationID, userID, messageID); err != nil {
+		return err
+	}
+	members, err := mysql.GetConversationMemberIDs(conversationID)
+	if err != nil {
+		zap.L().Warn("mysql.GetConversationMemberIDs failed", zap.Uint64("conversation_id", conversationID), zap.Error(err))
+		return nil
+	}
+	e := &models.ReadEvent{ConversationID: conversationID, UserID: userID, LastReadMessageID: messageID}
+	for _, uid := range members {
+		if uid == userID {
+			continue
+		}
+		if err := redis.PublishUserEvent(uid, models.EventMessageRead, e); err != nil {
+			zap.L().Warn("redis.PublishUserEvent failed", zap.Uint64("user_id", uid), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// pushMessage 推送新消息给会话的其他成员
+func pushMessage(m *models.Message, members []uint64) {
+	for _, uid := range members {
+		if uid == m.SenderID {
+			continue
+		}
+		if err := redis.PublishUserEvent(uid, models.EventMessage, m); err != nil {
+			zap.L().Warn("redis.PublishUserEvent failed", zap.Uint64("user_id", uid), zap.Error(err))
+		}
+	}
+}
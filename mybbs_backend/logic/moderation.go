@@ -0,0 +1,169 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/rbac"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 社区管理: 版主可以删除帖子/评论、置顶及锁定帖子、在社区内禁言用户
+
+var (
+	ErrorPostLocked   = errors.New("帖子已锁定")
+	ErrorBanModerator = errors.New("不能禁言版主或管理员")
+	ErrorBanNotExist  = errors.New("用户没有被禁言")
+)
+
+// BanError 用户在社区内被禁言, 携带禁言原因及到期时间
+type BanError struct {
+	Ban *models.CommunityBan
+}
+
+func (e *BanError) Error() string {
+	return fmt.Sprintf("已被禁言: %s", e.Ban.Reason)
+}
+
+// checkBan 检查用户是否在社区内被禁言
+func checkBan(userID, communityID uint64) error {
+	ban, err := mysql.GetActiveBan(communityID, userID)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return &BanError{Ban: ban}
+	}
+	return nil
+}
+
+// getCommunityPost 查询社区内的帖子, 不属于该社区的帖子视为不存在, 防止版主越权管理其他社区
+func getCommunityPost(communityID, postID uint64) (*models.Post, error) {
+	post, err := mysql.GetPostByID(int64(postID))
+	if err != nil {
+		return nil, err
+	}
+	if post.CommunityID != communityID {
+		return nil, mysql.ErrorInvalidID
+	}
+	return post, nil
+}
+
+// RemovePost 删除帖子
+func RemovePost(communityID, postID uint64) error {
+	post, err := getCommunityPost(communityID, postID)
+	if err != nil {
+		return err
+	}
+	if err := mysql.UpdatePostStatus(postID, models.StatusRemoved); err != nil {
+		return err
+	}
+	if err := redis.RemovePost(postID, post.AuthorId, communityID, post.FlairID); err != nil {
+		zap.L().Error("redis.RemovePost failed", zap.Uint64("post_id", postID), zap.Error(err))
+		return err
+	}
+	triggerPostWebhooks(models.WebhookPostRemoved, post)
+	return nil
+}
+
+// PinPost 置顶/取消置顶帖子
+func PinPost(communityID, postID uint64, pinned bool) error {
+	if _, err := getCommunityPost(communityID, postID); err != nil {
+		return err
+	}
+	return mysql.UpdatePostPinned(postID, pinned)
+}
+
+// LockPost 锁定/解锁帖子
+func LockPost(communityID, postID uint64, locked bool) error {
+	if _, err := getCommunityPost(communityID, postID); err != nil {
+		return err
+	}
+	return mysql.UpdatePostLocked(postID, locked)
+}
+
+// RemoveComment 删除评论
+func RemoveComment(communityID, commentID uint64) error {
+	comment, err := mysql.GetCommentByID(commentID)
+	if err != nil {
+		return err
+	}
+	if _, err := getCommunityPost(communityID, comment.PostID); err != nil {
+		return err
+	}
+	return mysql.UpdateCommentStatus(commentID, models.StatusRemoved)
+}
+
+// BanUser 在社区内禁言用户, 版主及管理员不能被禁言
+func BanUser(communityID, moderatorID uint64, p *models.BanForm) (*models.CommunityBan, error) {
+	if _, err := mysql.GetCommunityByID(communityID); err != nil {
+		return nil, err
+	}
+	if _, err := mysql.GetUserBrief(p.UserID); err != nil {
+		return nil, err
+	}
+	roles, err := mysql.GetUserRoles(p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if rbac.Can(roles, rbac.PermBanUser, communityID) {
+		return nil, ErrorBanModerator
+	}
+	ban := &models.CommunityBan{
+		CommunityID: communityID,
+		UserID:      p.UserID,
+		ModeratorID: moderatorID,
+		Reason:      p.Reason,
+		CreateTime:  time.Now(),
+	}
+	if p.Duration > 0 {
+		expire := ban.CreateTime.Add(time.Duration(p.Duration) * time.Second)
+		ban.ExpireTime = &expire
+	}
+	if err := mysql.UpsertCommunityBan(ban); err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// UnbanUser 解除禁言
+func UnbanUser(communityID, userID uint64) error {
+	ok, err := mysql.DeleteCommunityBan(communityID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorBanNotExist
+	}
+	return nil
+}
+
+// GetBanList 查询社区内未到期的禁言
+func GetBanList(communityID uint64) ([]*models.CommunityBan, error) {
+	return mysql.GetCommunityBanList(communityID)
+}
+
+// GetModeratorList 查询社区的版主
+func GetModeratorList(v *Viewer, communityID uint64) ([]*models.UserBrief, error) {
+	if _, err := readableCommunity(v, communityID); err != nil {
+		return nil, err
+	}
+	ids, err := mysql.GetCommunityModeratorIDs(communityID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*models.UserBrief, 0, len(ids))
+	for _, id := range ids {
+		user, err := GetUserBrief(id)
+		if err != nil {
+			zap.L().Warn("GetUserBrief failed", zap.Uint64("user_id", id), zap.Error(err))
+			continue
+		}
+		list = append(list, user)
+	}
+	return list, nil
+}
@@ -0,0 +1,139 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// 站内通知: 帖子被评论、评论被回复、帖子被点赞、被@提到
+// 未读时同一帖子/评论上的同类通知聚合为一条, 如"张三等12人赞了你的帖子", 热门帖子不会刷屏
+// 通知写入失败只记录日志, 不影响评论、投票等操作本身
+
+// notificationActions 每种通知的动作描述
+var notificationActions = map[string]string{
+	models.NotifyComment: "评论了你的帖子",
+	models.NotifyReply:   "回复了你的评论",
+	models.NotifyVote:    "赞了你的帖子",
+	models.NotifyMention: "提到了你",
+}
+
+// notify 写入通知, 自己触发的事件及屏蔽的用户触发的事件不通知
+func notify(e *models.NotificationEvent) {
+	if e.UserID == 0 || e.UserID == e.ActorID {
+		return
+	}
+	if blocked, err := mysql.IsBlocked(e.UserID, e.ActorID); err != nil || blocked {
+		if err != nil {
+			zap.L().Warn("mysql.IsBlocked failed", zap.Uint64("user_id", e.UserID), zap.Error(err))
+		}
+		return
+	}
+	if err := mysql.AddNotification(e); err != nil {
+		zap.L().Error("mysql.AddNotification failed",
+			zap.String("type", e.Type),
+			zap.Uint64("user_id", e.UserID),
+			zap.Error(err))
+		return
+	}
+	pushNotification(e)
+}
+
+// notifyComment 评论帖子时通知帖子作者, 回复评论时通知被回复评论的作者
+func notifyComment(post *models.Post, comment *models.Comment) {
+	if comment.ParentID == 0 {
+		notify(&models.NotificationEvent{
+			Type:      models.NotifyComment,
+			UserID:    post.AuthorId,
+			ActorID:   comment.AuthorID,
+			PostID:    post.PostID,
+			CommentID: comment.CommentID,
+			GroupKey:  fmt.Sprintf("comment:%d", post.PostID),
+		})
+		return
+	}
+	parent, err := mysql.GetCommentByID(comment.ParentID)
+	if err != nil {
+		zap.L().Warn("mysql.GetCommentByID failed", zap.Uint64("comment_id", comment.ParentID), zap.Error(err))
+		return
+	}
+	notify(&models.NotificationEvent{
+		Type:      models.NotifyReply,
+		UserID:    parent.AuthorID,
+		ActorID:   comment.AuthorID,
+		PostID:    post.PostID,
+		CommentID: comment.CommentID,
+		GroupKey:  fmt.Sprintf("reply:%d", parent.CommentID),
+	})
+}
+
+// notifyVote 帖子被点赞时通知作者, 取消投票及反对票不通知
+func notifyVote(post *models.Post, actorID uint64) {
+	notify(&models.NotificationEvent{
+		Type:     models.NotifyVote,
+		UserID:   post.AuthorId,
+		ActorID:  actorID,
+		PostID:   post.PostID,
+		GroupKey: fmt.Sprintf("vote:%d", post.PostID),
+	})
+}
+
+// notifyMention 在帖子或评论中被@提到时通知, 不聚合
+func notifyMention(userID, actorID, postID, commentID uint64) {
+	notify(&models.NotificationEvent{
+		Type:      models.NotifyMention,
+		UserID:    userID,
+		ActorID:   actorID,
+		PostID:    postID,
+		CommentID: commentID,
+	})
+}
+
+// notificationMessage 生成通知的提示信息
+func notificationMessage(n *models.Notification) string {
+	name := "有人"
+	if n.Actor != nil {
+		name = n.Actor.NickName
+	}
+	if n.ActorCount > 1 {
+		name = fmt.Sprintf("%s等%d人", name, n.ActorCount)
+	}
+	return name + notificationActions[n.Type]
+}
+
+// GetNotificationList 分页查询用户的通知及未读数
+func GetNotificationList(userID uint64, unreadOnly bool, page, size int64) (*models.NotificationList, error) {
+	unread, err := mysql.CountUnreadNotifications(userID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := mysql.GetNotificationList(userID, unreadOnly, page, size)
+	if err != nil {
+		return nil, err
+	}
+	actors := make(map[uint64]*models.UserBrief)
+	for _, n := range list {
+		actor, ok := actors[n.ActorID]
+		if !ok {
+			if actor, err = GetUserBrief(n.ActorID); err != nil {
+				zap.L().Warn("GetUserBrief failed", zap.Uint64("user_id", n.ActorID), zap.Error(err))
+			}
+			actors[n.ActorID] = actor
+		}
+		n.Actor = actor
+		n.Message = notificationMessage(n)
+	}
+	return &models.NotificationList{UnreadCount: unread, List: list}, nil
+}
+
+// GetUnreadNotificationCount 查询用户的未读通知数
+func GetUnreadNotificationCount(userID uint64) (int64, error) {
+	return mysql.CountUnreadNotifications(userID)
+}
+
+// MarkNotificationsRead 标记通知为已读, ids为空时全部标记为已读
+func MarkNotificationsRead(userID uint64, ids []uint64) error {
+	return mysql.MarkNotificationsRead(userID, ids)
+}
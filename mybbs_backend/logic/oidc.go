@@ -0,0 +1,134 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/jwt"
+	"bluebell_backend/pkg/oidc"
+	"bluebell_backend/pkg/snowflake"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 第三方登录(OpenID Connect)
+
+// oidcStateExpire 从跳转到身份提供方到回调的最长时间
+const oidcStateExpire = 10 * time.Minute
+
+var (
+	ErrorOIDCState = errors.New("登录请求无效或已过期")
+	ErrorOIDCEmail = errors.New("第三方账号没有已验证的邮箱")
+)
+
+// OIDCLoginURL 生成跳转到身份提供方的授权地址, state同时需要由调用方写入cookie与回调时比对
+func OIDCLoginURL() (authURL, state string, err error) {
+	p, err := oidc.Default()
+	if err != nil {
+		return
+	}
+	s := new(models.OIDCState)
+	if state, err = oidc.RandomString(); err != nil {
+		return
+	}
+	if s.Nonce, err = oidc.RandomString(); err != nil {
+		return
+	}
+	if s.CodeVerifier, err = oidc.RandomString(); err != nil {
+		return
+	}
+	if authURL, err = p.AuthCodeURL(state, s.Nonce, oidc.CodeChallenge(s.CodeVerifier)); err != nil {
+		return
+	}
+	err = redis.SaveOIDCState(state, s, oidcStateExpire)
+	return
+}
+
+// OIDCCallback 处理身份提供方的回调: 换取并校验ID Token, 找到或创建对应用户后签发我们自己的token
+func OIDCCallback(state, code string, device *jwt.Device) (*models.User2, error) {
+	p, err := oidc.Default()
+	if err != nil {
+		return nil, err
+	}
+	s, err := redis.TakeOIDCState(state)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrorOIDCState
+	}
+	token, err := p.Exchange(code, s.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := p.VerifyIDToken(token.IDToken, s.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	user, err := oidcUser(p, token, idToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := issueLoginTokens(user, device); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// oidcUser 找到第三方账号对应的用户:
+// 已绑定的直接返回; 否则按已验证的邮箱关联已有账号; 都没有则创建新用户
+func oidcUser(p *oidc.Provider, token *oidc.Token, idToken *oidc.IDToken) (*models.User2, error) {
+	user, err := mysql.GetUserByIdentity(p.Issuer(), idToken.Subject)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	// ID Token中没有邮箱时再查一次userinfo
+	email, verified, name := idToken.Email, idToken.EmailVerified, idToken.Name
+	if len(email) == 0 {
+		info, err := p.UserInfo(token.AccessToken, idToken.Subject)
+		if err != nil {
+			zap.L().Warn("oidc userinfo failed", zap.Error(err))
+		} else {
+			email, verified = info.Email, info.EmailVerified
+			if len(name) == 0 {
+				name = info.Name
+			}
+		}
+	}
+	// 未验证的邮箱可能被冒用, 不能据此关联或占用账号
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) == 0 || !verified {
+		return nil, ErrorOIDCEmail
+	}
+	identity := &models.UserIdentity{Issuer: p.Issuer(), Subject: idToken.Subject, Email: email}
+
+	user, err = mysql.GetUserByEmailAddr(email)
+	if err == nil {
+		identity.UserID = user.UserID
+		if err := mysql.InsertUserIdentity(identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, mysql.ErrorUserNotExit) {
+		return nil, err
+	}
+
+	userID, err := snowflake.GetID()
+	if err != nil {
+		return nil, mysql.ErrorGenIDFailed
+	}
+	if len(name) == 0 {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+	user = &models.User2{UserID: userID, Email: email, NickName: name}
+	identity.UserID = userID
+	if err := mysql.InsertUserWithIdentity(user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
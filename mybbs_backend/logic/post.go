@@ -5,7 +5,8 @@ import (
 	"bluebell_backend/dao/redis"
 	"bluebell_backend/models"
 	"bluebell_backend/pkg/snowflake"
-	"fmt"
+	"errors"
+	"strconv"
 
 	"go.uber.org/zap"
 )
@@ -15,7 +16,7 @@ import (
  * @Description //TODO 创建帖子
  * @Date 19:53 2022/2/12
  **/
-func CreatePost(post *models.Post) (err error) {
+func CreatePost(v *Viewer, post *models.Post) (err error) {
 	// 1、 生成post_id(生成帖子ID)
 	postID, err := snowflake.GetID()
 	if err != nil {
@@ -23,14 +24,43 @@ func CreatePost(post *models.Post) (err error) {
 		return
 	}
 	post.PostID = postID
-	// 2、创建帖子 保存到数据库
-	if err := mysql.CreatePost(post); err != nil {
-		zap.L().Error("mysql.CreatePost(&post) failed", zap.Error(err))
+	// 已归档的社区不能再发帖
+	community, err := mysql.GetCommunityByID(post.CommunityID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityByID failed", zap.Error(err))
 		return err
 	}
-	community, err := mysql.GetCommunityNameByID(fmt.Sprint(post.CommunityID))
+	if community.Status == models.CommunityStatusArchived {
+		return ErrorCommunityArchived
+	}
+	// 受限及私有社区只有通过审核的成员可以发帖
+	ok, err := canPost(v, community)
 	if err != nil {
-		zap.L().Error("mysql.GetCommunityNameByID failed", zap.Error(err))
+		return err
+	}
+	if !ok {
+		return ErrorNotMember
+	}
+	// 被禁言的用户不能在该社区发帖
+	if err := checkBan(post.AuthorId, community.CommunityID); err != nil {
+		return err
+	}
+	// 社区的发帖规则
+	if err := checkPostRule(community.CommunityID, post); err != nil {
+		return err
+	}
+	// 只能使用本社区的flair, 部分flair只有版主可以使用
+	if err := checkFlair(v, community.CommunityID, post.FlairID); err != nil {
+		return err
+	}
+	// 慢速模式下限制每个用户的发帖间隔
+	if err := takeSlowMode(v, community); err != nil {
+		return err
+	}
+	// 2、创建帖子 保存到数据库
+	if err := mysql.CreatePost(post); err != nil {
+		zap.L().Error("mysql.CreatePost(&post) failed", zap.Error(err))
+		releaseSlowMode(v, community)
 		return err
 	}
 	// redis存储帖子信息
@@ -43,6 +73,14 @@ func CreatePost(post *models.Post) (err error) {
 		zap.L().Error("redis.CreatePost failed", zap.Error(err))
 		return err
 	}
+	if post.FlairID > 0 {
+		if err := redis.SetPostFlair(post.PostID, 0, post.FlairID); err != nil {
+			zap.L().Error("redis.SetPostFlair failed", zap.Error(err))
+			return err
+		}
+	}
+	handleMentions(post.AuthorId, post, 0, post.Content)
+	triggerPostWebhooks(models.WebhookPostCreated, post)
 	return
 
 }
@@ -52,10 +90,10 @@ func CreatePost(post *models.Post) (err error) {
  * @Description //TODO 根据Id查询帖子详情
  * @Date 21:39 2022/2/12
  **/
-func GetPostById(postID int64) (data *models.ApiPostDetail, err error) {
+func GetPostById(v *Viewer, postID int64) (data *models.ApiPostDetail, err error) {
 	// 查询并组合我们接口想用的数据
-	// 查询帖子信息
-	post, err := mysql.GetPostByID(postID)
+	// 查询帖子信息, 私有社区的帖子对非成员按不存在处理
+	post, community, err := readablePost(v, postID)
 	if err != nil {
 		zap.L().Error("mysql.GetPostByID(postID) failed",
 			zap.Int64("postID", postID),
@@ -70,19 +108,34 @@ func GetPostById(postID int64) (data *models.ApiPostDetail, err error) {
 			zap.Error(err))
 		return
 	}
-	// 根据社区id查询社区详细信息
-	community, err := mysql.GetCommunityByID(post.CommunityID)
+	// 作者的昵称及头像, 查询失败不影响帖子详情
+	author, err := GetUserBrief(post.AuthorId)
 	if err != nil {
-		zap.L().Error("mysql.GetCommunityByID() failed",
-			zap.Uint64("community_id", post.CommunityID),
+		zap.L().Warn("GetUserBrief() failed",
+			zap.Uint64("author_id", post.AuthorId),
 			zap.Error(err))
-		return
+		err = nil
 	}
 	// 接口数据拼接
 	data = &models.ApiPostDetail{
 		Post:            post,
 		CommunityDetail: community,
 		AuthorName:      user.UserName,
+		Author:          author,
+	}
+	fillFlairs([]*models.ApiPostDetail{data})
+	fillPostMentions(data)
+	// 详情页不隐藏NSFW帖子, 按浏览偏好模糊显示
+	prefs, err := GetPreferences(v)
+	if err != nil {
+		return nil, err
+	}
+	blurPost(prefs, data)
+	fillSaved(v, []*models.ApiPostDetail{data})
+	// 先按上次读到的位置返回未读评论数, 再记录读到了最后一条评论
+	if v != nil {
+		fillUnread(v, []*models.ApiPostDetail{data})
+		markPostRead(v.UserID, post.PostID)
 	}
 	return
 }
@@ -92,174 +145,158 @@ func GetPostById(postID int64) (data *models.ApiPostDetail, err error) {
  * @Description //TODO 获取帖子列表
  * @Date 22:56 2022/2/12
  **/
-func GetPostList(page, size int64) (data []*models.ApiPostDetail, err error) {
-	postList, err := mysql.GetPostList(page, size)
+// GetPostList 按发帖时间分页查询帖子列表, 与GetPostListNew一样按过滤条件去掉无权浏览的私有社区、屏蔽的用户、社区、关键词及NSFW帖子, 去掉的帖子由后面的帖子补上
+func GetPostList(v *Viewer, page, size int64) (data []*models.ApiPostDetail, err error) {
+	f, err := newPostFilter(v, true)
 	if err != nil {
-		fmt.Println(err)
 		return
 	}
-	data = make([]*models.ApiPostDetail, 0, len(postList)) // data 初始化
-	for _, post := range postList {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserByID(post.AuthorId)
-		if err != nil {
-			zap.L().Error("mysql.GetUserByID() failed",
-				zap.Uint64("postID", post.AuthorId),
-				zap.Error(err))
-			continue
-		}
-		// 根据社区id查询社区详细信息
-		community, err := mysql.GetCommunityByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityByID() failed",
-				zap.Uint64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		// 接口数据拼接
-		postdetail := &models.ApiPostDetail{
-			Post:            post,
-			CommunityDetail: community,
-			AuthorName:      user.UserName,
-		}
-		data = append(data, postdetail)
+	data, err = listPosts(page, size, mysql.GetPostIDList, f)
+	if err != nil {
+		zap.L().Error("GetPostList failed", zap.Error(err))
+		return
 	}
-	return
+	return applyPreferences(v, data)
 }
 
 /**
  * @Author huchao
- * @Description //TODO 升级版帖子列表接口：按创建时间排序 或者 按照 分数排序
- * @Date 22:03 2022/2/15
+ * @Description //TODO 将两个查询帖子列表逻辑合二为一的函数
+ * @Date 12:08 2022/2/17
  **/
-func GetPostList2(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 2、去redis查询id列表
-	ids, err := redis.GetPostIDsInOrder(p)
-	if err != nil {
-		return
+func GetPostListNew(v *Viewer, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	// 按flair筛选时只查询flair所在的社区
+	if p.FlairID > 0 {
+		flair, err := mysql.GetFlairByID(p.FlairID)
+		if errors.Is(err, mysql.ErrorInvalidID) || (err == nil && p.CommunityID > 0 && flair.CommunityID != p.CommunityID) {
+			return make([]*models.ApiPostDetail, 0), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.CommunityID = flair.CommunityID
 	}
-	if len(ids) == 0 {
-		zap.L().Warn("redis.GetPostIDsInOrder(p) return 0 data")
-		return
+	// 根据请求参数的不同,执行不同的业务逻辑
+	var fetch idFetcher
+	if p.CommunityID == 0 {
+		// 查所有, 无权浏览的私有社区的帖子由过滤条件去掉
+		fetch = func(page, size int64) ([]string, error) {
+			return redis.GetPostIDsInOrder(pageOf(p, page, size))
+		}
+	} else if _, err = readableCommunity(v, p.CommunityID); err != nil {
+		// 无权浏览的私有社区与不存在的社区一样返回空列表
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			return make([]*models.ApiPostDetail, 0), nil
+		}
+	} else if p.FlairID > 0 {
+		// 根据flair查询, 不展示置顶的帖子
+		fetch = func(page, size int64) ([]string, error) {
+			return redis.GetFlairPostIDsInOrder(pageOf(p, page, size))
+		}
+	} else {
+		// 根据社区id查询
+		fetch = func(page, size int64) ([]string, error) {
+			return redis.GetCommunityPostIDsInOrder(pageOf(p, page, size))
+		}
 	}
-	zap.L().Debug("GetPostList2", zap.Any("ids", ids))
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
 	if err != nil {
-		return
+		zap.L().Error("GetPostListNew failed", zap.Error(err))
+		return nil, err
 	}
-
-	// 3、根据id去数据库查询帖子详细信息
-	// 返回的数据还要按照我给定的id的顺序返回  order by FIND_IN_SET(post_id, ?)
-	posts, err := mysql.GetPostListByIDs(ids)
+	// 浏览社区帖子列表的第一页时记录浏览时间, 之后发布的帖子下次浏览时标记为new
+	if p.CommunityID > 0 && p.Page == 1 {
+		recordCommunityVisit(v, p.CommunityID)
+	}
+	// 浏览指定的社区时不按屏蔽的社区过滤
+	f, err := newPostFilter(v, p.CommunityID == 0)
 	if err != nil {
-		return
+		return nil, err
 	}
-	// 将帖子的作者及分区信息查询出来填充到帖子中
-	for idx, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserByID(post.AuthorId)
-		if err != nil {
-			zap.L().Error("mysql.GetUserByID() failed",
-				zap.Uint64("postID", post.AuthorId),
-				zap.Error(err))
-			continue
+	data, err = listPosts(p.Page, p.Size, fetch, f)
+	if err == nil && p.CommunityID > 0 && p.FlairID == 0 && p.Page == 1 {
+		if data, err = prependPinnedPosts(p.CommunityID, data); err == nil {
+			data = f.filter(data)
 		}
-		// 根据社区id查询社区详细信息
-		community, err := mysql.GetCommunityByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityByID() failed",
-				zap.Uint64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		// 接口数据拼接
-		postdetail := &models.ApiPostDetail{
-			VoteNum:         voteData[idx],
-			Post:            post,
-			CommunityDetail: community,
-			AuthorName:      user.UserName,
-		}
-		data = append(data, postdetail)
 	}
-	return
-}
-
-/**
- * @Author huchao
- * @Description //TODO  根据社区去查询帖子列表
- * @Date 22:53 2022/2/16
- **/
-func GetCommunityPostList(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 2、去redis查询id列表
-	ids, err := redis.GetCommunityPostIDsInOrder(p)
 	if err != nil {
-		return
+		zap.L().Error("GetPostListNew failed", zap.Error(err))
+		return nil, err
 	}
+	return applyPreferences(v, data)
+}
+
+// getPostListByIDs 按ids的顺序查询帖子详情, 并填充投票数、作者及社区信息
+func getPostListByIDs(ids []string) (data []*models.ApiPostDetail, err error) {
 	if len(ids) == 0 {
-		zap.L().Warn("redis.GetCommunityPostList(p) return 0 data")
-		return
+		return make([]*models.ApiPostDetail, 0), nil
 	}
-	zap.L().Debug("GetPostList2", zap.Any("ids", ids))
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
+	posts, err := mysql.GetPostListByIDs(ids)
 	if err != nil {
 		return
 	}
+	return getPostDetails(posts)
+}
 
-	// 3、根据id去数据库查询帖子详细信息
-	// 返回的数据还要按照我给定的id的顺序返回  order by FIND_IN_SET(post_id, ?)
-	posts, err := mysql.GetPostListByIDs(ids)
+// getPostDetails 填充帖子的投票数、作者及社区信息
+func getPostDetails(posts []*models.Post) (data []*models.ApiPostDetail, err error) {
+	data = make([]*models.ApiPostDetail, 0, len(posts))
+	if len(posts) == 0 {
+		return
+	}
+	// 帖子可能已从数据库删除, 只查询仍然存在的帖子的投票数
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, strconv.FormatUint(post.PostID, 10))
+	}
+	voteData, err := redis.GetPostVoteData(ids)
 	if err != nil {
 		return
 	}
-	// 将帖子的作者及分区信息查询出来填充到帖子中
-	for idx, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserByID(post.AuthorId)
+	for i, post := range posts {
+		community, err := mysql.GetCommunityByID(post.CommunityID)
 		if err != nil {
-			zap.L().Error("mysql.GetUserByID() failed",
-				zap.Uint64("postID", post.AuthorId),
+			zap.L().Error("mysql.GetCommunityByID() failed",
+				zap.Uint64("community_id", post.CommunityID),
 				zap.Error(err))
 			continue
 		}
-		// 根据社区id查询社区详细信息
-		community, err := mysql.GetCommunityByID(post.CommunityID)
+		author, err := GetUserBrief(post.AuthorId)
 		if err != nil {
-			zap.L().Error("mysql.GetCommunityByID() failed",
-				zap.Uint64("community_id", post.CommunityID),
+			zap.L().Error("GetUserBrief() failed",
+				zap.Uint64("author_id", post.AuthorId),
 				zap.Error(err))
 			continue
 		}
-		// 接口数据拼接
-		postdetail := &models.ApiPostDetail{
-			VoteNum:         voteData[idx],
+		data = append(data, &models.ApiPostDetail{
+			VoteNum:         voteData[i],
 			Post:            post,
 			CommunityDetail: community,
-			AuthorName:      user.UserName,
-		}
-		data = append(data, postdetail)
+			AuthorName:      author.NickName,
+			Author:          author,
+		})
 	}
+	fillFlairs(data)
 	return
 }
 
-/**
- * @Author huchao
- * @Description //TODO 将两个查询帖子列表逻辑合二为一的函数
- * @Date 12:08 2022/2/17
- **/
-func GetPostListNew(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 根据请求参数的不同,执行不同的业务逻辑
-	if p.CommunityID == 0 {
-		// 查所有
-		data, err = GetPostList2(p)
-	} else {
-		// 根据社区id查询
-		data, err = GetCommunityPostList(p)
+// prependPinnedPosts 社区帖子列表的第一页把置顶的帖子放在最前面
+func prependPinnedPosts(communityID uint64, data []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	ids, err := mysql.GetPinnedPostIDs(communityID)
+	if err != nil || len(ids) == 0 {
+		return data, err
 	}
+	pinned, err := getPostListByIDs(ids)
 	if err != nil {
-		zap.L().Error("GetPostListNew failed", zap.Error(err))
 		return nil, err
 	}
-	return
+	exist := make(map[uint64]bool, len(pinned))
+	for _, post := range pinned {
+		exist[post.PostID] = true
+	}
+	for _, post := range data {
+		if !exist[post.PostID] {
+			pinned = append(pinned, post)
+		}
+	}
+	return pinned, nil
 }
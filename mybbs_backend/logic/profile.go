@@ -0,0 +1,215 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/identicon"
+	"bluebell_backend/pkg/snowflake"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册头像支持的图片格式
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 用户资料
+
+const (
+	AvatarMaxSize      = 2 << 20 // 上传头像的最大字节数
+	avatarMaxDimension = 4096    // 上传头像的最大宽高
+	identiconSize      = 240
+	avatarDir          = "./static/avatars" // 上传的头像保存在静态文件目录下
+	avatarURLPrefix    = "/static/avatars/"
+)
+
+var (
+	ErrorAvatarInvalid  = errors.New("头像只支持png/jpeg/gif格式的图片")
+	ErrorAvatarTooLarge = errors.New("头像图片过大")
+	ErrorNicknameEmpty  = errors.New("昵称不能为空")
+)
+
+// avatarURL 未上传头像的用户使用自动生成的identicon
+func avatarURL(userID uint64, avatar string) string {
+	if len(avatar) > 0 {
+		return avatar
+	}
+	return fmt.Sprintf("/api/v1/user/%d/identicon", userID)
+}
+
+// GetUserProfile 查询用户的公开资料, 包括karma及发帖、评论数
+func GetUserProfile(v *Viewer, userID uint64) (*models.UserProfile, error) {
+	profile, err := mysql.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	profile.Avatar = avatarURL(userID, profile.Avatar)
+	// 不统计无权浏览的私有社区中的帖子及评论
+	hidden, err := hiddenCommunities(v)
+	if err != nil {
+		return nil, err
+	}
+	if profile.PostCount, profile.CommentCount, err = mysql.CountUserPostsAndComments(userID, hidden); err != nil {
+		zap.L().Error("mysql.CountUserPostsAndComments failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	if profile.Karma, err = redis.GetUserKarma(userID); err != nil {
+		zap.L().Error("redis.GetUserKarma failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	if err = fillFollowInfo(v, profile); err != nil {
+		zap.L().Error("fillFollowInfo failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return profile, nil
+}
+
+// GetUserBrief 查询作者的昵称及头像
+func GetUserBrief(userID uint64) (*models.UserBrief, error) {
+	user, err := mysql.GetUserBrief(userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Avatar = avatarURL(userID, user.Avatar)
+	return user, nil
+}
+
+// UpdateProfile 修改昵称及个人简介
+func UpdateProfile(userID uint64, p *models.ProfileForm) error {
+	p.NickName = strings.TrimSpace(p.NickName)
+	p.Bio = strings.TrimSpace(p.Bio)
+	if len(p.NickName) == 0 {
+		return ErrorNicknameEmpty
+	}
+	return mysql.UpdateUserProfile(userID, p)
+}
+
+// UploadAvatar 保存上传的头像并返回访问地址, 旧的头像文件随之删除
+func UploadAvatar(userID uint64, data []byte) (string, error) {
+	if len(data) > AvatarMaxSize {
+		return "", ErrorAvatarTooLarge
+	}
+	// 按内容而不是文件名判断格式, 防止上传伪装成图片的文件
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrorAvatarInvalid
+	}
+	if cfg.Width > avatarMaxDimension || cfg.Height > avatarMaxDimension {
+		return "", ErrorAvatarTooLarge
+	}
+	id, err := snowflake.GetID()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(avatarDir, 0755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%d-%d.%s", userID, id, format)
+	if err := ioutil.WriteFile(filepath.Join(avatarDir, name), data, 0644); err != nil {
+		return "", err
+	}
+	old, err := mysql.GetUserBrief(userID)
+	if err != nil {
+		return "", err
+	}
+	if err := mysql.UpdateUserAvatar(userID, avatarURLPrefix+name); err != nil {
+		return "", err
+	}
+	removeAvatarFile(old.Avatar)
+	return avatarURLPrefix + name, nil
+}
+
+// ResetAvatar 删除上传的头像, 恢复为自动生成的identicon
+func ResetAvatar(userID uint64) error {
+	old, err := mysql.GetUserBrief(userID)
+	if err != nil {
+		return err
+	}
+	if err := mysql.UpdateUserAvatar(userID, ""); err != nil {
+		return err
+	}
+	removeAvatarFile(old.Avatar)
+	return nil
+}
+
+// removeAvatarFile 删除之前上传的头像文件
+func removeAvatarFile(avatar string) {
+	if !strings.HasPrefix(avatar, avatarURLPrefix) {
+		return
+	}
+	name := filepath.Base(avatar)
+	if err := os.Remove(filepath.Join(avatarDir, name)); err != nil && !os.IsNotExist(err) {
+		zap.L().Warn("remove avatar file failed", zap.String("avatar", avatar), zap.Error(err))
+	}
+}
+
+// Identicon 生成用户的默认头像
+func Identicon(userID uint64) ([]byte, error) {
+	return identicon.Generate([]byte(fmt.Sprintf("bluebell:%d", userID)), identiconSize)
+}
+
+// GetUserPostList 分页查询用户发布的帖子
+func GetUserPostList(v *Viewer, userID uint64, page, size int64) (data []*models.ApiPostDetail, err error) {
+	author, err := GetUserBrief(userID)
+	if err != nil {
+		return
+	}
+	// 屏蔽的用户的帖子不展示
+	if blocked, err := isBlockedBy(v, userID); err != nil || blocked {
+		return make([]*models.ApiPostDetail, 0), err
+	}
+	hidden, err := hiddenCommunities(v)
+	if err != nil {
+		return
+	}
+	prefs, err := GetPreferences(v)
+	if err != nil {
+		return
+	}
+	posts, err := mysql.GetPostListByAuthor(userID, page, size, hidden, prefs.HideNSFW)
+	if err != nil {
+		return
+	}
+	data = make([]*models.ApiPostDetail, 0, len(posts))
+	for _, post := range posts {
+		community, err := mysql.GetCommunityByID(post.CommunityID)
+		if err != nil {
+			zap.L().Error("mysql.GetCommunityByID() failed",
+				zap.Uint64("community_id", post.CommunityID),
+				zap.Error(err))
+			continue
+		}
+		data = append(data, &models.ApiPostDetail{
+			Post:            post,
+			CommunityDetail: community,
+			AuthorName:      author.NickName,
+			Author:          author,
+		})
+	}
+	fillFlairs(data)
+	return applyPreferences(v, data)
+}
+
+// GetUserCommentList 分页查询用户发表的评论
+func GetUserCommentList(v *Viewer, userID uint64, page, size int64) ([]*models.Comment, error) {
+	if _, err := mysql.GetUserBrief(userID); err != nil {
+		return nil, err
+	}
+	// 屏蔽的用户的评论不展示
+	if blocked, err := isBlockedBy(v, userID); err != nil || blocked {
+		return make([]*models.Comment, 0), err
+	}
+	hidden, err := hiddenCommunities(v)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.GetCommentListByAuthor(userID, page, size, hidden)
+}
@@ -0,0 +1,83 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/rbac"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// 角色管理
+
+var (
+	ErrorInvalidRole  = errors.New("无效的角色")
+	ErrorRoleNotExist = errors.New("用户没有该角色")
+	ErrorLastAdmin    = errors.New("不能撤销最后一个管理员")
+)
+
+// GetUserRoleList 查询用户的全部角色
+func GetUserRoleList(userID uint64) ([]*models.UserRole, error) {
+	if _, err := mysql.GetUserBrief(userID); err != nil {
+		return nil, err
+	}
+	return mysql.GetUserRoleList(userID)
+}
+
+// checkRoleForm 管理员是全局角色, 版主必须指定存在的社区
+func checkRoleForm(p *models.RoleForm) error {
+	if !rbac.IsValidRole(p.Role) {
+		return ErrorInvalidRole
+	}
+	if p.Role == rbac.RoleAdmin {
+		if p.CommunityID != 0 {
+			return ErrorInvalidRole
+		}
+		return nil
+	}
+	if p.CommunityID == 0 {
+		return ErrorInvalidRole
+	}
+	if _, err := mysql.GetCommunityByID(p.CommunityID); err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			return ErrorInvalidRole
+		}
+		return err
+	}
+	return nil
+}
+
+// GrantRole 授予角色, 用户下一次刷新token后生效
+func GrantRole(p *models.RoleForm) error {
+	if err := checkRoleForm(p); err != nil {
+		return err
+	}
+	if _, err := mysql.GetUserBrief(p.UserID); err != nil {
+		return err
+	}
+	return mysql.InsertUserRole(&models.UserRole{UserID: p.UserID, Role: p.Role, CommunityID: p.CommunityID})
+}
+
+// RevokeRole 撤销角色, 至少保留一个管理员
+// 角色保存在已签发的access token中, 撤销后吊销用户的全部会话, 用户重新登录后按新的角色签发token
+func RevokeRole(p *models.RoleForm) error {
+	if !rbac.IsValidRole(p.Role) {
+		return ErrorInvalidRole
+	}
+	ok, err := mysql.DeleteUserRole(&models.UserRole{UserID: p.UserID, Role: p.Role, CommunityID: p.CommunityID})
+	if errors.Is(err, mysql.ErrorLastAdmin) {
+		return ErrorLastAdmin
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorRoleNotExist
+	}
+	if err = RevokeAllSessions(p.UserID); err != nil {
+		zap.L().Error("RevokeAllSessions failed", zap.Uint64("user_id", p.UserID), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// 社区的发帖规则及发帖模板
+// 规则使用gin的validator校验, 校验失败时返回validator的FieldError, 由controller用同一个翻译器翻译成提示信息
+// 必须带有的标签使用自定义的anyof校验, 在controller.InitTrans中注册
+
+var (
+	ErrorInvalidRule = errors.New("无效的发帖规则")
+	ErrorPostRule    = errors.New("帖子不符合社区的发帖规则")
+)
+
+// PostRuleError 帖子不符合社区的发帖规则, 记录每个字段的校验错误
+type PostRuleError struct {
+	names  []string // 返回给客户端的字段名, 如 title、fields.difficulty
+	labels []string // 提示信息中展示的字段名称
+	errs   []validator.FieldError
+}
+
+func (e *PostRuleError) Error() string {
+	return ErrorPostRule.Error()
+}
+
+func (e *PostRuleError) Unwrap() error {
+	return ErrorPostRule
+}
+
+// Translate 翻译校验错误, 返回字段名及对应的提示信息
+func (e *PostRuleError) Translate(trans ut.Translator) map[string]string {
+	res := make(map[string]string, len(e.errs))
+	for i, fe := range e.errs {
+		// Var校验的FieldError没有字段名, 提示信息的开头补上字段名称
+		res[e.names[i]] = e.labels[i] + fe.Translate(trans)
+	}
+	return res
+}
+
+// check 按validator的规则校验一个字段
+func (e *PostRuleError) check(v *validator.Validate, name, label string, value interface{}, tag string) {
+	if len(tag) == 0 {
+		return
+	}
+	errs, ok := v.Var(value, tag).(validator.ValidationErrors)
+	if !ok {
+		return
+	}
+	for _, fe := range errs {
+		e.names = append(e.names, name)
+		e.labels = append(e.labels, label)
+		e.errs = append(e.errs, fe)
+	}
+}
+
+// ruleValidator 与请求参数校验使用同一个validator
+func ruleValidator() *validator.Validate {
+	return binding.Validator.Engine().(*validator.Validate)
+}
+
+// lengthTag 生成长度限制的校验规则, 0表示不限制
+func lengthTag(min, max int) string {
+	tags := make([]string, 0, 2)
+	if min > 0 {
+		tags = append(tags, fmt.Sprintf("min=%d", min))
+	}
+	if max > 0 {
+		tags = append(tags, fmt.Sprintf("max=%d", max))
+	}
+	return strings.Join(tags, ",")
+}
+
+// checkPostRule 按社区的发帖规则校验帖子, 只保留发帖模板中定义的字段
+func checkPostRule(communityID uint64, post *models.Post) error {
+	rule, err := mysql.GetCommunityRule(communityID)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		post.Fields = nil
+		return nil
+	}
+	v := ruleValidator()
+	e := new(PostRuleError)
+	e.check(v, "title", "title", post.Title, lengthTag(rule.TitleMin, rule.TitleMax))
+	e.check(v, "content", "content", post.Content, lengthTag(rule.ContentMin, rule.ContentMax))
+	if len(rule.RequiredTags) > 0 {
+		e.check(v, "tags", "tags", []string(post.Tags), "anyof="+strings.Join(rule.RequiredTags, " "))
+	}
+	if len(rule.AllowedAttachmentTypes) > 0 {
+		allowed := "oneof=" + strings.ToLower(strings.Join(rule.AllowedAttachmentTypes, " "))
+		for i, attachment := range post.Attachments {
+			name := fmt.Sprintf("attachments[%d]", i)
+			e.check(v, name, name, attachmentType(attachment), allowed)
+		}
+	}
+	fields := make(models.StringMap, len(rule.Fields))
+	for _, f := range rule.Fields {
+		value := strings.TrimSpace(post.Fields[f.Name])
+		label := f.Label
+		if len(label) == 0 {
+			label = f.Name
+		}
+		e.check(v, "fields."+f.Name, label, value, f.Rule)
+		if len(value) > 0 {
+			fields[f.Name] = value
+		}
+	}
+	post.Fields = fields
+	if len(e.errs) > 0 {
+		return e
+	}
+	return nil
+}
+
+// attachmentType 附件的扩展名
+func attachmentType(attachment string) string {
+	u, err := url.Parse(attachment)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+}
+
+// validTag 检查模板字段的校验规则能否被validator识别, 未定义的规则会让validator panic
+func validTag(v *validator.Validate, tag string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	_ = v.Var("", tag)
+	return true
+}
+
+// GetCommunityRule 查询社区的发帖规则及模板, 没有设置时返回空规则
+func GetCommunityRule(v *Viewer, communityID uint64) (*models.CommunityRule, error) {
+	if _, err := readableCommunity(v, communityID); err != nil {
+		return nil, err
+	}
+	rule, err := mysql.GetCommunityRule(communityID)
+	if err != nil || rule != nil {
+		return rule, err
+	}
+	return new(models.CommunityRule), nil
+}
+
+// SaveCommunityRule 修改社区的发帖规则及模板
+func SaveCommunityRule(communityID uint64, rule *models.CommunityRule) error {
+	if _, err := mysql.GetCommunityByID(communityID); err != nil {
+		return err
+	}
+	if (rule.TitleMax > 0 && rule.TitleMin > rule.TitleMax) || (rule.ContentMax > 0 && rule.ContentMin > rule.ContentMax) {
+		return ErrorInvalidRule
+	}
+	v := ruleValidator()
+	names := make(map[string]bool, len(rule.Fields))
+	for _, f := range rule.Fields {
+		if names[f.Name] || !validTag(v, f.Rule) {
+			return ErrorInvalidRule
+		}
+		names[f.Name] = true
+	}
+	// 标签及扩展名会拼接到oneof/anyof规则的参数中, 不能包含分隔符
+	for _, t := range rule.RequiredTags {
+		if strings.ContainsAny(t, " ,|") {
+			return ErrorInvalidRule
+		}
+	}
+	for i, t := range rule.AllowedAttachmentTypes {
+		if strings.ContainsAny(t, " ,|") {
+			return ErrorInvalidRule
+		}
+		rule.AllowedAttachmentTypes[i] = strings.ToLower(strings.TrimPrefix(t, "."))
+	}
+	return mysql.SaveCommunityRule(communityID, rule)
+}
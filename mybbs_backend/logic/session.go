@@ -0,0 +1,55 @@
+package logic
+
+import (
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"errors"
+)
+
+var ErrorSessionNotExist = errors.New("会话不存在")
+
+// GetUserSessions 查询用户的设备会话列表, 并标记出当前会话
+func GetUserSessions(userID uint64, currentID string) ([]*models.Session, error) {
+	sessions, err := redis.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		s.Current = s.SessionID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession 吊销用户的某个设备会话, 该设备上的access token和refresh token随即失效
+func RevokeSession(userID uint64, sessionID string) error {
+	ok, err := redis.SessionBelongsTo(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorSessionNotExist
+	}
+	return redis.RemoveUserSession(userID, sessionID)
+}
+
+// RevokeAllSessions 吊销用户的全部设备会话, 用户需要重新登录
+func RevokeAllSessions(userID uint64) error {
+	return RevokeOtherSessions(userID, "")
+}
+
+// RevokeOtherSessions 吊销除当前会话外的所有设备会话
+func RevokeOtherSessions(userID uint64, currentID string) error {
+	sessions, err := redis.GetUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if s.SessionID == currentID {
+			continue
+		}
+		if err := redis.RemoveUserSession(userID, s.SessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 社区的慢速模式: 每个用户两次发帖之间至少间隔N分钟, 版主及管理员不受限制
+
+// SlowModeError 慢速模式下发帖间隔未到, 携带需要等待的时间
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("社区开启了慢速模式, 请%v后再发帖", e.RetryAfter.Round(time.Second))
+}
+
+// SetSlowMode 设置社区的慢速模式, 0表示关闭
+func SetSlowMode(communityID uint64, p *models.SlowModeForm) error {
+	if _, err := mysql.GetCommunityByID(communityID); err != nil {
+		return err
+	}
+	return mysql.UpdateCommunitySlowMode(communityID, p.Minutes)
+}
+
+// takeSlowMode 占用用户在社区的发帖间隔, 间隔未到时返回SlowModeError
+func takeSlowMode(v *Viewer, community *models.CommunityDetail) error {
+	if community.SlowMode <= 0 || v == nil || v.moderates(community.CommunityID) {
+		return nil
+	}
+	interval := time.Duration(community.SlowMode) * time.Minute
+	wait, err := redis.TakeSlowMode(community.CommunityID, v.UserID, interval)
+	if err != nil {
+		// redis故障时不影响发帖
+		zap.L().Error("redis.TakeSlowMode failed", zap.Uint64("community_id", community.CommunityID), zap.Error(err))
+		return nil
+	}
+	if wait > 0 {
+		return &SlowModeError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// releaseSlowMode 发帖失败时释放占用的发帖间隔
+func releaseSlowMode(v *Viewer, community *models.CommunityDetail) {
+	if community.SlowMode <= 0 || v == nil {
+		return
+	}
+	if err := redis.ReleaseSlowMode(community.CommunityID, v.UserID); err != nil {
+		zap.L().Warn("redis.ReleaseSlowMode failed", zap.Uint64("community_id", community.CommunityID), zap.Error(err))
+	}
+}
@@ -0,0 +1,196 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/jwt"
+	"bluebell_backend/pkg/totp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// 两步验证(TOTP)
+
+const (
+	totpIssuer            = "bluebell"
+	totpQRCodeSize        = 256
+	recoveryCodeCount     = 10
+	maxChallengeAttempts  = 5
+	totpReplayGuardWindow = (2*totp.Skew + 1) * totp.Period * time.Second
+)
+
+var (
+	ErrorTOTPNotEnrolled    = errors.New("尚未开启两步验证")
+	ErrorTOTPAlreadyEnabled = errors.New("已经开启了两步验证")
+	ErrorTOTPInvalidCode    = errors.New("验证码错误")
+	ErrorInvalidChallenge   = errors.New("两步验证凭证无效或已过期")
+	ErrorTooManyAttempts    = errors.New("验证失败次数过多,请重新登录")
+)
+
+// EnrollTOTP 生成新的TOTP密钥, 需要再提交一次验证码确认后才会启用
+func EnrollTOTP(userID uint64, account string) (*models.TOTPEnrollment, error) {
+	t, err := mysql.GetUserTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil && t.Enabled {
+		return nil, ErrorTOTPAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := mysql.SaveUserTOTP(userID, secret); err != nil {
+		return nil, err
+	}
+	uri := totp.URI(totpIssuer, account, secret)
+	png, err := totp.QRCode(uri, totpQRCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TOTPEnrollment{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// GetTOTPQRCode 获取待确认的TOTP密钥的二维码
+func GetTOTPQRCode(userID uint64, account string) ([]byte, error) {
+	t, err := mysql.GetUserTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrorTOTPNotEnrolled
+	}
+	if t.Enabled {
+		return nil, ErrorTOTPAlreadyEnabled
+	}
+	return totp.QRCode(totp.URI(totpIssuer, account, t.Secret), totpQRCodeSize)
+}
+
+// ConfirmTOTP 校验验证码后启用两步验证, 返回只展示这一次的恢复码
+func ConfirmTOTP(userID uint64, code string) (recoveryCodes []string, err error) {
+	t, err := mysql.GetUserTOTP(userID)
+	if err != nil {
+		return
+	}
+	if t == nil {
+		return nil, ErrorTOTPNotEnrolled
+	}
+	if t.Enabled {
+		return nil, ErrorTOTPAlreadyEnabled
+	}
+	if err = checkTOTPCode(userID, t.Secret, code); err != nil {
+		return
+	}
+	recoveryCodes = make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes = append(recoveryCodes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+	err = mysql.EnableUserTOTP(userID, hashes)
+	return
+}
+
+// DisableTOTP 关闭两步验证, 需要提供当前的验证码
+func DisableTOTP(userID uint64, code string) error {
+	t, err := mysql.GetUserTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if t == nil || !t.Enabled {
+		return ErrorTOTPNotEnrolled
+	}
+	if err := checkTOTPCode(userID, t.Secret, code); err != nil {
+		return err
+	}
+	return mysql.DeleteUserTOTP(userID)
+}
+
+// LoginTwoFactor 两步验证登录的第二步: 校验临时凭证及验证码/恢复码后签发正式token
+func LoginTwoFactor(p *models.TwoFactorLoginForm, device *jwt.Device) (aToken, rToken string, err error) {
+	claims, err := jwt.ParseChallengeToken(p.ChallengeToken)
+	if err != nil {
+		return "", "", ErrorInvalidChallenge
+	}
+	t, err := mysql.GetUserTOTP(claims.UserID)
+	if err != nil {
+		return
+	}
+	if t == nil || !t.Enabled {
+		return "", "", ErrorInvalidChallenge
+	}
+
+	if len(p.Code) > 0 {
+		err = checkTOTPCode(claims.UserID, t.Secret, p.Code)
+	} else {
+		var ok bool
+		if ok, err = mysql.UseRecoveryCode(claims.UserID, hashRecoveryCode(p.RecoveryCode)); err == nil && !ok {
+			err = ErrorTOTPInvalidCode
+		}
+	}
+	if err != nil {
+		if !errors.Is(err, ErrorTOTPInvalidCode) {
+			return
+		}
+		// 限制每个临时凭证的尝试次数, 防止暴力破解验证码
+		n, rerr := redis.IncrTOTPChallengeAttempts(claims.Id, jwt.ChallengeExpireDuration)
+		if rerr == nil && n >= maxChallengeAttempts {
+			_ = jwt.RevokeClaims(claims)
+			return "", "", ErrorTooManyAttempts
+		}
+		return
+	}
+	// 临时凭证只能使用一次
+	if err = jwt.RevokeClaims(claims); err != nil {
+		return
+	}
+	return jwt.GenToken(claims.UserID, claims.Username, device)
+}
+
+// checkTOTPCode 校验验证码, 同一个验证码只能使用一次
+func checkTOTPCode(userID uint64, secret, code string) error {
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrorTOTPInvalidCode
+	}
+	first, err := redis.MarkTOTPStepUsed(userID, step, totpReplayGuardWindow)
+	if err != nil {
+		return err
+	}
+	if !first {
+		return ErrorTOTPInvalidCode
+	}
+	return nil
+}
+
+// newRecoveryCode 生成形如 abcde-fghij 的恢复码
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.EncodeToString(buf))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+// hashRecoveryCode 恢复码只保存sha256, 忽略大小写及分隔符
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,215 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/models"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 未读评论数及"上次浏览之后"的新帖子
+// 打开帖子详情时记录读到的最后一条评论, 列表中返回之后的新评论数;
+// 浏览社区的帖子列表时记录浏览时间, 间隔visitGap以内的多次浏览算作同一次, 上一次浏览之后发布的帖子标记为new
+// 阅读记录先写入redis, 后台任务每readFlushInterval写入mysql一次
+
+const (
+	visitGap          = 30 * time.Minute // 间隔超过该时间的浏览算作新的一次
+	readFlushInterval = time.Minute      // 阅读记录写入mysql的间隔
+	readFlushBatch    = 500              // 每批写入mysql的阅读记录数
+)
+
+// getPostReads 查询读到的最后一条评论, redis中没有的从mysql读取并回填
+func getPostReads(userID uint64, postIDs []uint64) (map[uint64]uint64, error) {
+	reads, err := redis.GetPostReads(userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	missing := make([]uint64, 0, len(postIDs))
+	for _, id := range postIDs {
+		if _, ok := reads[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return reads, nil
+	}
+	list, err := mysql.GetPostReads(userID, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		reads[r.PostID] = r.LastCommentID
+	}
+	if err = redis.FillPostReads(userID, list); err != nil {
+		zap.L().Warn("redis.FillPostReads failed", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	return reads, nil
+}
+
+// getCommunityVisits 查询浏览社区的时间, redis中没有的从mysql读取并回填
+func getCommunityVisits(userID uint64, communityIDs []uint64) (map[uint64]*models.CommunityVisit, error) {
+	visits, err := redis.GetCommunityVisits(userID, communityIDs)
+	if err != nil {
+		return nil, err
+	}
+	missing := make([]uint64, 0, len(communityIDs))
+	for _, id := range communityIDs {
+		if _, ok := visits[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return visits, nil
+	}
+	list, err := mysql.GetCommunityVisits(userID, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range list {
+		visits[v.CommunityID] = v
+	}
+	if err = redis.FillCommunityVisits(userID, list); err != nil {
+		zap.L().Warn("redis.FillCommunityVisits failed", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+	return visits, nil
+}
+
+// fillUnread 填充帖子的未读评论数及是否为上次浏览之后的新帖子, 查询失败不影响帖子列表
+func fillUnread(v *Viewer, data []*models.ApiPostDetail) {
+	if v == nil || len(data) == 0 {
+		return
+	}
+	postIDs := make([]uint64, 0, len(data))
+	communityIDs := make([]uint64, 0)
+	seen := make(map[uint64]bool)
+	for _, post := range data {
+		postIDs = append(postIDs, post.PostID)
+		if !seen[post.Post.CommunityID] {
+			seen[post.Post.CommunityID] = true
+			communityIDs = append(communityIDs, post.Post.CommunityID)
+		}
+	}
+	if reads, err := getPostReads(v.UserID, postIDs); err != nil {
+		zap.L().Warn("getPostReads failed", zap.Uint64("user_id", v.UserID), zap.Error(err))
+	} else {
+		list := make([]*models.PostRead, 0, len(reads))
+		for pid, cid := range reads {
+			list = append(list, &models.PostRead{PostID: pid, LastCommentID: cid})
+		}
+		counts, err := mysql.CountUnreadComments(list)
+		if err != nil {
+			zap.L().Warn("mysql.CountUnreadComments failed", zap.Uint64("user_id", v.UserID), zap.Error(err))
+		}
+		for _, post := range data {
+			post.UnreadComments = counts[post.PostID]
+		}
+	}
+	visits, err := getCommunityVisits(v.UserID, communityIDs)
+	if err != nil {
+		zap.L().Warn("getCommunityVisits failed", zap.Uint64("user_id", v.UserID), zap.Error(err))
+		return
+	}
+	for _, post := range data {
+		if visit, ok := visits[post.Post.CommunityID]; ok && visit.PrevVisit > 0 {
+			post.New = post.Post.CreateTime.Unix() > visit.PrevVisit
+		}
+	}
+}
+
+// markPostRead 打开帖子详情时记录读到了最后一条评论
+func markPostRead(userID, postID uint64) {
+	lastID, err := mysql.GetLastCommentID(postID)
+	if err != nil {
+		zap.L().Warn("mysql.GetLastCommentID failed", zap.Uint64("post_id", postID), zap.Error(err))
+		return
+	}
+	setPostRead(userID, postID, lastID)
+}
+
+// setPostRead 记录用户在帖子中读到的评论, 没有评论时也记录, 之后的评论都算作未读
+func setPostRead(userID, postID, commentID uint64) {
+	// 先回填mysql中的记录, 避免redis中的记录丢失后读到的位置后退
+	if _, err := getPostReads(userID, []uint64{postID}); err != nil {
+		zap.L().Warn("getPostReads failed", zap.Uint64("user_id", userID), zap.Error(err))
+		return
+	}
+	if err := redis.SetPostRead(userID, postID, commentID); err != nil {
+		zap.L().Warn("redis.SetPostRead failed", zap.Uint64("user_id", userID), zap.Error(err))
+	}
+}
+
+// recordCommunityVisit 浏览社区的帖子列表时记录浏览时间
+func recordCommunityVisit(v *Viewer, communityID uint64) {
+	if v == nil {
+		return
+	}
+	if _, err := getCommunityVisits(v.UserID, []uint64{communityID}); err != nil {
+		zap.L().Warn("getCommunityVisits failed", zap.Uint64("user_id", v.UserID), zap.Error(err))
+		return
+	}
+	if _, err := redis.RecordCommunityVisit(v.UserID, communityID, time.Now(), visitGap); err != nil {
+		zap.L().Warn("redis.RecordCommunityVisit failed", zap.Uint64("user_id", v.UserID), zap.Error(err))
+	}
+}
+
+// FlushReadMarkers 把redis中待写入的阅读记录写入mysql, 返回写入的条数
+func FlushReadMarkers() (int, error) {
+	total := 0
+	for {
+		members, err := redis.PopReadDirty(readFlushBatch)
+		if err != nil || len(members) == 0 {
+			return total, err
+		}
+		for i, m := range members {
+			if err = flushReadMarker(m); err != nil {
+				// 写入失败的及之后的记录放回集合, 下次再写入
+				if rerr := redis.RestoreReadDirty(members[i:]); rerr != nil {
+					zap.L().Error("redis.RestoreReadDirty failed", zap.Error(rerr))
+				}
+				return total, err
+			}
+			total++
+		}
+		if int64(len(members)) < readFlushBatch {
+			return total, nil
+		}
+	}
+}
+
+// flushReadMarker 写入一条阅读记录, 无效或已过期的记录直接丢弃
+func flushReadMarker(member string) error {
+	kind, userID, targetID, ok := redis.ParseDirtyMember(member)
+	if !ok {
+		return nil
+	}
+	switch kind {
+	case "p":
+		id, ok, err := redis.GetPostRead(userID, targetID)
+		if err != nil || !ok {
+			return err
+		}
+		return mysql.SavePostRead(userID, targetID, id)
+	case "c":
+		visit, err := redis.GetCommunityVisit(userID, targetID)
+		if err != nil || visit == nil {
+			return err
+		}
+		return mysql.SaveCommunityVisit(userID, visit)
+	}
+	return nil
+}
+
+// StartReadFlusher 启动定期把阅读记录写入mysql的后台任务
+func StartReadFlusher() {
+	go func() {
+		ticker := time.NewTicker(readFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := FlushReadMarkers(); err != nil {
+				zap.L().Error("FlushReadMarkers failed", zap.Int("flushed", n), zap.Error(err))
+			}
+		}
+	}()
+}
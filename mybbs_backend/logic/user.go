@@ -10,7 +10,8 @@ import (
 	"database/sql"
 	"errors"
 	"strings"
-	//"go.uber.org/zap"
+
+	"go.uber.org/zap"
 )
 
 /**
@@ -75,7 +76,7 @@ func SignUp(p *models.RegisterForm2) (error error) {
  * @Description //TODO 判断能否用邮箱登录的逻辑
  * @Date 21:52 2022/2/10
  **/
-func Login(p *models.LoginForm2) (user *models.User2, error error) {
+func Login(p *models.LoginForm2, device *jwt.Device) (user *models.User2, error error) {
 	user = &models.User2{
 		Email:    p.Email,
 		Password: p.Password,
@@ -83,14 +84,41 @@ func Login(p *models.LoginForm2) (user *models.User2, error error) {
 	if err := mysql.Login(user); err != nil {
 		return nil, err
 	}
+	if err := issueLoginTokens(user, device); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// issueLoginTokens 身份校验通过后签发token, 密码登录和第三方登录共用
+// 开启了两步验证的用户先拿到临时凭证, 提交验证码后才签发正式token
+func issueLoginTokens(user *models.User2, device *jwt.Device) (err error) {
+	t, err := mysql.GetUserTOTP(user.UserID)
+	if err != nil {
+		return
+	}
+	if t != nil && t.Enabled {
+		user.ChallengeToken, err = jwt.GenChallengeToken(user.UserID, user.Email)
+		return
+	}
 	// 生成JWT
 	//return jwt.GenToken(user.UserID,user.UserName)
-	atoken, rtoken, err := jwt.GenToken(user.UserID, user.Email)
-	if err != nil {
+	user.AccessToken, user.RefreshToken, err = jwt.GenToken(user.UserID, user.Email, device)
+	return
+}
+
+// Logout 登出: 吊销当前的access token, 以及客户端一并提交的refresh token
+func Logout(claims *jwt.MyClaims, p *models.LogoutForm) (err error) {
+	if err = jwt.RevokeClaims(claims); err != nil {
+		return
+	}
+	if len(p.RefreshToken) == 0 {
 		return
 	}
-	user.AccessToken = atoken
-	user.RefreshToken = rtoken
+	// refresh token本身已失效或不属于当前用户时不吊销, 不影响登出结果
+	if err := jwt.RevokeToken(p.RefreshToken, claims.UserID); err != nil {
+		zap.L().Warn("jwt.RevokeToken(refresh token) failed", zap.Uint64("user_id", claims.UserID), zap.Error(err))
+	}
 	return
 }
 
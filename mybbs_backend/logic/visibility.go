@@ -0,0 +1,105 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/rbac"
+	"errors"
+)
+
+// 受限及私有社区的访问控制
+// 私有社区的帖子只有通过审核的成员、版主及管理员可以浏览, 其他人访问时按不存在处理, 不暴露帖子是否存在
+// 受限社区所有人可以浏览, 只有通过审核的成员可以发帖
+
+var ErrorNotMember = errors.New("只有通过审核的社区成员才能发帖")
+
+// Viewer 当前访问的用户, 未登录时为nil
+type Viewer struct {
+	UserID uint64
+	Roles  []string
+}
+
+// moderates 是否为社区的版主或管理员
+func (v *Viewer) moderates(communityID uint64) bool {
+	return v != nil && rbac.Can(v.Roles, rbac.PermApproveMember, communityID)
+}
+
+// isMember 是否为通过审核的社区成员
+func (v *Viewer) isMember(communityID uint64) (bool, error) {
+	if v == nil {
+		return false, nil
+	}
+	status, ok, err := mysql.GetMemberStatus(v.UserID, communityID)
+	return ok && status == models.MemberApproved, err
+}
+
+// canRead 是否可以浏览社区的帖子
+func canRead(v *Viewer, community *models.CommunityDetail) (bool, error) {
+	if community.Visibility != models.CommunityPrivate || v.moderates(community.CommunityID) {
+		return true, nil
+	}
+	return v.isMember(community.CommunityID)
+}
+
+// canPost 是否可以在社区发帖
+func canPost(v *Viewer, community *models.CommunityDetail) (bool, error) {
+	if community.Visibility == models.CommunityPublic || v.moderates(community.CommunityID) {
+		return true, nil
+	}
+	return v.isMember(community.CommunityID)
+}
+
+// readableCommunity 查询可以浏览的社区, 无权浏览的私有社区按不存在处理
+func readableCommunity(v *Viewer, communityID uint64) (*models.CommunityDetail, error) {
+	community, err := mysql.GetCommunityByID(communityID)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := canRead(v, community)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, mysql.ErrorInvalidID
+	}
+	return community, nil
+}
+
+// readablePost 查询可以浏览的帖子, 私有社区的帖子对非成员按不存在处理
+func readablePost(v *Viewer, postID int64) (*models.Post, *models.CommunityDetail, error) {
+	post, err := mysql.GetPostByID(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	community, err := readableCommunity(v, post.CommunityID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, community, nil
+}
+
+// hiddenCommunities 查询viewer无权浏览的私有社区, 用于过滤帖子及评论列表
+func hiddenCommunities(v *Viewer) ([]uint64, error) {
+	private, err := mysql.GetPrivateCommunityIDs()
+	if err != nil || len(private) == 0 {
+		return nil, err
+	}
+	if v == nil {
+		return private, nil
+	}
+	joined, err := mysql.GetUserCommunityIDs(v.UserID)
+	if err != nil {
+		return nil, err
+	}
+	member := make(map[uint64]bool, len(joined))
+	for _, id := range joined {
+		member[id] = true
+	}
+	hidden := make([]uint64, 0, len(private))
+	for _, id := range private {
+		if !member[id] && !v.moderates(id) {
+			hidden = append(hidden, id)
+		}
+	}
+	return hidden, nil
+}
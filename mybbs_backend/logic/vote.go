@@ -6,6 +6,7 @@
 package logic
 
 import (
+	"bluebell_backend/dao/mysql"
 	"bluebell_backend/dao/redis"
 	"bluebell_backend/models"
 	"go.uber.org/zap"
@@ -47,10 +48,31 @@ v=-1时，有两种情况
  * @Description //TODO 投票功能
  * @Date 11:35 2022/2/14
  **/
-func VoteForPost(userId uint64,p *models.VoteDataForm) error {
+func VoteForPost(v *Viewer, p *models.VoteDataForm) error {
+	userId := v.UserID
 	zap.L().Debug("VoteForPost",
 		zap.Uint64("userId",userId),
 		zap.String("postId", p.PostID),
 		zap.Int8("Direction",p.Direction))
-	return redis.VoteForPost(strconv.Itoa(int(userId)), p.PostID, float64(p.Direction))
+	postID, err := strconv.ParseInt(p.PostID, 10, 64)
+	if err != nil {
+		return mysql.ErrorInvalidID
+	}
+	// 私有社区的帖子只有成员可以投票
+	post, _, err := readablePost(v, postID)
+	if err != nil {
+		return err
+	}
+	// 被禁言的用户不能给该社区的帖子投票
+	if err := checkBan(userId, post.CommunityID); err != nil {
+		return err
+	}
+	if err := redis.VoteForPost(strconv.Itoa(int(userId)), p.PostID, float64(p.Direction)); err != nil {
+		return err
+	}
+	pushVote(post.PostID)
+	if p.Direction == 1 {
+		notifyVote(post, userId)
+	}
+	return nil
 }
@@ -0,0 +1,344 @@
+package logic
+
+import (
+	"bluebell_backend/dao/mysql"
+	"bluebell_backend/models"
+	"bluebell_backend/pkg/webhook"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 向外部地址投递论坛事件(发帖、评论、删帖)
+// 管理员按事件类型及社区注册webhook, 事件发生时给每个匹配的webhook写入一条投递记录,
+// 后台任务取出到期的记录发送签名的请求, 失败后按指数退避重试, 重试次数用完后可以重新投递
+
+var (
+	ErrorWebhookNotExist   = errors.New("webhook不存在")
+	ErrorDeliveryNotExist  = errors.New("投递记录不存在")
+	ErrorDeliveryNotFailed = errors.New("只能重新投递失败的记录")
+	ErrorWebhookURL        = errors.New("webhook地址只支持http及https")
+)
+
+const (
+	webhookMaxAttempts  = 8                // 最多投递的次数, 之后标记为失败
+	webhookBackoffBase  = 30 * time.Second // 第一次失败后的重试间隔, 之后每次翻倍
+	webhookBackoffMax   = 6 * time.Hour    // 重试间隔的上限
+	webhookTimeout      = 10 * time.Second // 每次请求的超时时间
+	webhookLease        = time.Minute      // 投递中的记录在这段时间内不会被其他实例取走
+	webhookPollInterval = 10 * time.Second // 检查到期记录的间隔
+	webhookWorkers      = 8                // 同时投递的请求数, 一个地址响应慢不会拖住其他记录
+)
+
+var webhookClient = webhook.NewClient(webhookTimeout)
+
+// webhookWake 有新的投递记录时唤醒后台任务
+var webhookWake = make(chan struct{}, 1)
+
+// checkWebhookForm 检查webhook的地址及社区
+func checkWebhookForm(p *models.WebhookForm) error {
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return ErrorWebhookURL
+	}
+	if p.CommunityID > 0 {
+		if _, err := mysql.GetCommunityByID(p.CommunityID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getWebhook 查询webhook, 不存在时返回ErrorWebhookNotExist
+func getWebhook(webhookID uint64) (*models.Webhook, error) {
+	hook, err := mysql.GetWebhook(webhookID)
+	if errors.Is(err, mysql.ErrorInvalidID) {
+		return nil, ErrorWebhookNotExist
+	}
+	return hook, err
+}
+
+// CreateWebhook 注册webhook, 签名密钥只在创建时返回
+func CreateWebhook(creatorID uint64, p *models.WebhookForm) (*models.Webhook, error) {
+	if err := checkWebhookForm(p); err != nil {
+		return nil, err
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	active := p.Active == nil || *p.Active
+	id, err := mysql.CreateWebhook(creatorID, p, active, secret)
+	if err != nil {
+		return nil, err
+	}
+	hook, err := mysql.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = secret
+	return hook, nil
+}
+
+// UpdateWebhook 修改webhook, 签名密钥不变
+func UpdateWebhook(webhookID uint64, p *models.WebhookForm) (*models.Webhook, error) {
+	hook, err := getWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWebhookForm(p); err != nil {
+		return nil, err
+	}
+	active := hook.Active
+	if p.Active != nil {
+		active = *p.Active
+	}
+	if err := mysql.UpdateWebhook(webhookID, p, active); err != nil {
+		return nil, err
+	}
+	hook.URL, hook.Event, hook.CommunityID, hook.Active = p.URL, p.Event, p.CommunityID, active
+	hook.Secret = ""
+	return hook, nil
+}
+
+// DeleteWebhook 删除webhook及其投递记录
+func DeleteWebhook(webhookID uint64) error {
+	if _, err := getWebhook(webhookID); err != nil {
+		return err
+	}
+	return mysql.DeleteWebhook(webhookID)
+}
+
+// GetWebhookList 查询全部webhook, 不返回签名密钥
+func GetWebhookList() ([]*models.Webhook, error) {
+	list, err := mysql.GetWebhookList()
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range list {
+		hook.Secret = ""
+	}
+	return list, nil
+}
+
+// GetWebhookDeliveryList 分页查询webhook的投递记录, status小于0时查询全部状态
+func GetWebhookDeliveryList(webhookID uint64, status int8, page, size int64) ([]*models.WebhookDelivery, error) {
+	if _, err := getWebhook(webhookID); err != nil {
+		return nil, err
+	}
+	return mysql.GetWebhookDeliveryList(webhookID, status, page, size)
+}
+
+// ReplayDelivery 重新投递一条失败的记录, 返回新的投递记录
+func ReplayDelivery(deliveryID uint64) (*models.WebhookDelivery, error) {
+	d, err := mysql.GetWebhookDelivery(deliveryID)
+	if errors.Is(err, mysql.ErrorInvalidID) {
+		return nil, ErrorDeliveryNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != models.DeliveryFailed {
+		return nil, ErrorDeliveryNotFailed
+	}
+	id, err := mysql.ReplayDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	wakeWebhookWorker()
+	return mysql.GetWebhookDelivery(id)
+}
+
+// ReplayFailedDeliveries 重新投递webhook所有失败的记录, 返回重新投递的条数
+func ReplayFailedDeliveries(webhookID uint64) (int64, error) {
+	if _, err := getWebhook(webhookID); err != nil {
+		return 0, err
+	}
+	n, err := mysql.ReplayFailedDeliveries(webhookID)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		wakeWebhookWorker()
+	}
+	return n, nil
+}
+
+// triggerWebhooks 给接收社区内该事件的webhook写入投递记录, 失败只记录日志, 不影响发帖、评论等操作
+// 私有社区的事件只投递给注册在该社区上的webhook, 不投递给接收所有社区的webhook
+func triggerWebhooks(event string, communityID uint64, data interface{}) {
+	hooks, err := mysql.GetActiveWebhooks(event, communityID)
+	if err != nil {
+		zap.L().Error("mysql.GetActiveWebhooks failed", zap.String("event", event), zap.Error(err))
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	community, err := mysql.GetCommunityByID(communityID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityByID failed", zap.Uint64("community_id", communityID), zap.Error(err))
+		return
+	}
+	if community.Visibility == models.CommunityPrivate {
+		scoped := hooks[:0]
+		for _, hook := range hooks {
+			if hook.CommunityID != 0 {
+				scoped = append(scoped, hook)
+			}
+		}
+		if hooks = scoped; len(hooks) == 0 {
+			return
+		}
+	}
+	payload, err := json.Marshal(&models.WebhookPayload{
+		Event:     event,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		zap.L().Error("json.Marshal webhook payload failed", zap.String("event", event), zap.Error(err))
+		return
+	}
+	ids := make([]uint64, 0, len(hooks))
+	for _, hook := range hooks {
+		ids = append(ids, hook.WebhookID)
+	}
+	if err := mysql.InsertWebhookDeliveries(ids, event, payload); err != nil {
+		zap.L().Error("mysql.InsertWebhookDeliveries failed", zap.String("event", event), zap.Error(err))
+		return
+	}
+	wakeWebhookWorker()
+}
+
+// triggerPostWebhooks 发帖、删帖事件
+func triggerPostWebhooks(event string, post *models.Post) {
+	data := &models.WebhookPost{
+		PostID:      post.PostID,
+		CommunityID: post.CommunityID,
+		AuthorID:    post.AuthorId,
+		Title:       post.Title,
+	}
+	if event == models.WebhookPostCreated {
+		data.Content = post.Content
+	}
+	triggerWebhooks(event, post.CommunityID, data)
+}
+
+// triggerCommentWebhooks 评论事件
+func triggerCommentWebhooks(communityID uint64, comment *models.Comment) {
+	triggerWebhooks(models.WebhookCommentCreated, communityID, &models.WebhookComment{
+		CommentID:   comment.CommentID,
+		PostID:      comment.PostID,
+		ParentID:    comment.ParentID,
+		CommunityID: communityID,
+		AuthorID:    comment.AuthorID,
+		Content:     comment.Content,
+	})
+}
+
+// wakeWebhookWorker 唤醒后台任务, 已有待处理的唤醒时不阻塞
+func wakeWebhookWorker() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverWebhooks 由webhookWorkers个goroutine并发投递所有到期的记录, 返回投递的次数及遇到的第一个错误
+// 每条记录由ClaimDueDelivery单独取出, 不会被重复投递
+func DeliverWebhooks() (int, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		n        int
+		firstErr error
+	)
+	for i := 0; i < webhookWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				d, err := mysql.ClaimDueDelivery(time.Now(), webhookLease)
+				if err == nil && d != nil {
+					err = deliverWebhook(d)
+				}
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if err == nil && d != nil {
+					n++
+				}
+				mu.Unlock()
+				if err != nil || d == nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return n, firstErr
+}
+
+// deliverWebhook 投递一次并记录结果, 失败时按指数退避安排下次投递
+func deliverWebhook(d *models.WebhookDelivery) error {
+	now := time.Now()
+	d.Attempts++
+	hook, err := mysql.GetWebhook(d.WebhookID)
+	if err != nil && !errors.Is(err, mysql.ErrorInvalidID) {
+		return err
+	}
+	if hook == nil || !hook.Active {
+		// webhook已删除或停用, 不再重试
+		d.Status, d.ResponseCode, d.ResponseBody = models.DeliveryFailed, 0, "webhook已删除或停用"
+		return mysql.UpdateDeliveryResult(d)
+	}
+	res, err := webhookClient.Deliver(&webhook.Request{
+		URL:        hook.URL,
+		Secret:     hook.Secret,
+		Event:      d.Event,
+		DeliveryID: d.DeliveryID,
+		Payload:    d.Payload,
+	}, now)
+	d.ResponseCode, d.ResponseBody = res.StatusCode, res.Body
+	switch {
+	case err == nil:
+		d.Status = models.DeliverySuccess
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status = models.DeliveryFailed
+	default:
+		d.NextAttemptTime = now.Add(webhook.Backoff(d.Attempts, webhookBackoffBase, webhookBackoffMax))
+	}
+	if err != nil {
+		if res.StatusCode == 0 {
+			d.ResponseBody = err.Error()
+		}
+		zap.L().Warn("deliver webhook failed",
+			zap.Uint64("delivery_id", d.DeliveryID),
+			zap.Int("attempts", d.Attempts),
+			zap.Error(err))
+	}
+	return mysql.UpdateDeliveryResult(d)
+}
+
+// StartWebhookWorker 启动投递webhook的后台任务, 定期检查到期的记录, 有新的记录时立即投递
+func StartWebhookWorker() {
+	go func() {
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-webhookWake:
+			}
+			if n, err := DeliverWebhooks(); err != nil {
+				zap.L().Error("DeliverWebhooks failed", zap.Int("delivered", n), zap.Error(err))
+			}
+		}
+	}()
+}
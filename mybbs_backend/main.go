@@ -5,6 +5,10 @@ import (
 	"bluebell_backend/dao/mysql"
 	"bluebell_backend/dao/redis"
 	"bluebell_backend/logger"
+	"bluebell_backend/logic"
+	"bluebell_backend/pkg/jwt"
+	"bluebell_backend/pkg/mailer"
+	"bluebell_backend/pkg/oidc"
 	"bluebell_backend/pkg/snowflake"
 	"bluebell_backend/routers"
 	"bluebell_backend/settings"
@@ -38,6 +42,14 @@ func main() {
 		fmt.Printf("init logger failed, err:%v\n", err)
 		return
 	}
+	if err := jwt.Init(settings.Conf.AuthConfig); err != nil {
+		fmt.Printf("init jwt keys failed, err:%v\n", err)
+		return
+	}
+	if err := oidc.Init(settings.Conf.AuthConfig.OIDC); err != nil {
+		fmt.Printf("init oidc failed, err:%v\n", err)
+		return
+	}
 	if err := mysql.Init(settings.Conf.MySQLConfig); err != nil {
 		fmt.Printf("init mysql failed, err:%v\n", err)
 		return
@@ -48,11 +60,24 @@ func main() {
 		return
 	}
 	defer redis.Close()
+	jwt.SetStore(redis.TokenStore{}) // token吊销名单存放在redis中
+	jwt.SetRoleLoader(mysql.GetUserRoles) // 签发access token时带上用户的角色
 	// 雪花算法生成分布式ID
 	if err := snowflake.Init(1); err != nil {
 		fmt.Printf("init snowflake failed, err:%v\n", err)
 		return
 	}
+	logic.StartReadFlusher() // 定期把redis中的阅读记录写入mysql
+	if err := mailer.Init(settings.Conf.MailConfig); err != nil {
+		fmt.Printf("init mailer failed, err:%v\n", err)
+		return
+	}
+	if err := logic.InitDigest(settings.Conf.DigestConfig); err != nil {
+		fmt.Printf("init digest failed, err:%v\n", err)
+		return
+	}
+	logic.StartDigestScheduler() // 定期发送订阅社区的热门帖子邮件摘要
+	logic.StartWebhookWorker()   // 投递webhook事件, 失败时按指数退避重试
 
 	if err := controller.InitTrans("zh");err!=nil{
 		fmt.Printf("init validator Trans failed,err:%v\n",err)
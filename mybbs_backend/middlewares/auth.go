@@ -38,6 +38,39 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		}
 		// 将当前请求的userID信息保存到请求的上下文c上
 		c.Set(controller.ContextUserIDKey, mc.UserID)
+		c.Set(controller.ContextClaimsKey, mc) // 登出时需要用到token的jti及过期时间
 		c.Next() // 后续的处理函数可以用过c.Get(ContextUserIDKey)来获取当前请求的用户信息
 	}
 }
+
+// OptionalJWTAuthMiddleware 可选的JWT认证, 用于未登录也能访问的接口
+// 携带了有效的Token时与JWTAuthMiddleware一样保存用户信息, 否则按未登录处理
+func OptionalJWTAuthMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.Request.Header.Get("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if mc, err := jwt.ParseToken(parts[1]); err == nil {
+				c.Set(controller.ContextUserIDKey, mc.UserID)
+				c.Set(controller.ContextClaimsKey, mc)
+			}
+		}
+		c.Next()
+	}
+}
+
+// QueryTokenMiddleware 浏览器的EventSource不能设置请求头, 允许把access token放在URI的access_token参数中
+// 需要放在JWTAuthMiddleware之前, 之后按同样的方式校验
+func QueryTokenMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if c.Request.Header.Get("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+				// 不在访问日志中记录token
+				q := c.Request.URL.Query()
+				q.Del("access_token")
+				c.Request.URL.RawQuery = q.Encode()
+			}
+		}
+		c.Next()
+	}
+}
@@ -6,10 +6,15 @@
 package middlewares
 
 import (
+	"bluebell_backend/controller"
+	"bluebell_backend/dao/redis"
+	"bluebell_backend/settings"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"time"
 	"github.com/juju/ratelimit"
+	"go.uber.org/zap"
 )
 
 /**
@@ -29,4 +34,54 @@ func RateLimitMiddleware(fillInterval time.Duration, cap int64) func(c *gin.Cont
 		// 取到令牌就放行
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RateLimit 按操作限流, 登录用户按用户及IP分别计数, 未登录时只按IP计数
+// 限额读取config.yaml中的rate_limit, 修改配置文件后立即生效; 需要按用户计数时放在JWTAuthMiddleware之后
+func RateLimit(action string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		rule := rateLimitRule(action)
+		if rule == nil {
+			c.Next()
+			return
+		}
+		window := time.Duration(rule.Window) * time.Second
+		var keys []string
+		var limits []int64
+		if userID, ok := c.Get(controller.ContextUserIDKey); ok && rule.UserLimit > 0 {
+			keys = append(keys, fmt.Sprintf("%s:user:%v", action, userID))
+			limits = append(limits, rule.UserLimit)
+		}
+		if rule.IPLimit > 0 {
+			keys = append(keys, fmt.Sprintf("%s:ip:%s", action, c.ClientIP()))
+			limits = append(limits, rule.IPLimit)
+		}
+		for i, key := range keys {
+			wait, err := redis.TakeRateLimit(key, limits[i], window)
+			if err != nil {
+				// redis故障时不影响正常请求
+				zap.L().Error("redis.TakeRateLimit failed", zap.String("key", key), zap.Error(err))
+				break
+			}
+			if wait > 0 {
+				controller.ResponseTooManyRequests(c, wait)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// rateLimitRule 查询操作的限额, 未开启限流或没有配置时返回nil
+func rateLimitRule(action string) *settings.RateLimitRule {
+	cfg := settings.Conf.RateLimitConfig
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	rule, ok := cfg.Actions[action]
+	if !ok || rule == nil || rule.Window <= 0 {
+		return nil
+	}
+	return rule
+}
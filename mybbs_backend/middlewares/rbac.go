@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"bluebell_backend/controller"
+	"bluebell_backend/pkg/jwt"
+	"bluebell_backend/pkg/rbac"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 权限控制中间件, 需要放在JWTAuthMiddleware之后
+// 角色取自access token, 撤销角色时会吊销用户的全部会话, 不会继续使用旧token中的角色
+
+// ScopeFunc 从请求中取出权限作用的社区id
+type ScopeFunc func(c *gin.Context) (communityID uint64, ok bool)
+
+// CommunityParam 从路径参数中取社区id
+func CommunityParam(name string) ScopeFunc {
+	return func(c *gin.Context) (uint64, bool) {
+		id, err := strconv.ParseUint(c.Param(name), 10, 64)
+		return id, err == nil && id > 0
+	}
+}
+
+// currentRoles 取出access token中携带的角色
+func currentRoles(c *gin.Context) ([]string, bool) {
+	v, ok := c.Get(controller.ContextClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*jwt.MyClaims)
+	if !ok {
+		return nil, false
+	}
+	return claims.Roles, true
+}
+
+// RequireRole 要求拥有指定角色(任意社区), 管理员拥有所有角色
+func RequireRole(role string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		roles, ok := currentRoles(c)
+		if !ok {
+			controller.ResponseError(c, controller.CodeNotLogin)
+			c.Abort()
+			return
+		}
+		if !rbac.HasRole(roles, role) {
+			controller.ResponseError(c, controller.CodeForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission 要求拥有指定权限
+// 指定了scope时检查对应社区的权限(版主只能管理自己的社区), 否则要求全局权限
+func RequirePermission(perm string, scope ...ScopeFunc) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		roles, ok := currentRoles(c)
+		if !ok {
+			controller.ResponseError(c, controller.CodeNotLogin)
+			c.Abort()
+			return
+		}
+		var communityID uint64
+		if len(scope) > 0 {
+			if communityID, ok = scope[0](c); !ok {
+				controller.ResponseError(c, controller.CodeInvalidParams)
+				c.Abort()
+				return
+			}
+		}
+		if !rbac.Can(roles, perm, communityID) {
+			controller.ResponseError(c, controller.CodeForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+// MaxMutedKeywords 每个用户最多屏蔽的关键词数
+const MaxMutedKeywords = 50
+
+// BlockList 屏蔽的用户、不想看的社区及关键词
+type BlockList struct {
+	Users       []*UserBrief `json:"users"`
+	Communities []*Community `json:"communities"`
+	Keywords    []string     `json:"keywords"`
+}
+
+// MuteKeywordForm 屏蔽关键词
+type MuteKeywordForm struct {
+	Keyword string `json:"keyword" binding:"required,max=32"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MaxBookmarkFolders 每个用户最多创建的收藏夹数
+const MaxBookmarkFolders = 50
+
+// BookmarkFolder 收藏夹
+type BookmarkFolder struct {
+	FolderID   uint64    `json:"folder_id" db:"folder_id"`
+	Name       string    `json:"name" db:"name"`
+	PostCount  int64     `json:"post_count" db:"post_count"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// Bookmark 收藏的帖子及所在的收藏夹
+type Bookmark struct {
+	PostID   uint64 `db:"post_id"`
+	FolderID uint64 `db:"folder_id"`
+}
+
+// BookmarkFolderForm 创建/重命名收藏夹
+type BookmarkFolderForm struct {
+	Name string `json:"name" binding:"required,max=32"`
+}
+
+// SavePostForm 收藏帖子, 已收藏时移动到指定的收藏夹; folder_id为0表示未分类
+type SavePostForm struct {
+	FolderID uint64 `json:"folder_id"`
+}
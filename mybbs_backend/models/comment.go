@@ -3,10 +3,12 @@ package models
 import "time"
 
 type Comment struct {
-	PostID     uint64    `db:"question_id" json:"question_id"`
-	ParentID   uint64    `db:"parent_id" json:"parent_id"`
-	CommentID  uint64    `db:"comment_id" json:"comment_id"`
-	AuthorID   uint64    `db:"author_id" json:"author_id"`
-	Content    string    `db:"content" json:"content"`
-	CreateTime time.Time `db:"create_time" json:"create_time"`
+	PostID          uint64     `db:"post_id" json:"question_id"`
+	ParentID        uint64     `db:"parent_id" json:"parent_id"`
+	CommentID       uint64     `db:"comment_id" json:"comment_id"`
+	AuthorID        uint64     `db:"author_id" json:"author_id"`
+	Content         string     `db:"content" json:"content"`
+	CreateTime      time.Time  `db:"create_time" json:"create_time"`
+	Mentions        []*Mention `db:"-" json:"mentions,omitempty"`         // 提到的用户
+	RenderedContent string     `db:"-" json:"rendered_content,omitempty"` // 提到的用户渲染成链接后的内容
 }
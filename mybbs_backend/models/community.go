@@ -10,6 +10,7 @@ import "time"
 type Community struct {
 	CommunityID   uint64 `json:"community_id" db:"community_id"`
 	CommunityName string `json:"community_name" db:"community_name"`
+	Visibility    int8   `json:"visibility" db:"visibility"`	// 0公开 1受限 2私有
 }
 
 /**
@@ -21,5 +22,46 @@ type CommunityDetail struct {
 	CommunityID   uint64    `json:"community_id" db:"community_id"`
 	CommunityName string    `json:"community_name" db:"community_name"`
 	Introduction  string    `json:"introduction,omitempty" db:"introduction"`	// omitempty 当Introduction为空时不展示
+	Status        int8      `json:"status" db:"status"`	// 1正常 0已归档
+	Visibility    int8      `json:"visibility" db:"visibility"`	// 0公开 1受限 2私有
+	SortOrder     int       `json:"sort_order" db:"sort_order"`
+	SlowMode      int       `json:"slow_mode" db:"slow_mode"`	// 慢速模式: 每个用户两次发帖的最小间隔(分钟), 0表示不限制
+	MemberCount   int64     `json:"member_count" db:"-"`	// 加入社区的用户数
 	CreateTime    time.Time `json:"create_time" db:"create_time"`
 }
+
+// 社区状态
+const (
+	CommunityStatusArchived int8 = 0 // 已归档: 只读, 不在列表中展示
+	CommunityStatusNormal   int8 = 1
+)
+
+// 社区的可见性
+const (
+	CommunityPublic     int8 = 0 // 公开: 所有人可以浏览和发帖
+	CommunityRestricted int8 = 1 // 受限: 所有人可以浏览, 只有通过审核的成员可以发帖
+	CommunityPrivate    int8 = 2 // 私有: 只有通过审核的成员可以浏览和发帖
+)
+
+// 社区成员状态, 加入受限及私有社区需要版主审核
+const (
+	MemberPending  int8 = 0
+	MemberApproved int8 = 1
+)
+
+// CommunityForm 创建社区/修改社区名称及简介
+type CommunityForm struct {
+	CommunityName string `json:"community_name" binding:"required,max=128"`
+	Introduction  string `json:"introduction" binding:"max=256"`
+	Visibility    int8   `json:"visibility" binding:"min=0,max=2"`	// 0公开 1受限 2私有
+}
+
+// SlowModeForm 设置社区的慢速模式, 0表示关闭, 最长一天
+type SlowModeForm struct {
+	Minutes int `json:"minutes" binding:"min=0,max=1440"`
+}
+
+// CommunityOrderForm 调整社区的排序, 按给定的顺序排列
+type CommunityOrderForm struct {
+	CommunityIDs []uint64 `json:"community_ids" binding:"required,min=1,dive,gt=0"`
+}
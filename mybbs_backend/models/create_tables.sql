@@ -5,12 +5,20 @@ CREATE TABLE `user` (
     `username` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
     `password` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
     `email` varchar(64) COLLATE utf8mb4_general_ci,
+    `nickname` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '昵称',
+    `bio` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '个人简介',
+    `avatar` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '头像地址,为空时使用自动生成的identicon',
     `gender` tinyint(4) NOT NULL DEFAULT '0',
+    `hide_nsfw` tinyint(1) NOT NULL DEFAULT '1' COMMENT '帖子列表中隐藏NSFW帖子',
+    `blur_spoiler` tinyint(1) NOT NULL DEFAULT '1' COMMENT '模糊显示剧透帖子',
+    `digest_frequency` tinyint(4) NOT NULL DEFAULT '0' COMMENT '热门帖子邮件摘要: 0不发送 1每天 2每周',
+    `digest_sent_time` timestamp NULL DEFAULT NULL COMMENT '上次发送邮件摘要的时间',
     `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
     `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_username` (`username`) USING BTREE,
-    UNIQUE KEY `idx_user_id` (`user_id`) USING BTREE
+    UNIQUE KEY `idx_user_id` (`user_id`) USING BTREE,
+    KEY `idx_digest` (`digest_frequency`, `user_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 
@@ -20,16 +28,20 @@ CREATE TABLE `community` (
   `community_id` int(10) unsigned NOT NULL,
   `community_name` varchar(128) COLLATE utf8mb4_general_ci NOT NULL,
   `introduction` varchar(256) COLLATE utf8mb4_general_ci NOT NULL,
+  `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '1正常 0已归档(只读,不在列表中展示)',
+  `visibility` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0公开 1受限(成员才能发帖) 2私有(成员才能浏览)',
+  `sort_order` int(11) NOT NULL DEFAULT '0' COMMENT '列表中的排序,越小越靠前',
+  `slow_mode` int(11) NOT NULL DEFAULT '0' COMMENT '慢速模式: 每个用户两次发帖的最小间隔(分钟), 0表示不限制',
   `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`id`),
   UNIQUE KEY `idx_community_id` (`community_id`),
   UNIQUE KEY `idx_community_name` (`community_name`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
-INSERT INTO `community` VALUES ('1', '1', 'Go', 'Golang', '2016-11-01 08:10:10', '2016-11-01 08:10:10');
-INSERT INTO `community` VALUES ('2', '2', 'leetcode', '刷题刷题刷题', '2020-01-01 08:00:00', '2020-01-01 08:00:00');
-INSERT INTO `community` VALUES ('3', '3', 'PUBG', '大吉大利，今晚吃鸡。', '2018-08-07 08:30:00', '2018-08-07 08:30:00');
-INSERT INTO `community` VALUES ('4', '4', 'LOL', '欢迎来到英雄联盟!', '2016-01-01 08:00:00', '2016-01-01 08:00:00');
+INSERT INTO `community`(`id`, `community_id`, `community_name`, `introduction`, `create_time`, `update_time`) VALUES ('1', '1', 'Go', 'Golang', '2016-11-01 08:10:10', '2016-11-01 08:10:10');
+INSERT INTO `community`(`id`, `community_id`, `community_name`, `introduction`, `create_time`, `update_time`) VALUES ('2', '2', 'leetcode', '刷题刷题刷题', '2020-01-01 08:00:00', '2020-01-01 08:00:00');
+INSERT INTO `community`(`id`, `community_id`, `community_name`, `introduction`, `create_time`, `update_time`) VALUES ('3', '3', 'PUBG', '大吉大利，今晚吃鸡。', '2018-08-07 08:30:00', '2018-08-07 08:30:00');
+INSERT INTO `community`(`id`, `community_id`, `community_name`, `introduction`, `create_time`, `update_time`) VALUES ('4', '4', 'LOL', '欢迎来到英雄联盟!', '2016-01-01 08:00:00', '2016-01-01 08:00:00');
 
 DROP TABLE IF EXISTS `post`;
 CREATE TABLE `post` (
@@ -39,7 +51,15 @@ CREATE TABLE `post` (
   `content` varchar(8192) COLLATE utf8mb4_general_ci NOT NULL COMMENT '内容',
   `author_id` bigint(20) NOT NULL COMMENT '作者的用户id',
   `community_id` bigint(20) NOT NULL COMMENT '所属社区',
-  `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '帖子状态',
+  `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '帖子状态: 1正常 0被版主删除',
+  `pinned` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否在社区内置顶',
+  `locked` tinyint(1) NOT NULL DEFAULT '0' COMMENT '锁定后不能再评论',
+  `tags` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '标签(json数组)',
+  `attachments` varchar(2048) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '附件地址(json数组)',
+  `fields` varchar(2048) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '发帖模板中的字段(json对象)',
+  `flair_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '社区定义的flair, 0表示没有',
+  `nsfw` tinyint(1) NOT NULL DEFAULT '0' COMMENT '不适合工作场合浏览',
+  `spoiler` tinyint(1) NOT NULL DEFAULT '0' COMMENT '包含剧透',
   `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
@@ -57,10 +77,296 @@ CREATE TABLE `comment` (
   `post_id` bigint(20) NOT NULL,
   `author_id` bigint(20) NOT NULL,
   `parent_id` bigint(20) NOT NULL DEFAULT '0',
-  `status` tinyint(3) unsigned NOT NULL DEFAULT '1',
+  `status` tinyint(3) unsigned NOT NULL DEFAULT '1' COMMENT '评论状态: 1正常 0被版主删除',
   `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`id`),
   UNIQUE KEY `idx_comment_id` (`comment_id`),
-  KEY `idx_author_Id` (`author_id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
\ No newline at end of file
+  KEY `idx_author_Id` (`author_id`),
+  KEY `idx_post_comment` (`post_id`,`comment_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `user_totp`;
+CREATE TABLE `user_totp` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `secret` varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'base32编码的TOTP密钥',
+  `enabled` tinyint(4) NOT NULL DEFAULT '0' COMMENT '验证过一次验证码后才启用',
+  `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+
+DROP TABLE IF EXISTS `user_recovery_code`;
+CREATE TABLE `user_recovery_code` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `code_hash` char(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT '恢复码的sha256',
+  `used` tinyint(4) NOT NULL DEFAULT '0',
+  `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_code` (`user_id`, `code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `user_identity`;
+CREATE TABLE `user_identity` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `issuer` varchar(255) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'OIDC身份提供方',
+  `subject` varchar(255) COLLATE utf8mb4_general_ci NOT NULL COMMENT '身份提供方中的用户标识(sub)',
+  `email` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+  `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_issuer_subject` (`issuer`,`subject`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `user_role`;
+CREATE TABLE `user_role` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `role` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'admin/moderator, 普通用户没有角色',
+  `community_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '版主所管理的社区, 全局角色为0',
+  `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_role` (`user_id`,`role`,`community_id`),
+  KEY `idx_community_id` (`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+-- 初始管理员需要手动授予: INSERT INTO `user_role`(`user_id`, `role`) VALUES (<user_id>, 'admin');
+
+DROP TABLE IF EXISTS `community_member`;
+CREATE TABLE `community_member` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `community_id` int(10) unsigned NOT NULL,
+  `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '1已通过 0待审核(受限及私有社区)',
+  `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_community` (`user_id`,`community_id`),
+  KEY `idx_community_id` (`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community_ban`;
+CREATE TABLE `community_ban` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `community_id` int(10) unsigned NOT NULL,
+  `user_id` bigint(20) NOT NULL COMMENT '被禁言的用户',
+  `moderator_id` bigint(20) NOT NULL COMMENT '执行禁言的版主',
+  `reason` varchar(255) COLLATE utf8mb4_general_ci NOT NULL,
+  `expire_time` timestamp NULL DEFAULT NULL COMMENT '禁言到期时间, NULL为永久',
+  `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_community_user` (`community_id`,`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community_rule`;
+CREATE TABLE `community_rule` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `community_id` int(10) unsigned NOT NULL,
+  `rule` text COLLATE utf8mb4_general_ci NOT NULL COMMENT '发帖规则及模板(json)',
+  `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_community_id` (`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+INSERT INTO `community_rule`(`community_id`, `rule`) VALUES ('2', '{"title_min":5,"title_max":64,"content_min":10,"required_tags":["数组","字符串","动态规划","图","其他"],"template":"## 思路\\n\\n## 代码\\n","fields":[{"name":"problem_link","label":"题目链接","rule":"required,url"},{"name":"difficulty","label":"难度","rule":"required,oneof=简单 中等 困难"}]}');
+
+DROP TABLE IF EXISTS `community_flair`;
+CREATE TABLE `community_flair` (
+  `flair_id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `community_id` int(10) unsigned NOT NULL,
+  `name` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+  `color` varchar(7) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '十六进制颜色, 如#ff4500',
+  `mod_only` tinyint(1) NOT NULL DEFAULT '0' COMMENT '只有版主可以使用',
+  `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`flair_id`),
+  UNIQUE KEY `idx_community_name` (`community_id`,`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `notification`;
+CREATE TABLE `notification` (
+  `notification_id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT '接收通知的用户',
+  `type` varchar(16) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'comment/reply/vote/mention',
+  `post_id` bigint(20) NOT NULL DEFAULT '0',
+  `comment_id` bigint(20) NOT NULL DEFAULT '0',
+  `actor_id` bigint(20) NOT NULL COMMENT '最近一次触发通知的用户',
+  `actor_count` int(11) NOT NULL DEFAULT '1' COMMENT '聚合的不同用户数',
+  `group_key` varchar(64) COLLATE utf8mb4_general_ci DEFAULT NULL COMMENT '未读时用于聚合同类通知, 已读后置为NULL',
+  `is_read` tinyint(1) NOT NULL DEFAULT '0',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`notification_id`),
+  UNIQUE KEY `idx_user_group` (`user_id`,`group_key`),
+  KEY `idx_user_read` (`user_id`,`is_read`,`update_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `notification_actor`;
+CREATE TABLE `notification_actor` (
+  `notification_id` bigint(20) NOT NULL,
+  `actor_id` bigint(20) NOT NULL,
+  PRIMARY KEY (`notification_id`,`actor_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='聚合通知中已计数的用户, 同一用户只计一次';
+
+DROP TABLE IF EXISTS `mention`;
+CREATE TABLE `mention` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL COMMENT '被提到的用户',
+  `author_id` bigint(20) NOT NULL,
+  `post_id` bigint(20) NOT NULL,
+  `comment_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '在帖子正文中提到时为0',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_post_comment_user` (`post_id`,`comment_id`,`user_id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `user_block`;
+CREATE TABLE `user_block` (
+  `user_id` bigint(20) NOT NULL,
+  `blocked_id` bigint(20) NOT NULL COMMENT '被屏蔽的用户',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`,`blocked_id`),
+  KEY `idx_blocked_id` (`blocked_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `conversation`;
+CREATE TABLE `conversation` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `conversation_id` bigint(20) NOT NULL,
+  `is_group` tinyint(1) NOT NULL DEFAULT '0',
+  `title` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '群聊名称',
+  `creator_id` bigint(20) NOT NULL,
+  `direct_key` varchar(64) COLLATE utf8mb4_general_ci DEFAULT NULL COMMENT '一对一会话的两个用户id(小的在前), 群聊为NULL',
+  `last_message_id` bigint(20) NOT NULL DEFAULT '0',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_conversation_id` (`conversation_id`),
+  UNIQUE KEY `idx_direct_key` (`direct_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `conversation_member`;
+CREATE TABLE `conversation_member` (
+  `conversation_id` bigint(20) NOT NULL,
+  `user_id` bigint(20) NOT NULL,
+  `last_read_message_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '已读回执: 读到的最后一条消息',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`conversation_id`,`user_id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `message`;
+CREATE TABLE `message` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `message_id` bigint(20) NOT NULL,
+  `conversation_id` bigint(20) NOT NULL,
+  `sender_id` bigint(20) NOT NULL,
+  `content` varchar(2048) COLLATE utf8mb4_general_ci NOT NULL,
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_message_id` (`message_id`),
+  KEY `idx_conversation_message` (`conversation_id`,`message_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `user_follow`;
+CREATE TABLE `user_follow` (
+  `user_id` bigint(20) NOT NULL COMMENT '关注者',
+  `followee_id` bigint(20) NOT NULL COMMENT '被关注的用户',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`,`followee_id`),
+  KEY `idx_followee_id` (`followee_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community_mute`;
+CREATE TABLE `community_mute` (
+  `user_id` bigint(20) NOT NULL,
+  `community_id` int(10) unsigned NOT NULL COMMENT '不想在列表及feed中看到的社区',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`,`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `keyword_mute`;
+CREATE TABLE `keyword_mute` (
+  `user_id` bigint(20) NOT NULL,
+  `keyword` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '标题或正文包含该关键词的帖子不在列表及feed中展示',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`,`keyword`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `bookmark_folder`;
+CREATE TABLE `bookmark_folder` (
+  `folder_id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `name` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`folder_id`),
+  UNIQUE KEY `idx_user_name` (`user_id`,`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `bookmark`;
+CREATE TABLE `bookmark` (
+  `user_id` bigint(20) NOT NULL,
+  `post_id` bigint(20) NOT NULL,
+  `folder_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '所在的收藏夹, 0表示未分类',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`,`post_id`),
+  KEY `idx_user_folder` (`user_id`,`folder_id`,`create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `post_read`;
+CREATE TABLE `post_read` (
+  `user_id` bigint(20) NOT NULL,
+  `post_id` bigint(20) NOT NULL,
+  `last_comment_id` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '读到的最后一条评论',
+  `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`,`post_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community_visit`;
+CREATE TABLE `community_visit` (
+  `user_id` bigint(20) NOT NULL,
+  `community_id` int(10) unsigned NOT NULL,
+  `last_visit` bigint(20) NOT NULL DEFAULT '0' COMMENT '最近一次浏览的时间(unix时间戳)',
+  `prev_visit` bigint(20) NOT NULL DEFAULT '0' COMMENT '上一次浏览的时间(unix时间戳), 之后发布的帖子标记为new',
+  `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`,`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `webhook`;
+CREATE TABLE `webhook` (
+  `webhook_id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `url` varchar(512) COLLATE utf8mb4_general_ci NOT NULL COMMENT '接收事件的地址',
+  `secret` varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT '签名密钥',
+  `event` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'post.created/comment.created/post.removed',
+  `community_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '只接收该社区的事件, 0表示所有社区',
+  `active` tinyint(1) NOT NULL DEFAULT '1' COMMENT '停用后不再投递新的事件',
+  `creator_id` bigint(20) NOT NULL,
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`webhook_id`),
+  KEY `idx_event_community` (`event`,`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `webhook_delivery`;
+CREATE TABLE `webhook_delivery` (
+  `delivery_id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `webhook_id` bigint(20) NOT NULL,
+  `event` varchar(32) COLLATE utf8mb4_general_ci NOT NULL,
+  `payload` text COLLATE utf8mb4_general_ci NOT NULL COMMENT '请求体(json)',
+  `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0待投递 1成功 2失败(重试次数用完)',
+  `attempts` int(11) NOT NULL DEFAULT '0' COMMENT '已投递的次数',
+  `next_attempt_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '下次投递的时间',
+  `response_code` int(11) NOT NULL DEFAULT '0' COMMENT '最后一次投递的响应状态码, 没有收到响应时为0',
+  `response_body` varchar(1024) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '最后一次投递的响应内容或错误信息',
+  `replay_of` bigint(20) NOT NULL DEFAULT '0' COMMENT '重新投递的原投递id',
+  `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`delivery_id`),
+  KEY `idx_webhook_id` (`webhook_id`,`delivery_id`),
+  KEY `idx_status_next` (`status`,`next_attempt_time`),
+  KEY `idx_replay_of` (`replay_of`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,39 @@
+package models
+
+// 热门帖子邮件摘要的发送频率
+const (
+	DigestOff    int8 = 0 // 不发送
+	DigestDaily  int8 = 1 // 每天
+	DigestWeekly int8 = 2 // 每周
+)
+
+// DigestSetting 用户的邮件摘要设置
+type DigestSetting struct {
+	Frequency int8 `json:"frequency" db:"digest_frequency" binding:"oneof=0 1 2"` // 0不发送 1每天 2每周
+}
+
+// DigestRecipient 待发送邮件摘要的用户
+type DigestRecipient struct {
+	UserID    uint64 `db:"user_id"`
+	Email     string `db:"email"`
+	NickName  string `db:"nickname"`
+	Frequency int8   `db:"digest_frequency"`
+}
+
+// DigestPost 邮件摘要中的一个帖子
+type DigestPost struct {
+	Title         string
+	Summary       string
+	URL           string
+	CommunityName string
+	AuthorName    string
+	VoteNum       int64
+}
+
+// Digest 渲染邮件摘要模板的数据
+type Digest struct {
+	NickName       string
+	Period         string // "今日"或"本周"
+	Posts          []*DigestPost
+	UnsubscribeURL string
+}
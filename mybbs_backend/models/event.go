@@ -0,0 +1,40 @@
+package models
+
+import "encoding/json"
+
+// 实时事件类型
+const (
+	EventNotification = "notification" // 新通知
+	EventComment      = "comment"      // 正在浏览的帖子有新评论
+	EventVote         = "vote"         // 正在浏览的帖子的投票数
+	EventMessage      = "message"      // 新私信
+	EventMessageRead  = "message_read" // 会话的其他成员已读
+)
+
+// Event 通过redis pub/sub在多个实例间分发的实时事件
+type Event struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// NotificationPush 推送的新通知, 客户端据此更新未读数角标
+type NotificationPush struct {
+	Type        string `json:"type"`
+	PostID      uint64 `json:"post_id,string"`
+	CommentID   uint64 `json:"comment_id,string"`
+	ActorID     uint64 `json:"actor_id,string"`
+	UnreadCount int64  `json:"unread_count"`
+}
+
+// VoteEvent 帖子的投票数变化
+type VoteEvent struct {
+	PostID  uint64 `json:"post_id,string"`
+	VoteNum int64  `json:"vote_num"`
+}
+
+// ReadEvent 会话成员的已读回执变化
+type ReadEvent struct {
+	ConversationID    uint64 `json:"conversation_id,string"`
+	UserID            uint64 `json:"user_id,string"`
+	LastReadMessageID uint64 `json:"last_read_message_id,string"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// Flair 社区定义的帖子标记, 如"求助"、"已解决"
+type Flair struct {
+	FlairID     uint64 `json:"flair_id" db:"flair_id"`
+	CommunityID uint64 `json:"community_id" db:"community_id"`
+	Name        string `json:"name" db:"name"`
+	Color       string `json:"color" db:"color"`
+	ModOnly     bool   `json:"mod_only" db:"mod_only"` // 只有版主可以使用
+}
+
+// FlairForm 创建/修改flair
+type FlairForm struct {
+	Name    string `json:"name" binding:"required,max=32"`
+	Color   string `json:"color" binding:"omitempty,hexcolor"`
+	ModOnly bool   `json:"mod_only"`
+}
+
+// PostFlairForm 修改帖子的flair, 0表示去掉flair
+type PostFlairForm struct {
+	FlairID uint64 `json:"flair_id"`
+}
+
+// PostFlagsForm 修改帖子的NSFW及剧透标记, 不传的字段保持不变
+type PostFlagsForm struct {
+	NSFW    *bool `json:"nsfw"`
+	Spoiler *bool `json:"spoiler"`
+}
+
+// Preferences 用户的浏览偏好
+type Preferences struct {
+	HideNSFW    bool `json:"hide_nsfw" db:"hide_nsfw"`       // 帖子列表中隐藏NSFW帖子
+	BlurSpoiler bool `json:"blur_spoiler" db:"blur_spoiler"` // 模糊显示剧透帖子
+}
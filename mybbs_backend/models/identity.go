@@ -0,0 +1,15 @@
+package models
+
+// UserIdentity 用户绑定的第三方(OIDC)账号, issuer+subject唯一确定一个外部账号
+type UserIdentity struct {
+	UserID  uint64 `db:"user_id"`
+	Issuer  string `db:"issuer"`
+	Subject string `db:"subject"`
+	Email   string `db:"email"`
+}
+
+// OIDCState 发起第三方登录时保存的一次性参数, 回调时用于校验
+type OIDCState struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
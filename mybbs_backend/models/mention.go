@@ -0,0 +1,9 @@
+package models
+
+// Mention 帖子或评论中提到的用户
+type Mention struct {
+	CommentID uint64 `json:"-" db:"comment_id"`
+	UserID    uint64 `json:"user_id,string" db:"user_id"`
+	UserName  string `json:"username" db:"username"`
+	Link      string `json:"link" db:"-"` // 用户主页地址
+}
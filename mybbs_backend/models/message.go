@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// MaxGroupMembers 群聊最多的成员数(包括创建者)
+const MaxGroupMembers = 10
+
+// Conversation 私信会话, 一对一或小群聊
+type Conversation struct {
+	ConversationID    uint64       `json:"conversation_id,string" db:"conversation_id"`
+	IsGroup           bool         `json:"is_group" db:"is_group"`
+	Title             string       `json:"title" db:"title"`
+	LastMessageID     uint64       `json:"-" db:"last_message_id"`
+	LastReadMessageID uint64       `json:"last_read_message_id,string" db:"last_read_message_id"` // 当前用户读到的最后一条消息
+	UnreadCount       int64        `json:"unread_count" db:"unread_count"`
+	UpdateTime        time.Time    `json:"update_time" db:"update_time"`
+	LastMessage       *Message     `json:"last_message" db:"-"`
+	Members           []*UserBrief `json:"members" db:"-"`
+}
+
+// Message 私信消息, id由snowflake生成, 按id排序即按时间排序
+type Message struct {
+	MessageID      uint64    `json:"message_id,string" db:"message_id"`
+	ConversationID uint64    `json:"conversation_id,string" db:"conversation_id"`
+	SenderID       uint64    `json:"sender_id,string" db:"sender_id"`
+	Content        string    `json:"content" db:"content"`
+	CreateTime     time.Time `json:"create_time" db:"create_time"`
+}
+
+// ReadReceipt 已读回执, 会话成员读到的最后一条消息
+type ReadReceipt struct {
+	UserID            uint64 `json:"user_id,string" db:"user_id"`
+	LastReadMessageID uint64 `json:"last_read_message_id,string" db:"last_read_message_id"`
+}
+
+// MessageHistory 会话的历史消息及成员的已读回执
+type MessageHistory struct {
+	Messages     []*Message     `json:"messages"`
+	ReadReceipts []*ReadReceipt `json:"read_receipts"`
+}
+
+// MessageForm 发送私信, 指定conversation_id时发到已有的会话, 否则发给recipient_id(一对一会话不存在时自动创建)
+type MessageForm struct {
+	ConversationID uint64 `json:"conversation_id,string"`
+	RecipientID    uint64 `json:"recipient_id,string"`
+	Content        string `json:"content" binding:"required,max=2000"`
+}
+
+// ConversationForm 创建群聊, user_ids不包括创建者
+type ConversationForm struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=9,dive,numeric"`
+	Title   string   `json:"title" binding:"max=64"`
+}
+
+// MessageReadForm 标记会话已读到指定的消息
+type MessageReadForm struct {
+	MessageID uint64 `json:"message_id,string" binding:"required"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// 帖子及评论状态
+const (
+	StatusRemoved int32 = 0 // 被版主删除
+	StatusNormal  int32 = 1
+)
+
+// CommunityBan 社区禁言记录
+type CommunityBan struct {
+	CommunityID uint64     `json:"community_id" db:"community_id"`
+	UserID      uint64     `json:"user_id,string" db:"user_id"`
+	ModeratorID uint64     `json:"moderator_id,string" db:"moderator_id"`
+	Reason      string     `json:"reason" db:"reason"`
+	ExpireTime  *time.Time `json:"expire_time" db:"expire_time"` // nil表示永久禁言
+	CreateTime  time.Time  `json:"create_time" db:"create_time"`
+}
+
+// BanForm 在社区内禁言用户
+type BanForm struct {
+	UserID   uint64 `json:"user_id,string" binding:"required"`
+	Reason   string `json:"reason" binding:"required,max=255"`
+	Duration int64  `json:"duration" binding:"min=0"` // 禁言时长(秒), 0表示永久
+}
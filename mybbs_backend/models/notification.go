@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotifyComment = "comment" // 评论了你的帖子
+	NotifyReply   = "reply"   // 回复了你的评论
+	NotifyVote    = "vote"    // 赞了你的帖子
+	NotifyMention = "mention" // 在帖子或评论中提到了你
+)
+
+// Notification 站内通知, 未读时同一帖子/评论上的同类通知聚合为一条
+type Notification struct {
+	NotificationID uint64     `json:"notification_id" db:"notification_id"`
+	UserID         uint64     `json:"-" db:"user_id"`
+	Type           string     `json:"type" db:"type"`
+	PostID         uint64     `json:"post_id,string" db:"post_id"`
+	CommentID      uint64     `json:"comment_id,string" db:"comment_id"`
+	ActorID        uint64     `json:"-" db:"actor_id"`
+	ActorCount     int64      `json:"actor_count" db:"actor_count"` // 聚合的不同用户数
+	Actor          *UserBrief `json:"actor" db:"-"`                 // 最近一次触发通知的用户
+	Message        string     `json:"message" db:"-"`
+	Read           bool       `json:"read" db:"is_read"`
+	UpdateTime     time.Time  `json:"update_time" db:"update_time"`
+}
+
+// NotificationEvent 触发通知的事件
+type NotificationEvent struct {
+	Type      string
+	UserID    uint64 // 接收通知的用户
+	ActorID   uint64 // 触发通知的用户
+	PostID    uint64
+	CommentID uint64
+	GroupKey  string // 聚合的依据, 为空时不聚合
+}
+
+// NotificationList 通知列表及未读数
+type NotificationList struct {
+	UnreadCount int64           `json:"unread_count"`
+	List        []*Notification `json:"list"`
+}
+
+// NotificationReadForm 标记通知为已读, ids为空时全部标记为已读
+type NotificationReadForm struct {
+	IDs []uint64 `json:"ids"`
+}
@@ -26,6 +26,7 @@ type ParamPostList struct {
 	Page  int64			`json:"page" form:"page"`				   // 页码
 	Size  int64			`json:"size" form:"size"`				   // 每页数量
 	Order string		`json:"order" form:"order" example:"score"`// 排序依据
+	FlairID uint64		`json:"flair_id" form:"flair_id"`		   // 按flair筛选, 需要同时指定社区
 }
 
 /**
@@ -17,8 +17,16 @@ type Post struct {
 	AuthorId    uint64    `json:"author_id" db:"author_id"`
 	CommunityID uint64     `json:"community_id" db:"community_id" binding:"required"`
 	Status      int32     `json:"status" db:"status"`
+	Pinned      bool      `json:"pinned" db:"pinned"`	// 在社区内置顶
+	Locked      bool      `json:"locked" db:"locked"`	// 锁定后不能再评论
 	Title       string    `json:"title" db:"title" binding:"required"`
 	Content     string    `json:"content" db:"content" binding:"required"`
+	Tags        StringList `json:"tags" db:"tags" binding:"max=5,dive,required,max=20"`
+	Attachments StringList `json:"attachments" db:"attachments" binding:"max=9,dive,required,url"`	// 附件地址
+	Fields      StringMap  `json:"fields" db:"fields" binding:"max=10"`	// 社区发帖模板中的字段
+	FlairID     uint64    `json:"flair_id" db:"flair_id"`	// 社区定义的flair, 0表示没有
+	NSFW        bool      `json:"nsfw" db:"nsfw"`
+	Spoiler     bool      `json:"spoiler" db:"spoiler"`
 	CreateTime  time.Time `json:"-" db:"create_time"`
 }
 
@@ -28,6 +36,12 @@ func (p *Post) UnmarshalJSON(data []byte) (err error) {
 		Title       string `json:"title" db:"title"`
 		Content     string `json:"content" db:"content"`
 		CommunityID int64  `json:"community_id" db:"community_id"`
+		Tags        []string          `json:"tags"`
+		Attachments []string          `json:"attachments"`
+		Fields      map[string]string `json:"fields"`
+		FlairID     uint64            `json:"flair_id"`
+		NSFW        bool              `json:"nsfw"`
+		Spoiler     bool              `json:"spoiler"`
 	}{}
 	err = json.Unmarshal(data, &required)
 	if err != nil {
@@ -42,6 +56,12 @@ func (p *Post) UnmarshalJSON(data []byte) (err error) {
 		p.Title = required.Title
 		p.Content = required.Content
 		p.CommunityID = uint64(required.CommunityID)
+		p.Tags = required.Tags
+		p.Attachments = required.Attachments
+		p.Fields = required.Fields
+		p.FlairID = required.FlairID
+		p.NSFW = required.NSFW
+		p.Spoiler = required.Spoiler
 	}
 	return
 }
@@ -55,6 +75,15 @@ type ApiPostDetail struct {
 	*Post		  // 嵌入帖子结构体
 	*CommunityDetail	`json:"community"`  // 嵌入社区信息
 	AuthorName    string `json:"author_name"`
+	Author        *UserBrief `json:"author,omitempty"`	// 作者的昵称及头像
 	VoteNum 	  int64  `json:"vote_num"`
+	Flair         *Flair `json:"flair,omitempty"`	// 帖子的flair
+	Blur          bool   `json:"blur"`	// 按用户设置需要模糊显示(NSFW或剧透)
+	Mentions        []*Mention `json:"mentions,omitempty"`	// 正文中提到的用户
+	RenderedContent string     `json:"rendered_content,omitempty"`	// 提到的用户渲染成链接后的正文
+	Saved           bool       `json:"saved"`	// 当前用户是否收藏了该帖子
+	SavedFolderID   uint64     `json:"saved_folder_id,omitempty"`	// 收藏所在的收藏夹, 0表示未分类
+	UnreadComments  int64      `json:"unread_comments"`	// 上次浏览之后的新评论数, 没有浏览过的帖子为0
+	New             bool       `json:"new"`	// 在上次浏览该社区之后发布
 	//CommunityName string `json:"community_name"`
 }
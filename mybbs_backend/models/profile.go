@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// UserProfile 用户的公开资料, 不包含邮箱等隐私信息
+type UserProfile struct {
+	UserID         uint64    `json:"user_id,string" db:"user_id"`
+	NickName       string    `json:"nickname" db:"nickname"`
+	Bio            string    `json:"bio" db:"bio"`
+	Avatar         string    `json:"avatar" db:"avatar"`
+	Gender         int8      `json:"gender" db:"gender"`
+	CreateTime     time.Time `json:"create_time" db:"create_time"`
+	Karma          int64     `json:"karma"`
+	PostCount      int64     `json:"post_count"`
+	CommentCount   int64     `json:"comment_count"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+	IsFollowing    bool      `json:"is_following"` // 当前用户是否关注了该用户
+}
+
+// UserBrief 帖子、评论等处展示的作者信息
+type UserBrief struct {
+	UserID   uint64 `json:"user_id,string" db:"user_id"`
+	NickName string `json:"nickname" db:"nickname"`
+	Avatar   string `json:"avatar" db:"avatar"`
+}
+
+// ProfileForm 修改个人资料
+type ProfileForm struct {
+	NickName string `json:"nickname" binding:"required,max=32"`
+	Bio      string `json:"bio" binding:"max=256"`
+}
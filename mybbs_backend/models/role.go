@@ -0,0 +1,15 @@
+package models
+
+// UserRole 用户的角色, 版主角色带有所管理的社区, 全局角色的community_id为0
+type UserRole struct {
+	UserID      uint64 `json:"user_id,string" db:"user_id"`
+	Role        string `json:"role" db:"role"`
+	CommunityID uint64 `json:"community_id" db:"community_id"`
+}
+
+// RoleForm 授予/撤销角色
+type RoleForm struct {
+	UserID      uint64 `json:"user_id,string" binding:"required"`
+	Role        string `json:"role" binding:"required,oneof=admin moderator"`
+	CommunityID uint64 `json:"community_id"`
+}
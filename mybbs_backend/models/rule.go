@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// CommunityRule 社区的发帖规则及发帖模板, 长度限制为0表示不限制
+type CommunityRule struct {
+	TitleMin               int              `json:"title_min" binding:"min=0"`
+	TitleMax               int              `json:"title_max" binding:"min=0,max=128"`
+	ContentMin             int              `json:"content_min" binding:"min=0"`
+	ContentMax             int              `json:"content_max" binding:"min=0,max=8192"`
+	RequiredTags           []string         `json:"required_tags" binding:"dive,required,max=20"`            // 帖子至少要带其中一个标签
+	AllowedAttachmentTypes []string         `json:"allowed_attachment_types" binding:"dive,required,max=10"` // 附件允许的扩展名, 为空不限制
+	Template               string           `json:"template" binding:"max=4096"`                             // 发帖模板, 客户端预填到内容中
+	Fields                 []*TemplateField `json:"fields" binding:"max=10,dive"`                            // 模板中需要填写的字段
+}
+
+// TemplateField 发帖模板中的字段, 如题目链接、难度
+type TemplateField struct {
+	Name  string `json:"name" binding:"required,max=32"`
+	Label string `json:"label" binding:"max=32"`
+	Rule  string `json:"rule" binding:"max=128"` // validator的校验规则, 如 required,url
+}
+
+// StringList 以json格式保存在数据库中的字符串列表
+type StringList []string
+
+// Value 实现driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]string(l))
+	return string(data), err
+}
+
+// Scan 实现sql.Scanner
+func (l *StringList) Scan(src interface{}) error {
+	return scanJSON(src, l)
+}
+
+// StringMap 以json格式保存在数据库中的字符串字典
+type StringMap map[string]string
+
+// Value 实现driver.Valuer
+func (m StringMap) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(map[string]string(m))
+	return string(data), err
+}
+
+// Scan 实现sql.Scanner
+func (m *StringMap) Scan(src interface{}) error {
+	return scanJSON(src, m)
+}
+
+func scanJSON(src interface{}, dst interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported json column type")
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Session 用户的设备会话, 每次登录对应一个会话
+type Session struct {
+	SessionID  string    `json:"session_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreateTime time.Time `json:"create_time"`
+	LastSeen   time.Time `json:"last_seen"`
+	Current    bool      `json:"current"` // 是否为发起本次请求的会话
+}
@@ -0,0 +1,27 @@
+package models
+
+// UserTOTP 用户的两步验证(TOTP)配置
+type UserTOTP struct {
+	UserID  uint64 `db:"user_id"`
+	Secret  string `db:"secret"`
+	Enabled bool   `db:"enabled"`
+}
+
+// TOTPEnrollment 开启两步验证时返回给客户端的密钥信息
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`  // 无法扫码时手动输入
+	URI    string `json:"uri"`     // otpauth URI
+	QRCode string `json:"qr_code"` // PNG二维码的data URI
+}
+
+// TOTPCodeForm 提交验证码
+type TOTPCodeForm struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// TwoFactorLoginForm 两步验证登录的第二步: 验证码和恢复码二选一
+type TwoFactorLoginForm struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code" binding:"required_without=Code"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// PostRead 用户在帖子中读到的最后一条评论
+type PostRead struct {
+	PostID        uint64 `db:"post_id"`
+	LastCommentID uint64 `db:"last_comment_id"`
+}
+
+// CommunityVisit 用户浏览社区的时间(unix时间戳)
+// 间隔较短的多次浏览算作同一次, 只更新last_visit, prev_visit为上一次浏览的时间
+type CommunityVisit struct {
+	CommunityID uint64 `db:"community_id"`
+	LastVisit   int64  `db:"last_visit"`
+	PrevVisit   int64  `db:"prev_visit"`
+}
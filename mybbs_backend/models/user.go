@@ -26,8 +26,9 @@ type User2 struct {
 	CreateTime int64  `json:"createTime" form:"createTime"` // 创建时间
 	UpdateTime int64  `json:"updateTime" form:"updateTime"` // 更新时间
 
-	AccessToken  string
-	RefreshToken string
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string // 开启了两步验证时, 登录第一步只返回临时凭证
 }
 
 // UnmarshalJSON 为User类型实现自定义的UnmarshalJSON方法
@@ -84,6 +85,16 @@ type LoginForm2 struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// LogoutForm 登出请求参数, 携带refresh token时一并吊销
+type LogoutForm struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshTokenForm 刷新token请求参数
+type RefreshTokenForm struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type UpdatePasswordForm struct {
 	Email       string `json:"email" binding:"required"`
 	OldPassword string `json:"old_password" binding:"required"`
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// webhook的事件类型
+const (
+	WebhookPostCreated    = "post.created"
+	WebhookCommentCreated = "comment.created"
+	WebhookPostRemoved    = "post.removed"
+)
+
+// webhook投递的状态
+const (
+	DeliveryPending int8 = 0 // 待投递, 包括等待重试
+	DeliverySuccess int8 = 1 // 成功
+	DeliveryFailed  int8 = 2 // 重试次数用完, 可以重新投递
+)
+
+// Webhook 管理员注册的接收事件的地址
+type Webhook struct {
+	WebhookID   uint64    `json:"webhook_id" db:"webhook_id"`
+	URL         string    `json:"url" db:"url"`
+	Secret      string    `json:"secret,omitempty" db:"secret"` // 只在创建时返回
+	Event       string    `json:"event" db:"event"`
+	CommunityID uint64    `json:"community_id" db:"community_id"` // 0表示所有社区(不含私有社区)
+	Active      bool      `json:"active" db:"active"`
+	CreatorID   uint64    `json:"creator_id,string" db:"creator_id"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
+
+// WebhookForm 创建/修改webhook
+type WebhookForm struct {
+	URL         string `json:"url" binding:"required,url,max=512"`
+	Event       string `json:"event" binding:"required,oneof=post.created comment.created post.removed"`
+	CommunityID uint64 `json:"community_id"`
+	Active      *bool  `json:"active"` // 不传时为启用
+}
+
+// WebhookDelivery 一次事件投递及最后一次投递的结果
+type WebhookDelivery struct {
+	DeliveryID      uint64          `json:"delivery_id" db:"delivery_id"`
+	WebhookID       uint64          `json:"webhook_id" db:"webhook_id"`
+	Event           string          `json:"event" db:"event"`
+	Payload         json.RawMessage `json:"payload" db:"payload"`
+	Status          int8            `json:"status" db:"status"`
+	Attempts        int             `json:"attempts" db:"attempts"`
+	NextAttemptTime time.Time       `json:"next_attempt_time" db:"next_attempt_time"`
+	ResponseCode    int             `json:"response_code" db:"response_code"`
+	ResponseBody    string          `json:"response_body" db:"response_body"`
+	ReplayOf        uint64          `json:"replay_of" db:"replay_of"`
+	CreateTime      time.Time       `json:"create_time" db:"create_time"`
+	UpdateTime      time.Time       `json:"update_time" db:"update_time"`
+}
+
+// WebhookPayload 投递的请求体
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookPost post.created及post.removed事件的数据
+type WebhookPost struct {
+	PostID      uint64 `json:"post_id,string"`
+	CommunityID uint64 `json:"community_id"`
+	AuthorID    uint64 `json:"author_id,string"`
+	Title       string `json:"title"`
+	Content     string `json:"content,omitempty"`
+}
+
+// WebhookComment comment.created事件的数据
+type WebhookComment struct {
+	CommentID   uint64 `json:"comment_id,string"`
+	PostID      uint64 `json:"post_id,string"`
+	ParentID    uint64 `json:"parent_id,string"`
+	CommunityID uint64 `json:"community_id"`
+	AuthorID    uint64 `json:"author_id,string"`
+	Content     string `json:"content"`
+}
@@ -0,0 +1,64 @@
+package identicon
+
+import (
+	"bytes"
+	"crypto/md5"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// 根据用户标识生成GitHub风格的默认头像: 5x5左右对称的色块
+
+const (
+	grid   = 5
+	margin = 1 // 四周留白的格数
+)
+
+var background = color.NRGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+// Generate 生成size*size像素的PNG头像, 同一个key总是生成同样的图片
+func Generate(key []byte, size int) ([]byte, error) {
+	sum := md5.Sum(key)
+	fg := color.NRGBA{R: sum[13], G: sum[14], B: sum[15], A: 0xff}
+	// 颜色太浅时与背景区分不开
+	if int(fg.R)+int(fg.G)+int(fg.B) > 600 {
+		fg.R, fg.G, fg.B = fg.R/2, fg.G/2, fg.B/2
+	}
+
+	cells := grid + 2*margin
+	if size < cells {
+		size = cells
+	}
+	cell := size / cells
+	offset := (size - cell*grid) / 2
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	for row := 0; row < grid; row++ {
+		for col := 0; col < (grid+1)/2; col++ {
+			// 每一格由哈希的一位决定是否着色, 右半边与左半边对称
+			bit := row*((grid+1)/2) + col
+			if sum[bit/8]>>(uint(bit)%8)&1 == 0 {
+				continue
+			}
+			fill(img, offset+col*cell, offset+row*cell, cell, fg)
+			fill(img, offset+(grid-1-col)*cell, offset+row*cell, cell, fg)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fill(img *image.NRGBA, x0, y0, n int, c color.NRGBA) {
+	for y := y0; y < y0+n; y++ {
+		for x := x0; x < x0+n; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package identicon
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate([]byte("1"), 140)
+	assert.Nil(t, err)
+	b, _ := Generate([]byte("1"), 140)
+	c, _ := Generate([]byte("2"), 140)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+
+	img, err := png.Decode(bytes.NewReader(a))
+	assert.Nil(t, err)
+	assert.Equal(t, 140, img.Bounds().Dx())
+	assert.Equal(t, 140, img.Bounds().Dy())
+
+	// 左右对称
+	for y := 0; y < 140; y++ {
+		for x := 0; x < 70; x++ {
+			assert.Equal(t, img.At(x, y), img.At(139-x, y))
+		}
+	}
+}
@@ -1,73 +1,198 @@
 package jwt
 
 import (
+	"bluebell_backend/pkg/snowflake"
 	"errors"
-	"github.com/spf13/viper"
+	"strconv"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+// token类型, 防止refresh token被当作access token使用
+const (
+	TokenTypeAccess    = "access"
+	TokenTypeRefresh   = "refresh"
+	TokenTypeChallenge = "2fa" // 开启两步验证的用户通过密码校验后拿到的临时凭证
+)
+
+// ChallengeExpireDuration 两步验证临时凭证的有效期
+const ChallengeExpireDuration = time.Minute * 5
+
 // MyClaims 自定义声明结构体并内嵌jwt.StandardClaims
 // jwt包自带的jwt.StandardClaims只包含了官方字段
 // 我们这里需要额外记录一个UserID字段，所以要自定义结构体
 // 如果想要保存更多信息，都可以添加到这个结构体中
 type MyClaims struct {
-	UserID uint64 `json:"user_id"`
-	Username string `json:"username"`
+	UserID   uint64   `json:"user_id"`
+	Username string   `json:"username"`
+	FamilyID string   `json:"fid,omitempty"`   // 所属的refresh token家族, 同一次登录轮换出的token属于同一个家族
+	Type     string   `json:"typ"`             // token类型 access/refresh
+	Roles    []string `json:"roles,omitempty"` // 用户的角色, 只有access token携带
 	jwt.StandardClaims
 }
-//定义Secret
-var mySecret = []byte("夏天夏天悄悄过去")
+var (
+	ErrorTokenRevoked        = errors.New("token已被吊销")
+	ErrorInvalidTokenType    = errors.New("token类型错误")
+	ErrorRefreshTokenInvalid = errors.New("refresh token无效")
+	ErrorRefreshTokenReused  = errors.New("refresh token被重复使用")
+	ErrorTokenOwner          = errors.New("token不属于当前用户")
+)
+
+// Device 登录设备信息, 每个refresh token家族对应一个设备会话
+type Device struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenStore token的服务端状态存储(吊销名单、refresh token家族),由dao层实现并在main中通过SetStore注入
+type TokenStore interface {
+	// Revoke 吊销指定jti的token, ttl为token剩余的有效期
+	Revoke(jti string, ttl time.Duration) error
+	// IsRevoked 判断token是否已被吊销: jti在吊销名单中, 或所属家族已被吊销
+	// 家族有效时顺带刷新该设备会话的最近活跃时间
+	IsRevoked(jti, familyID string) (bool, error)
+	// SaveRefresh 创建一个新的refresh token家族(即一个设备会话), jti为家族当前唯一有效的refresh token
+	SaveRefresh(familyID, jti string, userID uint64, device *Device, ttl time.Duration) error
+	// RotateRefresh 原子地把家族当前的refresh token由oldJti换成newJti
+	// 家族不存在或已吊销时返回ErrorRefreshTokenInvalid;
+	// oldJti已被轮换过(重复使用)时吊销整个家族并返回ErrorRefreshTokenReused
+	RotateRefresh(familyID, oldJti, newJti string, ttl time.Duration) error
+	// RevokeFamily 吊销整个家族
+	RevokeFamily(familyID string) error
+}
+
+var store TokenStore
+
+// SetStore 设置token的服务端存储, 未设置时不做吊销及轮换校验
+func SetStore(s TokenStore) {
+	store = s
+}
 
-func keyFunc(_ *jwt.Token) (i interface{}, err error) {
-	return mySecret, nil
+// RoleLoader 查询用户的角色, 由dao层实现并在main中通过SetRoleLoader注入
+type RoleLoader func(userID uint64) ([]string, error)
+
+var loadRoles RoleLoader
+
+// SetRoleLoader 设置角色的查询方式, 每次签发access token(登录及刷新)时重新查询
+// 因此角色变更最迟在下一次刷新token后生效
+func SetRoleLoader(l RoleLoader) {
+	loadRoles = l
+}
+
+// newTokenID 生成token的唯一标识jti(同时用作家族ID)
+func newTokenID() (string, error) {
+	id, err := snowflake.GetID()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(id, 10), nil
 }
 
 //定义JWT的过期时间
 const TokenExpireDuration = time.Hour * 2
 
+// accessExpire access token有效期
+func accessExpire() time.Duration {
+	return time.Duration(authCfg.JwtExpire) * time.Hour
+}
+
+// refreshExpire refresh token有效期, 每次轮换都会顺延
+func refreshExpire() time.Duration {
+	return time.Duration(authCfg.RefreshExpire) * time.Hour
+}
+
 /**
  * @Author huchao
  * @Description //TODO 生成JWT
  * @Date 9:42 2022/2/11
  **/
-// GenToken 生成access token 和 refresh token
-func GenToken(userID uint64,username string) (aToken, rToken string, err error) {
+// GenToken 生成access token 和 refresh token, 每次登录都会为该设备开启一个新的refresh token家族
+func GenToken(userID uint64,username string, device *Device) (aToken, rToken string, err error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return
+	}
+	aToken, rToken, rID, err := genTokenPair(userID, username, familyID)
+	if err != nil {
+		return
+	}
+	if store != nil {
+		if device == nil {
+			device = new(Device)
+		}
+		err = store.SaveRefresh(familyID, rID, userID, device, refreshExpire())
+	}
+	return
+}
+
+// genTokenPair 为指定家族签发一对token, 返回refresh token的jti
+func genTokenPair(userID uint64, username, familyID string) (aToken, rToken, rID string, err error) {
+	// 每个token都带上jti, 登出时据此在服务端吊销
+	aID, err := newTokenID()
+	if err != nil {
+		return
+	}
+	rID, err = newTokenID()
+	if err != nil {
+		return
+	}
+	var roles []string
+	if loadRoles != nil {
+		if roles, err = loadRoles(userID); err != nil {
+			return
+		}
+	}
+	now := time.Now()
 	// 创建一个我们自己的声明
 	c := MyClaims{
-		userID, // 自定义字段
-		"username",	// 自定义字段
-		jwt.StandardClaims{	// JWT规定的7个官方字段
-			ExpiresAt: time.Now().Add(
-				time.Duration(viper.GetInt("auth.jwt_expire")) * time.Hour).Unix(), // 过期时间
-			Issuer:    "bluebell",                                 // 签发人
+		UserID:   userID,   // 自定义字段
+		Username: username, // 自定义字段
+		FamilyID: familyID,
+		Type:     TokenTypeAccess,
+		Roles:    roles,
+		StandardClaims: jwt.StandardClaims{ // JWT规定的7个官方字段
+			Id:        aID,                               // token唯一标识
+			ExpiresAt: now.Add(accessExpire()).Unix(), // 过期时间
+			Issuer:    "bluebell",                        // 签发人
 		},
 	}
 	// 加密并获得完整的编码后的字符串token
-	aToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(mySecret)
+	aToken, err = sign(c)
+	if err != nil {
+		return
+	}
 
-	// refresh token 不需要存任何自定义数据
-	rToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		ExpiresAt: time.Now().Add(time.Second * 30).Unix(), // 过期时间
-		Issuer:    "bluebell",                              // 签发人
-	}).SignedString(mySecret)
+	// refresh token 携带用户及家族信息, 其是否有效以服务端存储为准
+	rc := MyClaims{
+		UserID:   userID,
+		Username: username,
+		FamilyID: familyID,
+		Type:     TokenTypeRefresh,
+		StandardClaims: jwt.StandardClaims{
+			Id:        rID,                                // token唯一标识
+			ExpiresAt: now.Add(refreshExpire()).Unix(), // 过期时间
+			Issuer:    "bluebell",                         // 签发人
+		},
+	}
 	// 使用指定的secret签名并获得完整的编码后的字符串token
+	rToken, err = sign(rc)
 	return
 }
 //GenToken 生成 Token
 func GenToken2(userID uint64, username string) (Token string, err error) {
 	// 创建一个我们自己的声明
 	c := MyClaims{
-		userID, 			// 自定义字段
-		"username",	// 自定义字段
-		jwt.StandardClaims{	// JWT规定的7个官方字段
+		UserID:   userID,     // 自定义字段
+		Username: "username", // 自定义字段
+		Type:     TokenTypeAccess,
+		StandardClaims: jwt.StandardClaims{ // JWT规定的7个官方字段
 			ExpiresAt: time.Now().Add(TokenExpireDuration).Unix(), // 过期时间
 			Issuer:    "bluebell",                                 // 签发人
 		},
 	}
 	// 加密并获得完整的编码后的字符串token
-	Token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(mySecret)
+	Token, err = sign(c)
 
 	// refresh token 不需要存任何自定义数据
 	//rToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
@@ -77,12 +202,52 @@ func GenToken2(userID uint64, username string) (Token string, err error) {
 	return
 }
 
+// GenChallengeToken 签发两步验证的临时凭证, 只能用于提交验证码换取正式token
+func GenChallengeToken(userID uint64, username string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	return sign(MyClaims{
+		UserID:   userID,
+		Username: username,
+		Type:     TokenTypeChallenge,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			ExpiresAt: time.Now().Add(ChallengeExpireDuration).Unix(),
+			Issuer:    "bluebell",
+		},
+	})
+}
+
+// ParseChallengeToken 解析两步验证的临时凭证, 凭证使用后应通过RevokeClaims作废
+func ParseChallengeToken(tokenString string) (claims *MyClaims, err error) {
+	claims, err = parseToken(tokenString, TokenTypeChallenge)
+	if err != nil {
+		return
+	}
+	err = checkRevoked(claims)
+	return
+}
+
 /**
  * @Author huchao
  * @Description //TODO 解析JWT
  * @Date 9:43 2022/2/11
  **/
+// ParseToken 解析并校验access token
 func ParseToken(tokenString string) (claims *MyClaims, err error) {
+	claims, err = parseToken(tokenString, TokenTypeAccess)
+	if err != nil {
+		return
+	}
+	// 校验token是否已被吊销(已登出)
+	err = checkRevoked(claims)
+	return
+}
+
+// parseToken 校验签名、有效期及token类型
+func parseToken(tokenString, typ string) (claims *MyClaims, err error) {
 	// 解析token
 	var token *jwt.Token
 	claims = new(MyClaims)
@@ -92,25 +257,92 @@ func ParseToken(tokenString string) (claims *MyClaims, err error) {
 	}
 	if !token.Valid { // 校验token
 		err = errors.New("invalid token")
+		return
+	}
+	if claims.Type != typ {
+		err = ErrorInvalidTokenType
 	}
 	return
 }
 
-// RefreshToken 刷新AccessToken
-func RefreshToken(aToken, rToken string) (newAToken, newRToken string, err error) {
-	// refresh token无效直接返回
-	if _, err = jwt.Parse(rToken, keyFunc); err != nil {
-		return
+// checkRevoked 查询服务端的吊销名单
+func checkRevoked(claims *MyClaims) error {
+	if store == nil || claims.Id == "" {
+		return nil
+	}
+	revoked, err := store.IsRevoked(claims.Id, claims.FamilyID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrorTokenRevoked
 	}
+	return nil
+}
 
-	// 从旧access token中解析出claims数据	解析出payload负载信息
-	var claims MyClaims
-	_, err = jwt.ParseWithClaims(aToken, &claims, keyFunc)
-	v, _ := err.(*jwt.ValidationError)
+// CheckRevoked 查询已经解析出来的token是否已被吊销(登出或设备会话被吊销), 已吊销时返回ErrorTokenRevoked
+// 用于长连接等只在建立时校验过token的场景
+func CheckRevoked(claims *MyClaims) error {
+	return checkRevoked(claims)
+}
+
+// RevokeToken 吊销userID的一个token(access token或refresh token)及其所属家族
+// token属于其他用户时返回ErrorTokenOwner, 不能吊销别人的会话
+func RevokeToken(tokenString string, userID uint64) error {
+	claims := new(MyClaims)
+	if _, err := jwt.ParseWithClaims(tokenString, claims, keyFunc); err != nil {
+		return err
+	}
+	if claims.UserID != userID {
+		return ErrorTokenOwner
+	}
+	return RevokeClaims(claims)
+}
+
+// RevokeClaims 吊销已经解析出来的token及其所属家族
+func RevokeClaims(claims *MyClaims) error {
+	if store == nil {
+		return nil
+	}
+	if claims.FamilyID != "" {
+		if err := store.RevokeFamily(claims.FamilyID); err != nil {
+			return err
+		}
+	}
+	if claims.Id == "" {
+		return nil
+	}
+	// 吊销名单只需保留到token自然过期为止
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	return store.Revoke(claims.Id, ttl)
+}
 
-	// 当access token是过期错误 并且 refresh token没有过期时就创建一个新的access token
-	if v.Errors == jwt.ValidationErrorExpired {
-		return GenToken(claims.UserID,claims.Username)
+// RefreshToken 使用refresh token换取新的一对token, 旧的refresh token随即失效(轮换)
+// 已被轮换过的refresh token再次使用说明可能被窃取, 整个家族都会被吊销
+func RefreshToken(rToken string) (newAToken, newRToken string, err error) {
+	claims, err := parseToken(rToken, TokenTypeRefresh)
+	if err != nil {
+		err = ErrorRefreshTokenInvalid
+		return
+	}
+	// refresh token或其家族已被吊销(已登出)
+	if err = checkRevoked(claims); err != nil {
+		if errors.Is(err, ErrorTokenRevoked) {
+			err = ErrorRefreshTokenInvalid
+		}
+		return
+	}
+	newAToken, newRToken, rID, err := genTokenPair(claims.UserID, claims.Username, claims.FamilyID)
+	if err != nil {
+		return
+	}
+	if store != nil {
+		if err = store.RotateRefresh(claims.FamilyID, claims.Id, rID, refreshExpire()); err != nil {
+			return "", "", err
+		}
 	}
 	return
 }
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"bluebell_backend/pkg/snowflake"
+	"bluebell_backend/settings"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	if err := snowflake.Init(1); err != nil {
+		panic(err)
+	}
+}
+
+// writeKeyPair 生成一对密钥并写入PEM文件, 返回私钥及公钥文件路径
+func writeKeyPair(t *testing.T, dir, name string, priv, pub interface{}) (string, string) {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey failed, err:%v", err)
+	}
+	pubDer, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed, err:%v", err)
+	}
+	privFile := filepath.Join(dir, name+".pem")
+	pubFile := filepath.Join(dir, name+".pub.pem")
+	_ = ioutil.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	_ = ioutil.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), 0644)
+	return privFile, pubFile
+}
+
+func testKeys(t *testing.T) (dir string, cfgs map[string]*settings.KeyConfig) {
+	dir, err := ioutil.TempDir("", "jwt_keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	cfgs = map[string]*settings.KeyConfig{
+		"hs": {Kid: "hs", Alg: AlgHS256, Secret: "test-secret-0123456789abcdef0123456789"},
+	}
+	rsaPriv, _ := writeKeyPair(t, dir, "rs", rsaKey, &rsaKey.PublicKey)
+	cfgs["rs"] = &settings.KeyConfig{Kid: "rs", Alg: AlgRS256, PrivateKeyFile: rsaPriv}
+	ecPriv, _ := writeKeyPair(t, dir, "es", ecKey, &ecKey.PublicKey)
+	cfgs["es"] = &settings.KeyConfig{Kid: "es", Alg: AlgES256, PrivateKeyFile: ecPriv}
+	edPriv, edPub := writeKeyPair(t, dir, "ed", edKey, edKey.Public())
+	cfgs["ed"] = &settings.KeyConfig{Kid: "ed", Alg: AlgEdDSA, PrivateKeyFile: edPriv}
+	cfgs["ed-pub"] = &settings.KeyConfig{Kid: "ed", Alg: AlgEdDSA, PublicKeyFile: edPub}
+	return
+}
+
+func initKeys(t *testing.T, signing string, kcs ...*settings.KeyConfig) {
+	err := Init(&settings.AuthConfig{JwtExpire: 1, RefreshExpire: 24, SigningKid: signing, Keys: kcs})
+	if err != nil {
+		t.Fatalf("Init failed, err:%v", err)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir, cfgs := testKeys(t)
+	defer os.RemoveAll(dir)
+
+	for _, kid := range []string{"hs", "rs", "es", "ed"} {
+		initKeys(t, kid, cfgs[kid])
+		aToken, rToken, err := GenToken(1, "alice", nil)
+		if err != nil {
+			t.Fatalf("%s: GenToken failed, err:%v", kid, err)
+		}
+		claims, err := ParseToken(aToken)
+		if err != nil {
+			t.Fatalf("%s: ParseToken failed, err:%v", kid, err)
+		}
+		assert.Equal(t, uint64(1), claims.UserID)
+		assert.Equal(t, "alice", claims.Username)
+		// refresh token不能当作access token使用
+		_, err = ParseToken(rToken)
+		assert.Equal(t, ErrorInvalidTokenType, err, kid)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir, cfgs := testKeys(t)
+	defer os.RemoveAll(dir)
+
+	// 旧密钥ed签发的token
+	initKeys(t, "ed", cfgs["ed"])
+	oldToken, _, err := GenToken(1, "alice", nil)
+	assert.Nil(t, err)
+
+	// 切换到新密钥rs, ed只保留公钥用于验签
+	initKeys(t, "rs", cfgs["rs"], cfgs["ed-pub"])
+	_, err = ParseToken(oldToken)
+	assert.Nil(t, err)
+	newToken, _, err := GenToken(1, "alice", nil)
+	assert.Nil(t, err)
+	_, err = ParseToken(newToken)
+	assert.Nil(t, err)
+
+	// 旧密钥移除后, 它签发的token失效
+	initKeys(t, "rs", cfgs["rs"])
+	_, err = ParseToken(oldToken)
+	assert.NotNil(t, err)
+}
+
+func TestOnlyPublicKeyCannotSign(t *testing.T) {
+	dir, cfgs := testKeys(t)
+	defer os.RemoveAll(dir)
+
+	err := Init(&settings.AuthConfig{SigningKid: "ed", Keys: []*settings.KeyConfig{cfgs["ed-pub"]}})
+	assert.Equal(t, ErrorNoSigningKey, err)
+}
+
+func TestAlgorithmMismatch(t *testing.T) {
+	dir, cfgs := testKeys(t)
+	defer os.RemoveAll(dir)
+
+	// 私钥与声明的算法不一致
+	_, err := loadKey(&settings.KeyConfig{Kid: "x", Alg: AlgES256, PrivateKeyFile: cfgs["rs"].PrivateKeyFile})
+	assert.NotNil(t, err)
+
+	// 示例配置中的占位值及过短的HS256密钥
+	_, err = loadKey(&settings.KeyConfig{Kid: "x", Alg: AlgHS256, Secret: "change-me-in-production"})
+	assert.NotNil(t, err)
+	_, err = loadKey(&settings.KeyConfig{Kid: "x", Alg: AlgHS256, Secret: "short"})
+	assert.NotNil(t, err)
+
+	// 用HS256伪造一个带有RS256密钥kid的token
+	initKeys(t, "rs", &settings.KeyConfig{Kid: "rs", Alg: AlgHS256, Secret: "forged-secret-0123456789abcdef0123456789"})
+	forged, _, err := GenToken(1, "mallory", nil)
+	assert.Nil(t, err)
+	initKeys(t, "rs", cfgs["rs"])
+	_, err = ParseToken(forged)
+	assert.NotNil(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	dir, cfgs := testKeys(t)
+	defer os.RemoveAll(dir)
+
+	initKeys(t, "hs", cfgs["hs"], cfgs["rs"], cfgs["es"], cfgs["ed"])
+	set := JWKS()
+	// HS256对称密钥不能公开
+	kty := map[string]JSONWebKey{}
+	for _, k := range set.Keys {
+		kty[k.Kid] = k
+	}
+	assert.Equal(t, 3, len(set.Keys))
+	assert.Equal(t, "RSA", kty["rs"].Kty)
+	assert.Equal(t, "AQAB", kty["rs"].E)
+	assert.Equal(t, "P-256", kty["es"].Crv)
+	assert.Equal(t, 43, len(kty["es"].X))
+	assert.Equal(t, "Ed25519", kty["ed"].Crv)
+	assert.Equal(t, AlgEdDSA, kty["ed"].Alg)
+}
+
+func TestRolesInAccessToken(t *testing.T) {
+	initKeys(t, "hs", &settings.KeyConfig{Kid: "hs", Alg: AlgHS256, Secret: "test-secret-0123456789abcdef0123456789"})
+	SetRoleLoader(func(userID uint64) ([]string, error) {
+		return []string{"moderator:3"}, nil
+	})
+	defer SetRoleLoader(nil)
+
+	aToken, rToken, err := GenToken(1, "alice", nil)
+	assert.Nil(t, err)
+	claims, err := ParseToken(aToken)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"moderator:3"}, claims.Roles)
+	rClaims, err := parseToken(rToken, TokenTypeRefresh)
+	assert.Nil(t, err)
+	assert.Nil(t, rClaims.Roles)
+}
+
+// memStore 只记录吊销的家族, 用于测试吊销, 其余方法未实现
+type memStore struct {
+	TokenStore
+	families map[string]bool
+}
+
+func (m *memStore) Revoke(jti string, ttl time.Duration) error { return nil }
+
+func (m *memStore) RevokeFamily(familyID string) error {
+	m.families[familyID] = true
+	return nil
+}
+
+func TestRevokeTokenOwner(t *testing.T) {
+	initKeys(t, "hs", &settings.KeyConfig{Kid: "hs", Alg: AlgHS256, Secret: "test-secret-0123456789abcdef0123456789"})
+	_, rToken, err := GenToken(1, "alice", nil)
+	assert.Nil(t, err)
+	rClaims, err := parseToken(rToken, TokenTypeRefresh)
+	assert.Nil(t, err)
+
+	m := &memStore{families: make(map[string]bool)}
+	SetStore(m)
+	defer SetStore(nil)
+
+	// 其他用户提交的refresh token不吊销
+	assert.Equal(t, ErrorTokenOwner, RevokeToken(rToken, 2))
+	assert.False(t, m.families[rClaims.FamilyID])
+
+	assert.Nil(t, RevokeToken(rToken, 1))
+	assert.True(t, m.families[rClaims.FamilyID])
+}
@@ -0,0 +1,256 @@
+package jwt
+
+import (
+	"bluebell_backend/settings"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrorUnknownKey     = errors.New("未知的签名密钥")
+	ErrorNoSigningKey   = errors.New("未配置签名密钥")
+	ErrorUnsupportedAlg = errors.New("不支持的签名算法")
+)
+
+// signingKey 一个签名/验签密钥, 只配置了公钥的密钥只能用于验签
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{} // HS256为[]byte, 其余为私钥
+	verifyKey interface{} // HS256为[]byte, 其余为公钥
+}
+
+var (
+	keys       = map[string]*signingKey{} // kid -> 密钥, 包含所有可用于验签的密钥
+	currentKey *signingKey                // 当前用于签发token的密钥
+	authCfg    = new(settings.AuthConfig)
+)
+
+// Init 根据配置加载签名密钥
+// 轮换密钥时先加入新密钥并切换signing_kid, 旧密钥保留到其签发的token全部过期后再移除
+func Init(cfg *settings.AuthConfig) (err error) {
+	loaded := make(map[string]*signingKey, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		k, err := loadKey(kc)
+		if err != nil {
+			return fmt.Errorf("load key %q failed, err:%v", kc.Kid, err)
+		}
+		loaded[k.kid] = k
+	}
+	cur, ok := loaded[cfg.SigningKid]
+	if !ok || cur.signKey == nil {
+		return ErrorNoSigningKey
+	}
+	keys, currentKey, authCfg = loaded, cur, cfg
+	return nil
+}
+
+// loadKey 加载一个密钥, 私钥/公钥均为PEM文件
+func loadKey(kc *settings.KeyConfig) (k *signingKey, err error) {
+	if kc.Kid == "" {
+		return nil, errors.New("kid不能为空")
+	}
+	k = &signingKey{kid: kc.Kid}
+	switch kc.Alg {
+	case AlgHS256:
+		if len(kc.Secret) == 0 {
+			return nil, errors.New("HS256密钥缺少secret")
+		}
+		if err := settings.CheckSecret(kc.Secret); err != nil {
+			return nil, err
+		}
+		k.method = jwt.SigningMethodHS256
+		k.signKey, k.verifyKey = []byte(kc.Secret), []byte(kc.Secret)
+		return k, nil
+	case AlgRS256:
+		k.method = jwt.SigningMethodRS256
+	case AlgES256:
+		k.method = jwt.SigningMethodES256
+	case AlgEdDSA:
+		k.method = SigningMethodEdDSA
+	default:
+		return nil, ErrorUnsupportedAlg
+	}
+
+	if kc.PrivateKeyFile != "" {
+		priv, err := readPrivateKey(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok || !matchAlg(kc.Alg, signer.Public()) {
+			return nil, fmt.Errorf("私钥类型与算法%s不匹配", kc.Alg)
+		}
+		k.signKey, k.verifyKey = priv, signer.Public()
+	}
+	if kc.PublicKeyFile != "" {
+		pub, err := readPublicKey(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if !matchAlg(kc.Alg, pub) {
+			return nil, fmt.Errorf("公钥类型与算法%s不匹配", kc.Alg)
+		}
+		k.verifyKey = pub
+	}
+	if k.verifyKey == nil {
+		return nil, errors.New("缺少private_key_file或public_key_file")
+	}
+	return k, nil
+}
+
+// matchAlg 校验公钥类型与算法是否一致
+func matchAlg(alg string, pub crypto.PublicKey) bool {
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		return alg == AlgRS256
+	case *ecdsa.PublicKey:
+		return alg == AlgES256 && p.Curve == elliptic.P256()
+	case ed25519.PublicKey:
+		return alg == AlgEdDSA
+	}
+	return false
+}
+
+func readPEM(filename string) (*pem.Block, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+	return block, nil
+}
+
+// readPrivateKey 读取PKCS8/PKCS1/SEC1格式的私钥
+func readPrivateKey(filename string) (interface{}, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// readPublicKey 读取PKIX/PKCS1格式的公钥
+func readPublicKey(filename string) (interface{}, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+// sign 使用当前签名密钥签发token, header中带上kid
+func sign(claims jwt.Claims) (string, error) {
+	if currentKey == nil {
+		return "", ErrorNoSigningKey
+	}
+	token := jwt.NewWithClaims(currentKey.method, claims)
+	token.Header["kid"] = currentKey.kid
+	return token.SignedString(currentKey.signKey)
+}
+
+// keyFunc 根据token header中的kid选择验签密钥, 并要求算法与密钥一致, 防止算法混淆攻击
+func keyFunc(token *jwt.Token) (i interface{}, err error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := keys[kid]
+	if !ok {
+		return nil, ErrorUnknownKey
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, ErrorUnsupportedAlg
+	}
+	return k.verifyKey, nil
+}
+
+// JSONWebKey JWKS中的一个公钥
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet JWKS
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS 导出所有非对称验签公钥, 供其他内部服务校验我们签发的token
+// HS256为对称密钥, 不能对外公开
+func JWKS() *JSONWebKeySet {
+	set := &JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, k := range keys {
+		jwk := JSONWebKey{Kid: k.kid, Alg: k.method.Alg(), Use: "sig"}
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64URL(pub.N.Bytes())
+			jwk.E = base64URL(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64URL(padBytes(pub.X.Bytes(), size))
+			jwk.Y = base64URL(padBytes(pub.Y.Bytes(), size))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64URL(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// padBytes EC坐标需要左侧补零到固定长度
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	out := make([]byte, size)
+	copy(out[size-len(b):], b)
+	return out
+}
@@ -0,0 +1,219 @@
+package mailer
+
+import (
+	"bluebell_backend/settings"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 发送邮件: Mailer接口, 通过SMTP发送的实现及测试用的Outbox
+
+var (
+	ErrorNotConfigured = errors.New("未配置邮件服务器")
+	ErrorNoRecipient   = errors.New("邮件没有收件人")
+)
+
+// Message 一封同时包含HTML及纯文本正文的邮件
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+	Headers map[string]string // 额外的邮件头, 例如List-Unsubscribe
+}
+
+// Mailer 发送邮件
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+var std Mailer
+
+// Init 根据配置初始化默认的Mailer, 未配置host时不发送邮件
+func Init(cfg *settings.MailConfig) error {
+	if cfg == nil || len(cfg.Host) == 0 {
+		std = nil
+		return nil
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return fmt.Errorf("邮件发件人无效: %v", err)
+	}
+	std = NewSMTPMailer(cfg)
+	return nil
+}
+
+// SetMailer 替换默认的Mailer, 测试时使用Outbox
+func SetMailer(m Mailer) {
+	std = m
+}
+
+// Enabled 是否配置了Mailer
+func Enabled() bool {
+	return std != nil
+}
+
+// Send 通过默认的Mailer发送邮件
+func Send(msg *Message) error {
+	if std == nil {
+		return ErrorNotConfigured
+	}
+	return std.Send(msg)
+}
+
+// SMTPMailer 通过SMTP服务器发送邮件, 服务器支持时使用STARTTLS
+type SMTPMailer struct {
+	cfg *settings.MailConfig
+	// sendMail 与smtp.SendMail相同, 测试时替换
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPMailer 创建SMTPMailer
+func NewSMTPMailer(cfg *settings.MailConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg, sendMail: smtp.SendMail}
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(msg *Message) error {
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return ErrorNoRecipient
+	}
+	data, err := msg.Bytes(m.cfg.From, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if len(m.cfg.Username) > 0 {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := m.cfg.Host + ":" + strconv.Itoa(m.cfg.Port)
+	return m.sendMail(addr, auth, from.Address, []string{to.Address}, data)
+}
+
+// Bytes 生成multipart/alternative格式的邮件原文
+func (msg *Message) Bytes(from string, date time.Time) ([]byte, error) {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, ErrorNoRecipient
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	headers := map[string]string{
+		"From":         sender.String(),
+		"To":           to.String(),
+		"Subject":      mime.BEncoding.Encode("UTF-8", msg.Subject),
+		"Date":         date.Format(time.RFC1123Z),
+		"Message-ID":   messageID(sender.Address),
+		"MIME-Version": "1.0",
+		"Content-Type": `multipart/alternative; boundary="` + w.Boundary() + `"`,
+	}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var head bytes.Buffer
+	for _, k := range keys {
+		head.WriteString(k + ": " + headers[k] + "\r\n")
+	}
+	head.WriteString("\r\n")
+	// 纯文本在前, 客户端优先显示最后一个能够显示的部分
+	if err := writePart(w, "text/plain", msg.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(w, "text/html", msg.HTML); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+// writePart 写入quoted-printable编码的正文
+func writePart(w *multipart.Writer, contentType, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID 生成随机的Message-ID, 域名取发件人地址的域名
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// Outbox 只把邮件保存在内存中的Mailer, 用于测试及本地开发
+type Outbox struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+// NewOutbox 创建Outbox
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+// Send 保存邮件
+func (o *Outbox) Send(msg *Message) error {
+	if len(msg.To) == 0 {
+		return ErrorNoRecipient
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages 已发送的邮件, 先发送的在前
+func (o *Outbox) Messages() []*Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*Message(nil), o.messages...)
+}
+
+// Reset 清空已发送的邮件
+func (o *Outbox) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = nil
+}
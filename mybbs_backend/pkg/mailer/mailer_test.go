@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bluebell_backend/settings"
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := &Message{
+		To:      "小明 <ming@example.com>",
+		Subject: "每周热门帖子",
+		HTML:    "<p>你好, 这是本周的热门帖子</p>",
+		Text:    "你好, 这是本周的热门帖子",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+	data, err := msg.Bytes("bluebell <noreply@example.com>", time.Unix(1600000000, 0))
+	assert.Nil(t, err)
+
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	assert.Nil(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	assert.Nil(t, err)
+	assert.Equal(t, msg.Subject, subject)
+	to, err := mail.ParseAddress(m.Header.Get("To"))
+	assert.Nil(t, err)
+	assert.Equal(t, "小明", to.Name)
+	assert.Equal(t, "ming@example.com", to.Address)
+	assert.Equal(t, "<https://example.com/unsubscribe>", m.Header.Get("List-Unsubscribe"))
+	assert.Contains(t, m.Header.Get("Message-ID"), "@example.com>")
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	r := multipart.NewReader(m.Body, params["boundary"])
+	bodies := make(map[string]string)
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		// multipart.Reader会自动解码quoted-printable的正文
+		b, err := ioutil.ReadAll(part)
+		assert.Nil(t, err)
+		bodies[part.Header.Get("Content-Type")] = string(b)
+	}
+	assert.Equal(t, msg.Text, bodies["text/plain; charset=UTF-8"])
+	assert.Equal(t, msg.HTML, bodies["text/html; charset=UTF-8"])
+
+	_, err = (&Message{Subject: "x"}).Bytes("noreply@example.com", time.Now())
+	assert.Equal(t, ErrorNoRecipient, err)
+}
+
+func TestSMTPMailer(t *testing.T) {
+	m := NewSMTPMailer(&settings.MailConfig{
+		Host:     "smtp.example.com",
+		Port:     587,
+		Username: "bluebell",
+		Password: "secret",
+		From:     "bluebell <noreply@example.com>",
+	})
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotAuth smtp.Auth
+	m.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotTo = addr, a, from, to
+		return nil
+	}
+	err := m.Send(&Message{To: "小明 <ming@example.com>", Subject: "hi", Text: "hi", HTML: "<p>hi</p>"})
+	assert.Nil(t, err)
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.NotNil(t, gotAuth)
+	assert.Equal(t, "noreply@example.com", gotFrom)
+	assert.Equal(t, []string{"ming@example.com"}, gotTo)
+
+	assert.Equal(t, ErrorNoRecipient, m.Send(&Message{To: "not an address"}))
+}
+
+func TestOutbox(t *testing.T) {
+	o := NewOutbox()
+	SetMailer(o)
+	defer SetMailer(nil)
+	assert.True(t, Enabled())
+	assert.Nil(t, Send(&Message{To: "a@example.com", Subject: "1"}))
+	assert.Nil(t, Send(&Message{To: "b@example.com", Subject: "2"}))
+	assert.Equal(t, ErrorNoRecipient, Send(&Message{Subject: "3"}))
+
+	msgs := o.Messages()
+	assert.Equal(t, 2, len(msgs))
+	assert.Equal(t, "a@example.com", msgs[0].To)
+	assert.Equal(t, "2", msgs[1].Subject)
+	o.Reset()
+	assert.Equal(t, 0, len(o.Messages()))
+
+	SetMailer(nil)
+	assert.False(t, Enabled())
+	assert.Equal(t, ErrorNotConfigured, Send(&Message{To: "a@example.com"}))
+}
@@ -0,0 +1,43 @@
+package mention
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 解析及渲染内容中的@用户名
+// @前面不能是邮箱地址中可能出现的ASCII字符, 避免把邮箱地址当成提到用户; 中文内容中的@前面可以直接是汉字
+
+// pattern 第一个分组是@前面的字符, 第二个分组是用户名
+var pattern = regexp.MustCompile(`(^|[^A-Za-z0-9_.+-])@([\p{L}\p{N}_-]{1,64})`)
+
+// Parse 按出现的顺序取出内容中提到的用户名, 去重后最多返回max个, max<=0表示不限制
+func Parse(content string, max int) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range pattern.FindAllStringSubmatch(content, -1) {
+		name := m[2]
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		if max > 0 && len(names) >= max {
+			break
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// Render 把提到的用户名渲染成markdown格式的链接, link返回用户主页的地址, 不存在的用户返回空字符串保持原样
+func Render(content string, link func(name string) string) string {
+	return pattern.ReplaceAllStringFunc(content, func(s string) string {
+		m := pattern.FindStringSubmatch(s)
+		url := link(m[2])
+		if len(url) == 0 {
+			return s
+		}
+		return fmt.Sprintf("%s[@%s](%s)", m[1], m[2], url)
+	})
+}
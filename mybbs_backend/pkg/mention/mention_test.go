@@ -0,0 +1,28 @@
+package mention
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert.Equal(t, []string{"alice", "张三", "bob_1"}, Parse("@alice 你好 @张三,还有@bob_1。", 0))
+	// 邮箱地址不是提到用户
+	assert.Equal(t, []string{}, Parse("联系 a@example.com", 0))
+	// 重复的用户名只取一次, 不区分大小写
+	assert.Equal(t, []string{"alice"}, Parse("@alice @Alice @alice", 0))
+	// 超过上限的忽略
+	assert.Equal(t, []string{"a", "b"}, Parse("@a @b @c @d", 2))
+}
+
+func TestRender(t *testing.T) {
+	link := func(name string) string {
+		if name == "alice" {
+			return "/user/1"
+		}
+		return ""
+	}
+	assert.Equal(t, "hi [@alice](/user/1) and @nobody", Render("hi @alice and @nobody", link))
+	assert.Equal(t, "[@alice](/user/1)", Render("@alice", link))
+}
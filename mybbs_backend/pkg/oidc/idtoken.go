@@ -0,0 +1,254 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// clockSkew 校验exp/iat时允许的时钟误差
+const clockSkew = time.Minute
+
+// jwksRefreshInterval 遇到未知kid时重新拉取JWKS的最小间隔, 防止被恶意token打爆身份提供方
+const jwksRefreshInterval = time.Minute
+
+// audience aud可以是字符串也可以是字符串数组
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IDToken 校验通过的ID Token中的声明
+type IDToken struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	ExpiresAt       int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   bool     `json:"email_verified"`
+	Name            string   `json:"name"`
+	Picture         string   `json:"picture"`
+}
+
+// Valid 实现jwt.Claims, 只校验时间, 其余声明在VerifyIDToken中校验
+func (t *IDToken) Valid() error {
+	now := time.Now()
+	if t.ExpiresAt == 0 || now.Add(-clockSkew).Unix() > t.ExpiresAt {
+		return errors.New("token已过期")
+	}
+	if t.IssuedAt != 0 && now.Add(clockSkew).Unix() < t.IssuedAt {
+		return errors.New("token签发时间晚于当前时间")
+	}
+	return nil
+}
+
+// VerifyIDToken 校验ID Token的签名、issuer、audience、有效期及nonce
+func (p *Provider) VerifyIDToken(raw, nonce string) (*IDToken, error) {
+	if _, err := p.discover(); err != nil {
+		return nil, err
+	}
+	claims := new(IDToken)
+	if _, err := jwt.ParseWithClaims(raw, claims, p.keys.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidIDToken, err)
+	}
+	if claims.Issuer != p.metadata.Issuer {
+		return nil, fmt.Errorf("%w: issuer不匹配", ErrorInvalidIDToken)
+	}
+	if len(claims.Subject) == 0 {
+		return nil, fmt.Errorf("%w: 缺少sub", ErrorInvalidIDToken)
+	}
+	if !claims.Audience.contains(p.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: audience不匹配", ErrorInvalidIDToken)
+	}
+	// 有多个audience时azp必须是我们自己
+	if len(claims.Audience) > 1 || len(claims.AuthorizedParty) > 0 {
+		if claims.AuthorizedParty != p.cfg.ClientID {
+			return nil, fmt.Errorf("%w: azp不匹配", ErrorInvalidIDToken)
+		}
+	}
+	if claims.Nonce != nonce {
+		return nil, ErrorNonceMismatch
+	}
+	return claims, nil
+}
+
+// keySet 身份提供方的验签公钥, 按kid缓存, 身份提供方轮换密钥后自动重新拉取
+type keySet struct {
+	p   *Provider
+	uri string
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+func newKeySet(p *Provider, uri string) *keySet {
+	return &keySet{p: p, uri: uri}
+}
+
+// keyFunc 只接受RSA/ECDSA签名, 拒绝none及HS*, 防止算法混淆攻击
+func (s *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := s.get(kid)
+	if err != nil {
+		return nil, err
+	}
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := key.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("不支持的签名算法%s", token.Method.Alg())
+}
+
+// get 按kid查找公钥, 未命中时(最多每分钟一次)重新拉取JWKS
+func (s *keySet) get(kid string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	if !s.fetchedAt.IsZero() && time.Since(s.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("未知的kid %q", kid)
+	}
+	if err := s.fetch(); err != nil {
+		return nil, err
+	}
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未知的kid %q", kid)
+}
+
+// lookup 未指定kid时只有一个公钥才能使用
+func (s *keySet) lookup(kid string) (interface{}, bool) {
+	if len(kid) == 0 && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (s *keySet) fetch() error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	s.fetchedAt = time.Now()
+	if err := s.p.getJSON(s.uri, "", &set); err != nil {
+		return fmt.Errorf("获取JWKS失败: %v", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue // 忽略我们不支持的密钥
+		}
+		keys[k.Kid] = key
+	}
+	s.keys = keys
+	return nil
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线%s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC公钥不在曲线上")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("不支持的密钥类型%s", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("空的密钥参数")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,258 @@
+package oidc
+
+import (
+	"bluebell_backend/settings"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 通用的OpenID Connect客户端: 服务发现、授权码+PKCE、ID Token校验
+
+var (
+	ErrorNotConfigured   = errors.New("未配置OIDC登录")
+	ErrorDiscovery       = errors.New("OIDC服务发现失败")
+	ErrorExchange        = errors.New("授权码换取token失败")
+	ErrorInvalidIDToken  = errors.New("ID Token无效")
+	ErrorNonceMismatch   = errors.New("ID Token的nonce不匹配")
+	ErrorSubjectMismatch = errors.New("用户信息与ID Token不属于同一用户")
+)
+
+// Metadata 服务发现文档(/.well-known/openid-configuration)中我们用到的字段
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// Token 授权码换取到的token
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+}
+
+// UserInfo userinfo接口返回的用户信息
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+// Provider 一个OIDC身份提供方, 服务发现文档在第一次使用时获取并缓存
+type Provider struct {
+	cfg    *settings.OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *keySet
+}
+
+var provider *Provider
+
+// Init 根据配置初始化默认的身份提供方, 未配置issuer时不开启OIDC登录
+func Init(cfg *settings.OIDCConfig) error {
+	if cfg == nil || len(cfg.Issuer) == 0 {
+		provider = nil
+		return nil
+	}
+	if len(cfg.ClientID) == 0 || len(cfg.RedirectURL) == 0 {
+		return errors.New("OIDC缺少client_id或redirect_url")
+	}
+	provider = NewProvider(cfg, nil)
+	return nil
+}
+
+// Default 返回默认的身份提供方, 未开启OIDC登录时返回ErrorNotConfigured
+func Default() (*Provider, error) {
+	if provider == nil {
+		return nil, ErrorNotConfigured
+	}
+	return provider, nil
+}
+
+// NewProvider 创建一个身份提供方, client为nil时使用带超时的默认client
+func NewProvider(cfg *settings.OIDCConfig, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Issuer 身份提供方的标识, 与sub一起唯一确定一个外部账号
+func (p *Provider) Issuer() string {
+	return strings.TrimSuffix(p.cfg.Issuer, "/")
+}
+
+// discover 获取并缓存服务发现文档, 失败时下次再重试
+func (p *Provider) discover() (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	m := new(Metadata)
+	if err := p.getJSON(p.Issuer()+"/.well-known/openid-configuration", "", m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorDiscovery, err)
+	}
+	// 防止被伪造的发现文档冒充其他issuer
+	if strings.TrimSuffix(m.Issuer, "/") != p.Issuer() {
+		return nil, fmt.Errorf("%w: issuer %q不匹配", ErrorDiscovery, m.Issuer)
+	}
+	if len(m.AuthorizationEndpoint) == 0 || len(m.TokenEndpoint) == 0 || len(m.JWKSURI) == 0 {
+		return nil, fmt.Errorf("%w: 缺少必要的endpoint", ErrorDiscovery)
+	}
+	p.metadata = m
+	p.keys = newKeySet(p, m.JWKSURI)
+	return m, nil
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	m, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	v := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange 使用授权码及PKCE verifier换取token
+func (p *Provider) Exchange(code, codeVerifier string) (*Token, error) {
+	m, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// 公开客户端(只用PKCE)不需要client_secret
+	if len(p.cfg.ClientSecret) > 0 {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorExchange, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorExchange, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		_ = json.Unmarshal(body, &e)
+		return nil, fmt.Errorf("%w: status %d %s %s", ErrorExchange, resp.StatusCode, e.Error, e.Description)
+	}
+	t := new(Token)
+	if err := json.Unmarshal(body, t); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorExchange, err)
+	}
+	if len(t.IDToken) == 0 {
+		return nil, fmt.Errorf("%w: 响应中没有id_token", ErrorExchange)
+	}
+	return t, nil
+}
+
+// UserInfo 使用access token查询用户信息, 并要求与ID Token属于同一用户
+func (p *Provider) UserInfo(accessToken, subject string) (*UserInfo, error) {
+	m, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.UserinfoEndpoint) == 0 {
+		return nil, errors.New("身份提供方不支持userinfo")
+	}
+	info := new(UserInfo)
+	if err := p.getJSON(m.UserinfoEndpoint, accessToken, info); err != nil {
+		return nil, err
+	}
+	if info.Subject != subject {
+		return nil, ErrorSubjectMismatch
+	}
+	return info, nil
+}
+
+// getJSON GET请求并解析JSON响应
+func (p *Provider) getJSON(u, bearer string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(bearer) > 0 {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// RandomString 生成URL安全的随机字符串, 用作state/nonce/PKCE verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge 计算PKCE S256的code_challenge
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,258 @@
+package oidc
+
+import (
+	"bluebell_backend/settings"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockProvider 本地模拟的身份提供方, 实现服务发现、JWKS、授权码换token及userinfo
+type mockProvider struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	kid      string
+	clientID string
+
+	// 授权码 -> 授权请求中的code_challenge、nonce
+	codes map[string]url.Values
+	// 修改即将签发的ID Token
+	mutate func(claims jwt.MapClaims)
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key, kid: "mock-1", clientID: "bluebell", codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Metadata{
+			Issuer:                m.URL,
+			AuthorizationEndpoint: m.URL + "/authorize",
+			TokenEndpoint:         m.URL + "/token",
+			UserinfoEndpoint:      m.URL + "/userinfo",
+			JWKSURI:               m.URL + "/jwks",
+			CodeChallengeMethods:  []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := m.key.PublicKey
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jsonWebKey{{
+			Kty: "RSA",
+			Kid: m.kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		auth, ok := m.codes[r.PostForm.Get("code")]
+		if !ok || CodeChallenge(r.PostForm.Get("code_verifier")) != auth.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(m.codes, r.PostForm.Get("code"))
+		_ = json.NewEncoder(w).Encode(Token{
+			AccessToken: "mock-access-token",
+			TokenType:   "Bearer",
+			IDToken:     m.idToken(t, auth.Get("nonce")),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mock-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(UserInfo{Subject: "user-1", Email: "alice@example.com", EmailVerified: true})
+	})
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+// authorize 模拟用户在身份提供方同意授权, 返回回调时带回的授权码
+func (m *mockProvider) authorize(t *testing.T, authURL string) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	assert.Equal(t, m.clientID, q.Get("client_id"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	code = "code-" + q.Get("state")
+	m.codes[code] = q
+	return code, q.Get("state")
+}
+
+func (m *mockProvider) idToken(t *testing.T, nonce string) string {
+	claims := jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            "user-1",
+		"aud":            m.clientID,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+	}
+	if m.mutate != nil {
+		m.mutate(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	s, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(&settings.OIDCConfig{
+		Issuer:      m.URL,
+		ClientID:    m.clientID,
+		RedirectURL: "http://127.0.0.1/callback",
+	}, m.Client())
+}
+
+// login 走一遍完整的授权码+PKCE流程
+func login(t *testing.T, m *mockProvider, p *Provider) (*Token, *IDToken, error) {
+	state, _ := RandomString()
+	nonce, _ := RandomString()
+	verifier, _ := RandomString()
+	authURL, err := p.AuthCodeURL(state, nonce, CodeChallenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, gotState := m.authorize(t, authURL)
+	assert.Equal(t, state, gotState)
+	token, err := p.Exchange(code, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToken, err := p.VerifyIDToken(token.IDToken, nonce)
+	return token, idToken, err
+}
+
+func TestLogin(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.Close()
+	p := m.provider()
+
+	token, idToken, err := login(t, m, p)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-1", idToken.Subject)
+	assert.Equal(t, "alice@example.com", idToken.Email)
+	assert.True(t, idToken.EmailVerified)
+	assert.Equal(t, m.URL, p.Issuer())
+
+	info, err := p.UserInfo(token.AccessToken, idToken.Subject)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice@example.com", info.Email)
+	_, err = p.UserInfo(token.AccessToken, "user-2")
+	assert.Equal(t, ErrorSubjectMismatch, err)
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.Close()
+	p := m.provider()
+
+	state, _ := RandomString()
+	verifier, _ := RandomString()
+	authURL, _ := p.AuthCodeURL(state, "nonce", CodeChallenge(verifier))
+	code, _ := m.authorize(t, authURL)
+	_, err := p.Exchange(code, "wrong-verifier")
+	assert.ErrorIs(t, err, ErrorExchange)
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	cases := map[string]func(claims jwt.MapClaims){
+		"expired":      func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"issuer":       func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"audience":     func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"azp":          func(c jwt.MapClaims) { c["aud"] = []string{"bluebell", "other-client"} },
+		"nonce":        func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+		"empty sub":    func(c jwt.MapClaims) { c["sub"] = "" },
+		"future token": func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+	}
+	for name, mutate := range cases {
+		m := newMockProvider(t)
+		m.mutate = mutate
+		_, _, err := login(t, m, m.provider())
+		assert.NotNil(t, err, name)
+		m.Close()
+	}
+}
+
+func TestVerifyIDTokenForged(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.Close()
+	p := m.provider()
+	if _, err := p.discover(); err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{
+		"iss": m.URL, "sub": "user-1", "aud": m.clientID,
+		"exp": time.Now().Add(time.Minute).Unix(), "nonce": "n",
+	}
+
+	// 用其他密钥签名
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	forged, _ := token.SignedString(other)
+	_, err := p.VerifyIDToken(forged, "n")
+	assert.ErrorIs(t, err, ErrorInvalidIDToken)
+
+	// 用HS256冒充, 以公钥作为secret
+	token = jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = m.kid
+	forged, _ = token.SignedString(m.key.PublicKey.N.Bytes())
+	_, err = p.VerifyIDToken(forged, "n")
+	assert.ErrorIs(t, err, ErrorInvalidIDToken)
+}
+
+func TestKeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.Close()
+	p := m.provider()
+	_, _, err := login(t, m, p)
+	assert.Nil(t, err)
+
+	// 身份提供方换了新密钥, 过了最小刷新间隔后重新拉取JWKS
+	m.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	m.kid = "mock-2"
+	p.keys.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	_, _, err = login(t, m, p)
+	assert.Nil(t, err)
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.Close()
+	p := NewProvider(&settings.OIDCConfig{Issuer: m.URL + "/other", ClientID: "bluebell"}, m.Client())
+	_, err := p.AuthCodeURL("s", "n", "c")
+	assert.ErrorIs(t, err, ErrorDiscovery)
+}
+
+func TestInit(t *testing.T) {
+	assert.Nil(t, Init(nil))
+	_, err := Default()
+	assert.Equal(t, ErrorNotConfigured, err)
+	assert.NotNil(t, Init(&settings.OIDCConfig{Issuer: "https://idp.example.com"}))
+}
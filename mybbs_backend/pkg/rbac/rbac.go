@@ -0,0 +1,100 @@
+package rbac
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 基于角色的权限控制
+// 角色保存在MySQL的user_role表中, 登录时写入access token的roles声明
+// 版主角色按社区授予, 在token中编码为"moderator:<community_id>"
+
+// 角色, 普通用户没有任何角色
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// 权限
+const (
+	PermManageRoles     = "role:manage"      // 授予/撤销角色
+	PermManageCommunity = "community:manage" // 创建、修改、删除社区
+	PermModeratePost    = "post:moderate"    // 删除、置顶、锁定帖子
+	PermModerateComment = "comment:moderate" // 删除评论
+	PermBanUser         = "user:ban"         // 在社区内禁言用户
+	PermApproveMember   = "member:approve"   // 审核受限及私有社区的成员, 浏览私有社区
+	PermManageRules     = "rule:manage"      // 修改社区的发帖规则及模板
+	PermManageWebhooks  = "webhook:manage"   // 注册webhook及查看、重新投递投递记录
+)
+
+// rolePermissions 每个角色拥有的权限, 管理员拥有全部权限
+var rolePermissions = map[string][]string{
+	RoleModerator: {PermModeratePost, PermModerateComment, PermBanUser, PermApproveMember, PermManageRules},
+}
+
+// IsValidRole 是否为可以授予的角色
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleModerator
+}
+
+// Encode 把角色及其作用的社区编码成token中的字符串, communityID为0表示全局角色
+func Encode(role string, communityID uint64) string {
+	if communityID == 0 {
+		return role
+	}
+	return role + ":" + strconv.FormatUint(communityID, 10)
+}
+
+// Decode 解析token中的角色字符串
+func Decode(s string) (role string, communityID uint64) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return s, 0
+	}
+	communityID, err := strconv.ParseUint(s[i+1:], 10, 64)
+	if err != nil {
+		return s[:i], 0
+	}
+	return s[:i], communityID
+}
+
+// HasRole 是否拥有指定角色(任意社区); 管理员视为拥有所有角色
+func HasRole(roles []string, role string) bool {
+	for _, s := range roles {
+		r, cid := Decode(s)
+		if (r == RoleAdmin && cid == 0) || r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can 判断是否拥有在指定社区执行某项操作的权限, communityID为0表示需要全局权限
+// 全局角色在所有社区生效, 社区角色只在对应的社区生效
+func Can(roles []string, perm string, communityID uint64) bool {
+	for _, s := range roles {
+		r, cid := Decode(s)
+		if r == RoleAdmin && cid == 0 {
+			return true
+		}
+		if cid != 0 && cid != communityID {
+			continue
+		}
+		for _, p := range rolePermissions[r] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ModeratedCommunities 返回版主管理的社区
+func ModeratedCommunities(roles []string) (ids []uint64) {
+	for _, s := range roles {
+		if r, cid := Decode(s); r == RoleModerator && cid != 0 {
+			ids = append(ids, cid)
+		}
+	}
+	return
+}
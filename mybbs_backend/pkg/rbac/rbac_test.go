@@ -0,0 +1,47 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	assert.Equal(t, "admin", Encode(RoleAdmin, 0))
+	assert.Equal(t, "moderator:3", Encode(RoleModerator, 3))
+	r, cid := Decode("moderator:3")
+	assert.Equal(t, RoleModerator, r)
+	assert.Equal(t, uint64(3), cid)
+	r, cid = Decode("admin")
+	assert.Equal(t, RoleAdmin, r)
+	assert.Equal(t, uint64(0), cid)
+}
+
+func TestCan(t *testing.T) {
+	admin := []string{"admin"}
+	mod := []string{"moderator:3"}
+
+	assert.True(t, Can(admin, PermManageRoles, 0))
+	assert.True(t, Can(admin, PermModeratePost, 5))
+
+	assert.True(t, Can(mod, PermModeratePost, 3))
+	assert.True(t, Can(mod, PermBanUser, 3))
+	assert.True(t, Can(mod, PermApproveMember, 3))
+	// 只在自己管理的社区生效
+	assert.False(t, Can(mod, PermModeratePost, 4))
+	assert.False(t, Can(mod, PermModeratePost, 0))
+	assert.False(t, Can(mod, PermManageCommunity, 3))
+	assert.False(t, Can(mod, PermManageWebhooks, 3))
+	assert.True(t, Can(admin, PermManageWebhooks, 0))
+
+	assert.False(t, Can(nil, PermModeratePost, 3))
+	// 伪造的社区管理员不是全局管理员
+	assert.False(t, Can([]string{"admin:3"}, PermManageRoles, 0))
+}
+
+func TestHasRole(t *testing.T) {
+	assert.True(t, HasRole([]string{"moderator:3"}, RoleModerator))
+	assert.True(t, HasRole([]string{"admin"}, RoleModerator))
+	assert.False(t, HasRole([]string{"moderator:3"}, RoleAdmin))
+	assert.Equal(t, []uint64{3, 5}, ModeratedCommunities([]string{"moderator:3", "admin", "moderator:5"}))
+}
@@ -0,0 +1,63 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 附录B中SHA1的测试向量
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	cases := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for ts, want := range cases {
+		assert.Equal(t, want, hotp(key, uint64(ts/Period), 8), "time %d", ts)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	code, err := Code(secret, now)
+	assert.Nil(t, err)
+	assert.Equal(t, "050471", code)
+
+	step, ok := Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// 允许一个周期的时钟偏差
+	_, ok = Validate(secret, code, now.Add(Period*time.Second))
+	assert.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(3*Period*time.Second))
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "000000", now)
+	assert.False(t, ok)
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(secret))
+
+	uri := URI("bluebell", "alice@example.com", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/bluebell:alice@example.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+
+	png, err := QRCode(uri, 256)
+	assert.Nil(t, err)
+	assert.Equal(t, "\x89PNG", string(png[:4]))
+}
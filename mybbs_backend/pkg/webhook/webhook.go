@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 向外部地址投递事件: 签名、发送及重试间隔
+// 签名为 "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)), 接收方用同一个密钥校验,
+// 并检查时间戳防止重放
+
+const (
+	HeaderEvent     = "X-Bluebell-Event"     // 事件类型
+	HeaderDelivery  = "X-Bluebell-Delivery"  // 投递id, 重试时不变, 接收方可以用来去重
+	HeaderTimestamp = "X-Bluebell-Timestamp" // 发送时间(unix时间戳)
+	HeaderSignature = "X-Bluebell-Signature" // 签名
+)
+
+const maxResponseBody = 1024 // 记录的响应内容的最大长度
+
+// ErrorStatus 接收方返回了非2xx的状态码
+var ErrorStatus = errors.New("webhook接收方返回了错误的状态码")
+
+// Sign 计算请求的签名
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验请求的签名, 供接收方及测试使用
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewSecret 生成随机的签名密钥
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Backoff 第attempt次失败后距下次重试的时间, 从base开始每次翻倍, 不超过max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// Request 一次投递
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID uint64
+	Payload    []byte
+}
+
+// Result 接收方的响应, 请求没有发出或没有收到响应时StatusCode为0
+type Result struct {
+	StatusCode int
+	Body       string
+}
+
+// Client 发送webhook请求, 不跟随重定向
+type Client struct {
+	http *http.Client
+}
+
+// NewClient 创建Client, timeout为每次请求的超时时间
+func NewClient(timeout time.Duration) *Client {
+	return &Client{http: &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Deliver 发送一次请求, 接收方返回2xx时成功, 其余情况返回错误
+func (c *Client) Deliver(r *Request, now time.Time) (*Result, error) {
+	req, err := http.NewRequest(http.MethodPost, r.URL, bytes.NewReader(r.Payload))
+	if err != nil {
+		return &Result{}, err
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bluebell-webhook")
+	req.Header.Set(HeaderEvent, r.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(r.DeliveryID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(r.Secret, ts, r.Payload))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &Result{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	// 响应内容会写入投递记录, 去掉不合法的UTF-8字符
+	res := &Result{StatusCode: resp.StatusCode, Body: strings.ToValidUTF8(string(body), "")}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return res, fmt.Errorf("%w: %d", ErrorStatus, resp.StatusCode)
+	}
+	return res, nil
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver 本地模拟的webhook接收方, 校验签名并记录收到的请求
+type receiver struct {
+	*httptest.Server
+	secret string
+	status int
+	delay  time.Duration
+
+	events     []string
+	deliveries []string
+	bodies     []string
+	badSig     int
+}
+
+func newReceiver(secret string) *receiver {
+	r := &receiver{secret: secret, status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		ts, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+		if !Verify(r.secret, ts, body, req.Header.Get(HeaderSignature)) {
+			r.badSig++
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.events = append(r.events, req.Header.Get(HeaderEvent))
+		r.deliveries = append(r.deliveries, req.Header.Get(HeaderDelivery))
+		r.bodies = append(r.bodies, string(body))
+		if r.delay > 0 {
+			time.Sleep(r.delay)
+		}
+		w.WriteHeader(r.status)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	return r
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"post.created"}`)
+	sig := Sign("secret", 1600000000, body)
+	assert.Equal(t, 71, len(sig))
+	assert.True(t, Verify("secret", 1600000000, body, sig))
+	assert.False(t, Verify("other", 1600000000, body, sig))
+	assert.False(t, Verify("secret", 1600000001, body, sig))
+	assert.False(t, Verify("secret", 1600000000, []byte(`{}`), sig))
+
+	s1, err := NewSecret()
+	assert.Nil(t, err)
+	s2, _ := NewSecret()
+	assert.Equal(t, 64, len(s1))
+	assert.NotEqual(t, s1, s2)
+}
+
+func TestBackoff(t *testing.T) {
+	base, max := 30*time.Second, time.Hour
+	assert.Equal(t, 30*time.Second, Backoff(1, base, max))
+	assert.Equal(t, time.Minute, Backoff(2, base, max))
+	assert.Equal(t, 4*time.Minute, Backoff(4, base, max))
+	assert.Equal(t, 32*time.Minute, Backoff(7, base, max))
+	assert.Equal(t, time.Hour, Backoff(8, base, max))
+	assert.Equal(t, time.Hour, Backoff(100, base, max))
+}
+
+func TestDeliver(t *testing.T) {
+	r := newReceiver("secret")
+	defer r.Close()
+	c := NewClient(time.Second)
+	req := &Request{URL: r.URL, Secret: "secret", Event: "post.created", DeliveryID: 42, Payload: []byte(`{"a":1}`)}
+
+	res, err := c.Deliver(req, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "ok", res.Body)
+	assert.Equal(t, []string{"post.created"}, r.events)
+	assert.Equal(t, []string{"42"}, r.deliveries)
+	assert.Equal(t, []string{`{"a":1}`}, r.bodies)
+
+	// 密钥不一致时接收方拒绝
+	req.Secret = "wrong"
+	res, err = c.Deliver(req, time.Now())
+	assert.True(t, errors.Is(err, ErrorStatus))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, 1, r.badSig)
+
+	// 非2xx的响应算作失败
+	req.Secret = "secret"
+	r.status = http.StatusInternalServerError
+	res, err = c.Deliver(req, time.Now())
+	assert.True(t, errors.Is(err, ErrorStatus))
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+	// 不跟随重定向
+	r.status = http.StatusFound
+	res, err = c.Deliver(req, time.Now())
+	assert.True(t, errors.Is(err, ErrorStatus))
+	assert.Equal(t, http.StatusFound, res.StatusCode)
+
+	// 超时
+	r.status, r.delay = http.StatusOK, 200*time.Millisecond
+	res, err = NewClient(50*time.Millisecond).Deliver(req, time.Now())
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, res.StatusCode)
+
+	// 接收方不可达
+	r.Close()
+	_, err = c.Deliver(req, time.Now())
+	assert.NotNil(t, err)
+}
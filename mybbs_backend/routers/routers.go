@@ -5,6 +5,7 @@ import (
 	_ "bluebell_backend/docs" // 千万不要忘了导入把你上一步生成的docs
 	"bluebell_backend/logger"
 	"bluebell_backend/middlewares"
+	"bluebell_backend/pkg/rbac"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -34,34 +35,171 @@ func SetupRouter(mode string) *gin.Engine {
 		context.HTML(http.StatusOK, "index.html", nil)
 	})
 
+	// 公开JWT验签公钥, 供其他内部服务校验token
+	r.GET("/.well-known/jwks.json", controller.JWKSHandler)
+
 	// 注册swagger
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	v1 := r.Group("/api/v1")
-	v1.POST("/login", controller.LoginHandler)
-	v1.POST("/signup", controller.SignUpHandler)				// 注册业务路由
-	v1.GET("/refresh_token", controller.RefreshTokenHandler)
+	// 按操作限流, 限额在config.yaml的rate_limit中配置
+	loginLimit := middlewares.RateLimit("login")
+	v1.POST("/login", loginLimit, controller.LoginHandler)
+	v1.POST("/login/2fa", loginLimit, controller.LoginTwoFactorHandler)	// 两步验证登录的第二步
+	v1.GET("/oauth/oidc/login", controller.OIDCLoginHandler)	// 跳转到第三方身份提供方登录
+	v1.GET("/oauth/oidc/callback", controller.OIDCCallbackHandler)	// 第三方登录回调
+	v1.POST("/signup", middlewares.RateLimit("signup"), controller.SignUpHandler)				// 注册业务路由
+	v1.POST("/refresh_token", controller.RefreshTokenHandler)	// 轮换refresh token
+	v1.GET("/refresh_token", controller.RefreshTokenHandler)	// 兼容旧客户端
+
+	// 未登录也能访问, 登录后可以看到自己加入的私有社区
+	optionalAuth := middlewares.OptionalJWTAuthMiddleware()
+	v1.GET("/posts", optionalAuth, controller.PostListHandler)		// 分页展示帖子列表
+	v1.GET("/posts2", optionalAuth, controller.PostList2Handler) // 根据时间或者分数排序分页展示帖子列表
+	v1.GET("/community", optionalAuth, controller.CommunityHandler)	// 获取分类社区列表
+	v1.GET("/community/:id", optionalAuth, controller.CommunityDetailHandler)	// 根据ID查找社区详情
+	v1.GET("/community/:id/moderators", optionalAuth, controller.CommunityModeratorListHandler) // 社区的版主
+	v1.GET("/community/:id/rules", optionalAuth, controller.CommunityRuleHandler)               // 社区的发帖规则及模板
+	v1.GET("/community/:id/flairs", optionalAuth, controller.CommunityFlairListHandler)         // 社区的flair
+	v1.GET("/post/:id", optionalAuth, controller.PostDetailHandler) // 查询帖子详情
+
+	v1.GET("/user/:id", optionalAuth, controller.UserProfileHandler)                // 用户公开资料
+	v1.GET("/user/:id/posts", optionalAuth, controller.UserPostListHandler)         // 用户发布的帖子
+	v1.GET("/user/:id/comments", optionalAuth, controller.UserCommentListHandler)   // 用户发表的评论
+	v1.GET("/user/:id/followers", controller.FollowerListHandler)                   // 用户的粉丝
+	v1.GET("/user/:id/following", controller.FollowingListHandler)                  // 用户关注的人
+	v1.GET("/user/:id/identicon", controller.UserIdenticonHandler)    // 自动生成的默认头像
+	v1.GET("/digest/unsubscribe", controller.DigestUnsubscribePageHandler) // 邮件中的退订链接, 只显示确认页面
+	v1.POST("/digest/unsubscribe", controller.DigestUnsubscribeHandler) // 邮件客户端的一键退订
 
-	v1.GET("/posts", controller.PostListHandler)		// 分页展示帖子列表
-	v1.GET("/posts2", controller.PostList2Handler) // 根据时间或者分数排序分页展示帖子列表
-	v1.GET("/community", controller.CommunityHandler)	// 获取分类社区列表
-	v1.GET("/community/:id", controller.CommunityDetailHandler)	// 根据ID查找社区详情
-	v1.GET("/post/:id", controller.PostDetailHandler) // 查询帖子详情
+	// 实时事件(SSE), EventSource不能设置请求头, 允许在URI中携带token
+	v1.GET("/events", middlewares.QueryTokenMiddleware(), middlewares.JWTAuthMiddleware(), controller.EventStreamHandler)
 
 	v1.Use(middlewares.JWTAuthMiddleware())	// 应用JWT认证中间件
 	{
 		//v1.GET("/community", controller.CommunityHandler)	// 获取分类社区列表
 		//v1.GET("/community/:id", controller.CommunityDetailHandler)	// 根据ID查找社区详情
 
-		v1.POST("/post", controller.CreatePostHandler)	 // 创建帖子
+		v1.POST("/logout", controller.LogoutHandler) // 登出
+		v1.GET("/user/sessions", controller.SessionListHandler)                 // 设备会话列表
+		v1.DELETE("/user/sessions/:id", controller.SessionRevokeHandler)        // 注销指定设备
+		v1.DELETE("/user/sessions", controller.SessionRevokeOthersHandler)      // 注销其他所有设备
+
+		v1.PUT("/user/profile", controller.UpdateProfileHandler) // 修改个人资料
+		v1.POST("/user/avatar", controller.UploadAvatarHandler)  // 上传头像
+		v1.DELETE("/user/avatar", controller.ResetAvatarHandler) // 恢复默认头像
+		v1.GET("/user/preferences", controller.PreferencesHandler)       // 浏览偏好(NSFW/剧透)
+		v1.PUT("/user/preferences", controller.UpdatePreferencesHandler) // 修改浏览偏好
+		v1.GET("/user/digest", controller.DigestSettingHandler)          // 邮件摘要设置
+		v1.PUT("/user/digest", controller.UpdateDigestSettingHandler)    // 修改邮件摘要的发送频率
+
+		v1.POST("/user/2fa/enroll", controller.TOTPEnrollHandler)   // 生成两步验证密钥
+		v1.GET("/user/2fa/qrcode", controller.TOTPQRCodeHandler)    // 两步验证密钥二维码(PNG)
+		v1.POST("/user/2fa/confirm", controller.TOTPConfirmHandler) // 确认并开启两步验证
+		v1.POST("/user/2fa/disable", controller.TOTPDisableHandler) // 关闭两步验证
+
+		// 管理后台
+		admin := v1.Group("/admin")
+		{
+			admin.GET("/users/:id/roles", middlewares.RequirePermission(rbac.PermManageRoles), controller.UserRoleListHandler) // 用户的角色
+			admin.POST("/roles", middlewares.RequirePermission(rbac.PermManageRoles), controller.GrantRoleHandler)             // 授予角色
+			admin.DELETE("/roles", middlewares.RequirePermission(rbac.PermManageRoles), controller.RevokeRoleHandler)          // 撤销角色
+
+			community := admin.Group("/community", middlewares.RequirePermission(rbac.PermManageCommunity))
+			community.GET("", controller.AdminCommunityListHandler)                  // 全部社区(包括已归档)
+			community.POST("", controller.CreateCommunityHandler)                    // 创建社区
+			community.PUT("/order", controller.ReorderCommunityHandler)              // 调整社区排序
+			community.PUT("/:id", controller.UpdateCommunityHandler)                 // 修改社区名称及简介
+			community.POST("/:id/archive", controller.ArchiveCommunityHandler)       // 归档社区
+			community.DELETE("/:id/archive", controller.UnarchiveCommunityHandler)   // 恢复归档的社区
+
+			webhooks := admin.Group("/webhooks", middlewares.RequirePermission(rbac.PermManageWebhooks))
+			webhooks.GET("", controller.WebhookListHandler)                           // 全部webhook
+			webhooks.POST("", controller.CreateWebhookHandler)                        // 注册webhook
+			webhooks.PUT("/:id", controller.UpdateWebhookHandler)                     // 修改webhook
+			webhooks.DELETE("/:id", controller.DeleteWebhookHandler)                  // 删除webhook
+			webhooks.GET("/:id/deliveries", controller.WebhookDeliveryListHandler)    // 投递记录
+			webhooks.POST("/:id/replay", controller.ReplayFailedDeliveriesHandler)    // 重新投递所有失败的记录
+			webhooks.POST("/deliveries/:id/replay", controller.ReplayDeliveryHandler) // 重新投递一条失败的记录
+		}
+
+		v1.POST("/community/:id/join", controller.JoinCommunityHandler)    // 加入社区
+		v1.DELETE("/community/:id/join", controller.LeaveCommunityHandler) // 退出社区
+		v1.GET("/user/communities", controller.UserCommunityListHandler)   // 我加入的社区
+		v1.GET("/feed", controller.FeedHandler)                            // 首页feed: 加入的社区的帖子
+		v1.GET("/feed/following", controller.FollowingFeedHandler)         // 关注的人的帖子
+		v1.POST("/user/:id/follow", controller.FollowHandler)              // 关注用户
+		v1.DELETE("/user/:id/follow", controller.UnfollowHandler)          // 取消关注
+
+		v1.GET("/user/blocks", controller.BlockListHandler)                              // 屏蔽的用户、社区及关键词
+		v1.POST("/user/:id/block", controller.BlockUserHandler)                          // 屏蔽用户
+		v1.DELETE("/user/:id/block", controller.UnblockUserHandler)                      // 取消屏蔽用户
+		v1.POST("/community/:id/mute", controller.MuteCommunityHandler)                  // 屏蔽社区
+		v1.DELETE("/community/:id/mute", controller.UnmuteCommunityHandler)              // 取消屏蔽社区
+		v1.POST("/user/muted_keywords", controller.MuteKeywordHandler)                   // 屏蔽关键词
+		v1.DELETE("/user/muted_keywords/:keyword", controller.UnmuteKeywordHandler)      // 取消屏蔽关键词
+
+		v1.GET("/notifications", controller.NotificationListHandler)                 // 我的通知及未读数
+		v1.GET("/notifications/unread_count", controller.UnreadNotificationCountHandler) // 未读通知数
+		v1.POST("/notifications/read", controller.MarkNotificationsReadHandler)      // 标记通知为已读
+
+		v1.POST("/messages", middlewares.RateLimit("message"), controller.SendMessageHandler) // 发送私信
+		v1.POST("/conversations", controller.CreateConversationHandler)                      // 创建群聊
+		v1.GET("/conversations", controller.ConversationListHandler)                         // 我的会话及未读数
+		v1.GET("/conversations/:id/messages", controller.MessageHistoryHandler)              // 会话的历史消息及已读回执
+		v1.POST("/conversations/:id/read", controller.MarkConversationReadHandler)           // 标记会话已读
+
+		// 社区管理, 版主只能管理自己的社区
+		moderate := v1.Group("/community/:id")
+		{
+			inCommunity := middlewares.CommunityParam("id")
+			modPost := middlewares.RequirePermission(rbac.PermModeratePost, inCommunity)
+			moderate.DELETE("/post/:post_id", modPost, controller.RemovePostHandler)     // 删除帖子
+			moderate.POST("/post/:post_id/pin", modPost, controller.PinPostHandler)      // 置顶帖子
+			moderate.DELETE("/post/:post_id/pin", modPost, controller.UnpinPostHandler)  // 取消置顶
+			moderate.POST("/post/:post_id/lock", modPost, controller.LockPostHandler)    // 锁定帖子
+			moderate.DELETE("/post/:post_id/lock", modPost, controller.UnlockPostHandler) // 解锁帖子
+
+			modComment := middlewares.RequirePermission(rbac.PermModerateComment, inCommunity)
+			moderate.DELETE("/comment/:comment_id", modComment, controller.RemoveCommentHandler) // 删除评论
+
+			banUser := middlewares.RequirePermission(rbac.PermBanUser, inCommunity)
+			moderate.GET("/bans", banUser, controller.BanListHandler)                // 社区禁言列表
+			moderate.POST("/bans", banUser, controller.BanUserHandler)               // 禁言用户
+			moderate.DELETE("/bans/:user_id", banUser, controller.UnbanUserHandler) // 解除禁言
+
+			approveMember := middlewares.RequirePermission(rbac.PermApproveMember, inCommunity)
+			moderate.GET("/members/pending", approveMember, controller.PendingMemberListHandler)         // 待审核的加入申请
+			moderate.POST("/members/:user_id/approve", approveMember, controller.ApproveMemberHandler)   // 通过加入申请
+			moderate.DELETE("/members/:user_id", approveMember, controller.RemoveMemberHandler)          // 移除成员或拒绝申请
+
+			manageRules := middlewares.RequirePermission(rbac.PermManageRules, inCommunity)
+			moderate.PUT("/rules", manageRules, controller.UpdateCommunityRuleHandler)               // 修改发帖规则及模板
+			moderate.POST("/flairs", manageRules, controller.CreateFlairHandler)                     // 创建flair
+			moderate.PUT("/flairs/:flair_id", manageRules, controller.UpdateFlairHandler)            // 修改flair
+			moderate.DELETE("/flairs/:flair_id", manageRules, controller.DeleteFlairHandler)         // 删除flair
+			moderate.PUT("/slow_mode", manageRules, controller.UpdateSlowModeHandler)                // 设置慢速模式
+		}
+
+		v1.POST("/post", middlewares.RateLimit("post"), controller.CreatePostHandler)	 // 创建帖子
+		v1.PUT("/post/:id/flair", controller.PostFlairHandler) // 修改帖子的flair(作者或版主)
+		v1.PUT("/post/:id/flags", controller.PostFlagsHandler) // 修改帖子的NSFW及剧透标记
+		v1.POST("/post/:id/save", controller.SavePostHandler)     // 收藏帖子
+		v1.DELETE("/post/:id/save", controller.UnsavePostHandler) // 取消收藏
+
+		v1.GET("/user/saved", controller.SavedPostListHandler)                           // 我收藏的帖子
+		v1.GET("/user/saved/folders", controller.BookmarkFolderListHandler)              // 我的收藏夹
+		v1.POST("/user/saved/folders", controller.CreateBookmarkFolderHandler)           // 创建收藏夹
+		v1.PUT("/user/saved/folders/:id", controller.RenameBookmarkFolderHandler)        // 重命名收藏夹
+		v1.DELETE("/user/saved/folders/:id", controller.DeleteBookmarkFolderHandler)     // 删除收藏夹
 		//v1.GET("/post/:id", controller.PostDetailHandler) // 查询帖子详情
 		//v1.GET("/posts", controller.PostListHandler)		// 分页展示帖子列表
 		//
 		//v1.GET("/posts2", controller.PostList2Handler) // 根据时间或者分数排序分页展示帖子列表
 
-		v1.POST("/vote", controller.VoteHandler)		   // 投票
+		v1.POST("/vote", middlewares.RateLimit("vote"), controller.VoteHandler)		   // 投票
 
-		v1.POST("/comment", controller.CommentHandler)
+		v1.POST("/comment", middlewares.RateLimit("comment"), controller.CommentHandler)
 		v1.GET("/comment", controller.CommentListHandler)
 
 		v1.GET("/ping", func(c *gin.Context) {
@@ -1,7 +1,9 @@
 package settings
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
@@ -10,15 +12,46 @@ import (
 var Conf = new(AppConfig)
 
 type AppConfig struct {
-	Mode         string `mapstructure:"mode"`
-	Port         int    `mapstructure:"port"`
-	Name         string `mapstructure:"name"`
-	Version      string `mapstructure:"version"`
-	StartTime    string `mapstructure:"start_time"`
-	MachineID    int    `mapstructure:"machine_id"`
-	*LogConfig   `mapstructure:"log"`
-	*MySQLConfig `mapstructure:"mysql"`
-	*RedisConfig `mapstructure:"redis"`
+	Mode             string `mapstructure:"mode"`
+	Port             int    `mapstructure:"port"`
+	Name             string `mapstructure:"name"`
+	Version          string `mapstructure:"version"`
+	StartTime        string `mapstructure:"start_time"`
+	MachineID        int    `mapstructure:"machine_id"`
+	*AuthConfig      `mapstructure:"auth"`
+	*LogConfig       `mapstructure:"log"`
+	*MySQLConfig     `mapstructure:"mysql"`
+	*RedisConfig     `mapstructure:"redis"`
+	*RateLimitConfig `mapstructure:"rate_limit"`
+	*MailConfig      `mapstructure:"mail"`
+	*DigestConfig    `mapstructure:"digest"`
+}
+
+type AuthConfig struct {
+	JwtExpire     int          `mapstructure:"jwt_expire"`     // access token有效期(小时)
+	RefreshExpire int          `mapstructure:"refresh_expire"` // refresh token有效期(小时)
+	SigningKid    string       `mapstructure:"signing_kid"`    // 当前用于签发token的密钥
+	Keys          []*KeyConfig `mapstructure:"keys"`           // 所有可用于验签的密钥
+	OIDC          *OIDCConfig  `mapstructure:"oidc"`           // 第三方登录, 不配置则不开启
+}
+
+// KeyConfig JWT签名密钥, alg可选HS256/RS256/ES256/EdDSA
+// HS256使用secret; 其余算法使用PEM格式的私钥文件, 只用于验签的旧密钥可以只配置公钥文件
+type KeyConfig struct {
+	Kid            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// OIDCConfig OpenID Connect身份提供方, 其余endpoint通过issuer的服务发现文档获取
+type OIDCConfig struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
 }
 
 type MySQLConfig struct {
@@ -40,6 +73,51 @@ type RedisConfig struct {
 	MinIdleConns int    `mapstructure:"min_idle_conns"`
 }
 
+// RateLimitConfig 按操作限流, 计数保存在redis中, 多个实例共享同一个限额
+type RateLimitConfig struct {
+	Enabled bool                      `mapstructure:"enabled"`
+	Actions map[string]*RateLimitRule `mapstructure:"actions"` // key是操作名: post/comment/vote/login/signup
+}
+
+// RateLimitRule 一个时间窗口内每个用户及每个IP允许的次数, 0表示不限制
+type RateLimitRule struct {
+	Window    int   `mapstructure:"window"`     // 时间窗口(秒)
+	UserLimit int64 `mapstructure:"user_limit"` // 每个登录用户的次数
+	IPLimit   int64 `mapstructure:"ip_limit"`   // 每个IP的次数
+}
+
+// MailConfig 发送邮件的SMTP服务器, host留空则不发送邮件
+type MailConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"` // 发件人, 例如 "bluebell <noreply@example.com>"
+}
+
+// DigestConfig 订阅社区的热门帖子邮件摘要
+type DigestConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	BaseURL     string `mapstructure:"base_url"`     // 站点地址, 用于生成帖子及退订链接
+	Secret      string `mapstructure:"secret"`       // 退订链接的签名密钥
+	TemplateDir string `mapstructure:"template_dir"` // 邮件模板目录, 包含digest.html及digest.txt
+	PostLimit   int    `mapstructure:"post_limit"`   // 每封邮件最多包含的帖子数
+}
+
+// MinSecretLen 签名密钥的最短长度(字节), 与HMAC-SHA256的输出长度相同
+const MinSecretLen = 32
+
+// CheckSecret 检查签名密钥, 拒绝示例配置中的占位值及过短的密钥
+func CheckSecret(secret string) error {
+	if strings.HasPrefix(secret, "change-me") {
+		return errors.New("secret仍是示例配置中的占位值, 请换成随机生成的密钥")
+	}
+	if len(secret) < MinSecretLen {
+		return fmt.Errorf("secret至少需要%d字节", MinSecretLen)
+	}
+	return nil
+}
+
 type LogConfig struct {
 	Level      string `mapstructure:"level"`
 	Filename   string `mapstructure:"filename"`